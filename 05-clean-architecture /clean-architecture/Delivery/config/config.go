@@ -0,0 +1,176 @@
+// Package config holds the settings of the server. They are loaded from an
+// optional YAML or TOML file, then environment variables, then command-line
+// flags, each overriding the one before, and checked before anything starts.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"taskmanager/auth/Domain"
+	"taskmanager/auth/Infrastructure"
+)
+
+// DefaultJWTSecret is the JWT secret used in dev mode when none is set.
+// Anyone can sign tokens with it, so it is refused in production.
+const DefaultJWTSecret = "default-jwt-should-be-set-in-env-this-is-a-backup"
+
+const (
+	ModeDev        = "dev"
+	ModeProduction = "production"
+)
+
+type Config struct {
+	// Mode is "dev" or "production"; dev allows insecure defaults
+	Mode      string
+	Server    ServerConfig
+	Storage   StorageConfig
+	Auth      AuthConfig
+	Schedules ScheduleConfig
+	Webhooks  WebhookConfig
+}
+
+type ServerConfig struct {
+	Port              int
+	ReadHeaderTimeout time.Duration
+	// ShutdownTimeout is how long in-flight requests get to finish
+	ShutdownTimeout time.Duration
+	// ShutdownDelay is how long the server keeps taking requests after
+	// reporting that it is not ready, so load balancers can stop sending
+	// them first
+	ShutdownDelay time.Duration
+}
+
+type StorageConfig struct {
+	// Backend is "mongo" or "memory"
+	Backend  string
+	MongoURI string
+	Database string
+	// OperationTimeout bounds every MongoDB operation
+	OperationTimeout time.Duration
+	// EventsChangeStream shares task events between instances through a
+	// MongoDB change stream
+	EventsChangeStream bool
+}
+
+type AuthConfig struct {
+	// Tokens are signed with JWTPrivateKeyFile (RS256/EdDSA) when set,
+	// otherwise with the shared JWTSecret (HS256)
+	JWTSecret         string
+	JWTPrivateKeyFile string
+	JWTPublicKeyFiles []string
+	AccessTokenTTL    time.Duration
+	RefreshTokenTTL   time.Duration
+	BcryptCost        int
+	// AdminBootstrapToken lets a registered user become the first admin
+	AdminBootstrapToken string
+}
+
+type ScheduleConfig struct {
+	// RecurrenceInterval is how often recurring tasks are checked for
+	// their next occurrence
+	RecurrenceInterval time.Duration
+	// WebhookDispatchInterval is how often webhook deliveries are checked
+	// for retries that are due
+	WebhookDispatchInterval time.Duration
+}
+
+type WebhookConfig struct {
+	// Timeout is how long a receiver has to answer a delivery
+	Timeout time.Duration
+	// AllowPrivateAddresses lets webhooks be sent to loopback, link-local
+	// and private addresses, such as a receiver on localhost. Only dev mode
+	// allows it.
+	AllowPrivateAddresses bool
+}
+
+// Default returns the settings used for anything that is not configured
+func Default() *Config {
+	return &Config{
+		Mode: ModeProduction,
+		Server: ServerConfig{
+			Port:              8080,
+			ReadHeaderTimeout: 10 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Storage: StorageConfig{
+			Backend:          "mongo",
+			MongoURI:         "mongodb://localhost:27017",
+			Database:         "taskmanager",
+			OperationTimeout: 5 * time.Second,
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 7 * 24 * time.Hour,
+			BcryptCost:      10,
+		},
+		Schedules: ScheduleConfig{
+			RecurrenceInterval:      time.Minute,
+			WebhookDispatchInterval: 10 * time.Second,
+		},
+		Webhooks: WebhookConfig{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// IsDev reports whether the server runs in dev mode
+func (c *Config) IsDev() bool {
+	return c.Mode == ModeDev
+}
+
+// UsesDefaultJWTSecret reports whether tokens are signed with
+// DefaultJWTSecret, which only dev mode allows
+func (c *Config) UsesDefaultJWTSecret() bool {
+	return c.Auth.JWTPrivateKeyFile == "" && c.Auth.JWTSecret == DefaultJWTSecret
+}
+
+// Validate checks the settings, returning every problem it finds
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Mode == ModeDev || c.Mode == ModeProduction, "mode must be %q or %q", ModeDev, ModeProduction)
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port must be between 1 and 65535")
+	check(c.Server.ReadHeaderTimeout > 0, "server.read_header_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay cannot be negative")
+
+	check(c.Storage.Backend == "mongo" || c.Storage.Backend == "memory", "storage.backend must be \"mongo\" or \"memory\"")
+	if c.Storage.Backend == "mongo" {
+		check(c.Storage.MongoURI != "", "storage.mongodb_uri is required")
+		check(c.Storage.Database != "", "storage.database is required")
+	}
+	check(c.Storage.OperationTimeout > 0, "storage.operation_timeout must be positive")
+	check(!c.Storage.EventsChangeStream || c.Storage.Backend == "mongo", "storage.task_events_change_stream needs the mongo storage backend")
+
+	if c.Auth.JWTPrivateKeyFile == "" {
+		check(c.Auth.JWTSecret != "", "auth.jwt_secret or auth.jwt_private_key_file is required outside dev mode")
+		check(c.IsDev() || c.Auth.JWTSecret != DefaultJWTSecret, "auth.jwt_secret cannot be the default secret outside dev mode")
+	}
+	check(c.Auth.AccessTokenTTL > 0, "auth.access_token_ttl must be positive")
+	check(c.Auth.RefreshTokenTTL > c.Auth.AccessTokenTTL, "auth.refresh_token_ttl must be longer than auth.access_token_ttl")
+	check(c.Auth.BcryptCost >= Infrastructure.MinHashCost && c.Auth.BcryptCost <= Infrastructure.MaxHashCost,
+		"auth.bcrypt_cost must be between %d and %d", Infrastructure.MinHashCost, Infrastructure.MaxHashCost)
+
+	check(c.Schedules.RecurrenceInterval > 0, "schedules.recurrence_interval must be positive")
+	check(c.Schedules.WebhookDispatchInterval > 0, "schedules.webhook_dispatch_interval must be positive")
+
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	// A delivery still waiting on its receiver when its lease ends would be
+	// claimed and sent again by another dispatcher
+	check(c.Webhooks.Timeout < Domain.WebhookDeliveryLease, "webhooks.timeout must be shorter than the %s delivery lease", Domain.WebhookDeliveryLease)
+	check(c.IsDev() || !c.Webhooks.AllowPrivateAddresses, "webhooks.allow_private_addresses is only allowed in dev mode")
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
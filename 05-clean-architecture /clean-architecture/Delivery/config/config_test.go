@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets every setting's environment variable for the test, so
+// the environment it runs in cannot change what Load returns
+func clearEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	for _, s := range settings {
+		t.Setenv(s.env, "")
+	}
+}
+
+// writeFile writes a config file named name into a temporary directory
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	yamlFile := "server:\n  port: 1000\n  shutdown_timeout: 5s\nauth:\n  jwt_secret: from-file\n"
+	tomlFile := "[server]\nport = 1000\nshutdown_timeout = \"5s\"\n\n[auth]\njwt_secret = \"from-file\"\n"
+
+	tests := []struct {
+		name     string
+		fileName string
+		file     string
+		env      map[string]string
+		args     []string
+		wantPort int
+		// wantShutdown shows whether settings the later sources leave
+		// alone keep the value from the file
+		wantShutdown time.Duration
+		wantSecret   string
+	}{
+		{"defaults", "", "", map[string]string{"JWT_SECRET": "from-env"}, nil, 8080, 20 * time.Second, "from-env"},
+		{"dev mode secret", "", "", map[string]string{"APP_MODE": "dev"}, nil, 8080, 20 * time.Second, DefaultJWTSecret},
+		{"yaml file", "config.yaml", yamlFile, nil, nil, 1000, 5 * time.Second, "from-file"},
+		{"toml file", "config.toml", tomlFile, nil, nil, 1000, 5 * time.Second, "from-file"},
+		{"env over file", "config.yaml", yamlFile, map[string]string{"PORT": "2000"}, nil, 2000, 5 * time.Second, "from-file"},
+		{"flag over env", "config.yaml", yamlFile, map[string]string{"PORT": "2000", "JWT_SECRET": "from-env"},
+			[]string{"--server.port", "3000"}, 3000, 5 * time.Second, "from-env"},
+		{"file from env", "config.yaml", yamlFile, map[string]string{"CONFIG_FILE": "<file>"}, nil, 1000, 5 * time.Second, "from-file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			var args []string
+			if tt.file != "" {
+				path := writeFile(t, tt.fileName, tt.file)
+				if tt.env["CONFIG_FILE"] == "" {
+					args = append(args, "--config", path)
+				} else {
+					t.Setenv("CONFIG_FILE", path)
+				}
+			}
+			for name, value := range tt.env {
+				if name != "CONFIG_FILE" {
+					t.Setenv(name, value)
+				}
+			}
+
+			cfg, err := Load(append(args, tt.args...))
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Server.Port != tt.wantPort {
+				t.Errorf("server.port = %d, want %d", cfg.Server.Port, tt.wantPort)
+			}
+			if cfg.Server.ShutdownTimeout != tt.wantShutdown {
+				t.Errorf("server.shutdown_timeout = %v, want %v", cfg.Server.ShutdownTimeout, tt.wantShutdown)
+			}
+			if cfg.Auth.JWTSecret != tt.wantSecret {
+				t.Errorf("auth.jwt_secret = %q, want %q", cfg.Auth.JWTSecret, tt.wantSecret)
+			}
+		})
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		file     string
+		env      map[string]string
+		args     []string
+		// wantErr is part of the error Load returns
+		wantErr string
+	}{
+		{"no secret in production", "", "", nil, nil, "auth.jwt_secret or auth.jwt_private_key_file is required"},
+		{"default secret in production", "", "", map[string]string{"JWT_SECRET": DefaultJWTSecret}, nil, "cannot be the default secret"},
+		{"unknown mode", "", "", map[string]string{"APP_MODE": "staging"}, nil, `mode must be "dev" or "production"`},
+		{"port out of range", "", "", map[string]string{"APP_MODE": "dev"}, []string{"--server.port", "70000"}, "server.port must be between 1 and 65535"},
+		{"port not a number", "", "", map[string]string{"PORT": "http"}, nil, `invalid PORT "http": expected a whole number`},
+		{"bad duration flag", "", "", nil, []string{"--webhooks.timeout", "10"}, `invalid --webhooks.timeout "10": expected a duration`},
+		{"bad value in file", "config.yaml", "storage:\n  operation_timeout: soon\n", nil, nil, `invalid storage.operation_timeout "soon" in`},
+		{"unknown setting in file", "config.yaml", "server:\n  host: localhost\n", nil, nil, `unknown setting "server.host"`},
+		{"unknown file type", "config.json", "{}", nil, nil, "must end in .yaml, .yml or .toml"},
+		{"unknown flag", "", "", nil, []string{"--server.host", "localhost"}, "flag provided but not defined"},
+		{"unknown backend", "", "", map[string]string{"APP_MODE": "dev", "STORAGE_BACKEND": "sql"}, nil, `storage.backend must be "mongo" or "memory"`},
+		{"change stream in memory", "", "", map[string]string{"APP_MODE": "dev", "STORAGE_BACKEND": "memory", "TASK_EVENTS_CHANGE_STREAM": "true"}, nil,
+			"storage.task_events_change_stream needs the mongo storage backend"},
+		{"refresh shorter than access", "", "", map[string]string{"APP_MODE": "dev", "ACCESS_TOKEN_TTL": "1h", "REFRESH_TOKEN_TTL": "30m"}, nil,
+			"auth.refresh_token_ttl must be longer than auth.access_token_ttl"},
+		{"bcrypt cost too low", "", "", map[string]string{"APP_MODE": "dev", "BCRYPT_COST": "1"}, nil, "auth.bcrypt_cost must be between"},
+		{"webhook timeout not positive", "", "", map[string]string{"APP_MODE": "dev", "WEBHOOK_TIMEOUT": "0s"}, nil, "webhooks.timeout must be positive"},
+		{"webhook timeout as long as the lease", "", "", map[string]string{"APP_MODE": "dev", "WEBHOOK_TIMEOUT": "1m"}, nil,
+			"webhooks.timeout must be shorter than the 1m0s delivery lease"},
+		{"private addresses in production", "", "", map[string]string{"JWT_SECRET": "secret", "WEBHOOK_ALLOW_PRIVATE_ADDRESSES": "true"}, nil,
+			"webhooks.allow_private_addresses is only allowed in dev mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"--config", writeFile(t, tt.fileName, tt.file)}, args...)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := Load(args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
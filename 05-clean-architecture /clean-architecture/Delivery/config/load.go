@@ -0,0 +1,232 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// setting is one configurable value. key names it in config files and,
+// prefixed with "--", on the command line; env is its environment variable.
+type setting struct {
+	key   string
+	env   string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{"mode", "APP_MODE", "dev or production; dev allows the default JWT secret", func(c *Config, v string) error {
+		c.Mode = v
+		return nil
+	}},
+
+	{"server.port", "PORT", "port to listen on", intSetting(func(c *Config) *int { return &c.Server.Port })},
+	{"server.read_header_timeout", "READ_HEADER_TIMEOUT", "how long clients get to send request headers", durationSetting(func(c *Config) *time.Duration { return &c.Server.ReadHeaderTimeout })},
+	{"server.shutdown_timeout", "SHUTDOWN_TIMEOUT", "how long in-flight requests get to finish on shutdown", durationSetting(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{"server.shutdown_delay", "SHUTDOWN_DELAY", "how long to keep serving after reporting not ready on shutdown", durationSetting(func(c *Config) *time.Duration { return &c.Server.ShutdownDelay })},
+
+	{"storage.backend", "STORAGE_BACKEND", "mongo or memory", stringSetting(func(c *Config) *string { return &c.Storage.Backend })},
+	{"storage.mongodb_uri", "MONGODB_URI", "MongoDB connection string", stringSetting(func(c *Config) *string { return &c.Storage.MongoURI })},
+	{"storage.database", "MONGODB_DATABASE", "MongoDB database name", stringSetting(func(c *Config) *string { return &c.Storage.Database })},
+	{"storage.operation_timeout", "DB_OPERATION_TIMEOUT", "deadline for every MongoDB operation", durationSetting(func(c *Config) *time.Duration { return &c.Storage.OperationTimeout })},
+	{"storage.task_events_change_stream", "TASK_EVENTS_CHANGE_STREAM", "share task events between instances through a MongoDB change stream", boolSetting(func(c *Config) *bool { return &c.Storage.EventsChangeStream })},
+
+	{"auth.jwt_secret", "JWT_SECRET", "secret for signing tokens (HS256)", stringSetting(func(c *Config) *string { return &c.Auth.JWTSecret })},
+	{"auth.jwt_private_key_file", "JWT_PRIVATE_KEY_FILE", "PEM private key to sign tokens with (RS256/EdDSA)", stringSetting(func(c *Config) *string { return &c.Auth.JWTPrivateKeyFile })},
+	{"auth.jwt_public_key_files", "JWT_PUBLIC_KEY_FILES", "comma-separated PEM public keys still accepted for verification", listSetting(func(c *Config) *[]string { return &c.Auth.JWTPublicKeyFiles })},
+	{"auth.access_token_ttl", "ACCESS_TOKEN_TTL", "lifetime of access tokens", durationSetting(func(c *Config) *time.Duration { return &c.Auth.AccessTokenTTL })},
+	{"auth.refresh_token_ttl", "REFRESH_TOKEN_TTL", "lifetime of refresh tokens", durationSetting(func(c *Config) *time.Duration { return &c.Auth.RefreshTokenTTL })},
+	{"auth.bcrypt_cost", "BCRYPT_COST", "bcrypt cost for new password hashes", intSetting(func(c *Config) *int { return &c.Auth.BcryptCost })},
+	{"auth.admin_bootstrap_token", "ADMIN_BOOTSTRAP_TOKEN", "secret that lets a registered user become the first admin", stringSetting(func(c *Config) *string { return &c.Auth.AdminBootstrapToken })},
+
+	{"schedules.recurrence_interval", "RECURRENCE_INTERVAL", "how often recurring tasks are checked", durationSetting(func(c *Config) *time.Duration { return &c.Schedules.RecurrenceInterval })},
+	{"schedules.webhook_dispatch_interval", "WEBHOOK_DISPATCH_INTERVAL", "how often webhook retries are checked", durationSetting(func(c *Config) *time.Duration { return &c.Schedules.WebhookDispatchInterval })},
+
+	{"webhooks.timeout", "WEBHOOK_TIMEOUT", "how long a receiver has to answer a delivery", durationSetting(func(c *Config) *time.Duration { return &c.Webhooks.Timeout })},
+	{"webhooks.allow_private_addresses", "WEBHOOK_ALLOW_PRIVATE_ADDRESSES", "allow webhooks to loopback, link-local and private addresses (dev mode only)", boolSetting(func(c *Config) *bool { return &c.Webhooks.AllowPrivateAddresses })},
+}
+
+// Load builds the configuration from the defaults, the config file, the
+// environment and the command-line args, in increasing precedence, and
+// validates it. The file is named by --config or CONFIG_FILE.
+func Load(args []string) (*Config, error) {
+	flags := flag.NewFlagSet("taskmanager", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.key] = flags.String(s.key, "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+
+	if *configFile != "" {
+		values, err := readFile(*configFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range settings {
+			if value, ok := values[s.key]; ok {
+				if err := s.set(cfg, value); err != nil {
+					return nil, fmt.Errorf("invalid %s %q in %s: %w", s.key, value, *configFile, err)
+				}
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if value := os.Getenv(s.env); value != "" {
+			if err := s.set(cfg, value); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", s.env, value, err)
+			}
+		}
+	}
+
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		s, ok := findSetting(f.Name)
+		if !ok || err != nil {
+			return
+		}
+		if setErr := s.set(cfg, *flagValues[s.key]); setErr != nil {
+			err = fmt.Errorf("invalid --%s %q: %w", s.key, *flagValues[s.key], setErr)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Dev mode works without setting up any keys
+	if cfg.IsDev() && cfg.Auth.JWTSecret == "" && cfg.Auth.JWTPrivateKeyFile == "" {
+		cfg.Auth.JWTSecret = DefaultJWTSecret
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func findSetting(key string) (setting, bool) {
+	i := slices.IndexFunc(settings, func(s setting) bool { return s.key == key })
+	if i < 0 {
+		return setting{}, false
+	}
+	return settings[i], true
+}
+
+// readFile reads a YAML or TOML file, chosen by its extension, into its
+// settings' values keyed by their dotted names
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var document map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		err = toml.Unmarshal(data, &document)
+	default:
+		return nil, fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten(values, "", document)
+	for key := range values {
+		if _, ok := findSetting(key); !ok {
+			return nil, fmt.Errorf("config file %s: unknown setting %q", path, key)
+		}
+	}
+	return values, nil
+}
+
+// flatten turns nested tables into dotted keys, and values into the same
+// text their environment variables would hold
+func flatten(values map[string]string, prefix string, document map[string]any) {
+	for name, value := range document {
+		key := prefix + name
+		switch value := value.(type) {
+		case map[string]any:
+			flatten(values, key+".", value)
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+}
+
+func stringSetting(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func intSetting(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected a whole number")
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func boolSetting(field func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false")
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func durationSetting(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("expected a duration such as \"10s\" or \"1m\"")
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+func listSetting(field func(c *Config) *[]string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*field(c) = items
+		return nil
+	}
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/auth/Domain"
+	"taskmanager/auth/Infrastructure"
+)
+
+func (c *Controller) HandleListUsers(ctx *gin.Context) {
+	opts, err := parseUserListOptions(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	page, err := c.userUseCase.ListUsers(ctx.Request.Context(), opts)
+	if err != nil {
+		respondError(ctx, err, listMessages)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Domain.UserListResponse{
+		Users:      page.Users,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	})
+}
+
+func (c *Controller) HandleGetUser(ctx *gin.Context) {
+	user, err := c.userUseCase.GetUserByID(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, err, userMessages)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+func (c *Controller) HandleUpdateUserRole(ctx *gin.Context) {
+	actorID, err := c.authMiddleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	var req Domain.UpdateRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		_ = ctx.Error(Infrastructure.ValidationError(err))
+		return
+	}
+
+	user, err := c.userUseCase.SetUserRole(ctx.Request.Context(), actorID, ctx.Param("id"), req.Role)
+	if err != nil {
+		respondError(ctx, err, userMessages.with(Domain.ErrInvalidInput, "Unknown role"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+func (c *Controller) HandleBootstrapAdmin(ctx *gin.Context) {
+	userID, err := c.authMiddleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	var req Domain.BootstrapAdminRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		_ = ctx.Error(Infrastructure.ValidationError(err))
+		return
+	}
+
+	user, err := c.userUseCase.BootstrapAdmin(ctx.Request.Context(), userID, req.Token)
+	if err != nil {
+		respondError(ctx, err, errorMessages{Domain.ErrForbidden: "Invalid bootstrap token"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+func (c *Controller) HandleDisableUser(ctx *gin.Context) {
+	c.setUserDisabled(ctx, true)
+}
+
+func (c *Controller) HandleEnableUser(ctx *gin.Context) {
+	c.setUserDisabled(ctx, false)
+}
+
+func (c *Controller) setUserDisabled(ctx *gin.Context, disabled bool) {
+	actorID, err := c.authMiddleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	user, err := c.userUseCase.SetUserDisabled(ctx.Request.Context(), actorID, ctx.Param("id"), disabled)
+	if err != nil {
+		respondError(ctx, err, userMessages)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+func (c *Controller) HandleDeleteUser(ctx *gin.Context) {
+	actorID, err := c.authMiddleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	err = c.userUseCase.DeleteUser(ctx.Request.Context(), actorID, ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, err, userMessages)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
+
+func (c *Controller) HandleListAuditLog(ctx *gin.Context) {
+	opts, err := parseAuditListOptions(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	page, err := c.auditUseCase.ListEntries(ctx.Request.Context(), opts)
+	if err != nil {
+		respondError(ctx, err, listMessages)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Domain.AuditLogResponse{
+		Entries:    page.Entries,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	})
+}
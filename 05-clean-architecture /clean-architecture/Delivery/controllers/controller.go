@@ -13,201 +13,330 @@ import (
 type Controller struct {
 	taskUseCase    *Usecases.TaskUseCase
 	userUseCase    *Usecases.UserUseCase
+	auditUseCase   *Usecases.AuditUseCase
+	webhookUseCase *Usecases.WebhookUseCase
 	authMiddleware *Infrastructure.AuthMiddleware
+	jwtService     *Infrastructure.JWTService
 }
 
 func NewController(
 	taskUseCase *Usecases.TaskUseCase,
 	userUseCase *Usecases.UserUseCase,
+	auditUseCase *Usecases.AuditUseCase,
+	webhookUseCase *Usecases.WebhookUseCase,
 	authMiddleware *Infrastructure.AuthMiddleware,
+	jwtService *Infrastructure.JWTService,
 ) *Controller {
 	return &Controller{
 		taskUseCase:    taskUseCase,
 		userUseCase:    userUseCase,
+		auditUseCase:   auditUseCase,
+		webhookUseCase: webhookUseCase,
 		authMiddleware: authMiddleware,
+		jwtService:     jwtService,
 	}
 }
 
 func (c *Controller) HandleRegister(ctx *gin.Context) {
 	var req Domain.RegisterRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = ctx.Error(Infrastructure.ValidationError(err))
 		return
 	}
 
-	user, token, err := c.userUseCase.Register(req)
+	user, tokens, err := c.userUseCase.Register(ctx.Request.Context(), req)
 	if err != nil {
-		if err == Domain.ErrUsernameTaken {
-			ctx.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+		respondError(ctx, err, nil)
 		return
 	}
 
 	ctx.JSON(http.StatusCreated, Domain.AuthResponse{
-		Token: token,
-		User:  *user,
+		TokenPair: *tokens,
+		User:      *user,
 	})
 }
 
 func (c *Controller) HandleLogin(ctx *gin.Context) {
 	var req Domain.LoginRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = ctx.Error(Infrastructure.ValidationError(err))
 		return
 	}
 
-	user, token, err := c.userUseCase.Login(req)
+	user, tokens, err := c.userUseCase.Login(ctx.Request.Context(), req)
 	if err != nil {
-		if err == Domain.ErrInvalidCredentials || err == Domain.ErrNotFound {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate user"})
+		respondError(ctx, err, nil)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, Domain.AuthResponse{
-		Token: token,
-		User:  *user,
+		TokenPair: *tokens,
+		User:      *user,
 	})
 }
 
+func (c *Controller) HandleRefreshToken(ctx *gin.Context) {
+	var req Domain.RefreshRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		_ = ctx.Error(Infrastructure.ValidationError(err))
+		return
+	}
+
+	tokens, err := c.userUseCase.RefreshTokens(ctx.Request.Context(), req.RefreshToken)
+	if err != nil {
+		respondError(ctx, err, errorMessages{Domain.ErrInvalidToken: "Invalid or expired refresh token"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+func (c *Controller) HandleLogout(ctx *gin.Context) {
+	claims, err := c.authMiddleware.GetClaimsFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	err = c.userUseCase.Logout(ctx.Request.Context(), claims.SessionID, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		respondError(ctx, err, nil)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+func (c *Controller) HandleJWKS(ctx *gin.Context) {
+	// Let verifiers cache the keys, but pick up rotations reasonably fast
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, c.jwtService.JWKS())
+}
+
 func (c *Controller) HandleGetTasks(ctx *gin.Context) {
-	userID, err := c.authMiddleware.GetUserIDFromContext(ctx)
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not identify user"})
+		_ = ctx.Error(err)
 		return
 	}
 
-	role, _ := ctx.Get("role")
-	isAdmin := role == string(Domain.RoleAdmin)
+	opts, err := parseTaskListOptions(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
 
-	tasks, err := c.taskUseCase.GetAllTasks(userID, isAdmin)
+	page, err := c.taskUseCase.GetAllTasks(ctx.Request.Context(), policy, opts)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tasks"})
+		respondError(ctx, err, listMessages)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, Domain.TaskResponse{Tasks: tasks})
+	ctx.JSON(http.StatusOK, Domain.TaskListResponse{
+		Tasks:      page.Tasks,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	})
+}
+
+func (c *Controller) HandleSearchTasks(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	opts, err := parseTaskSearchOptions(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	hits, err := c.taskUseCase.SearchTasks(ctx.Request.Context(), policy, opts)
+	if err != nil {
+		respondError(ctx, err, errorMessages{Domain.ErrInvalidInput: "Invalid search query"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Domain.TaskSearchResponse{Hits: hits})
 }
 
 func (c *Controller) HandleGetTask(ctx *gin.Context) {
-	userID, err := c.authMiddleware.GetUserIDFromContext(ctx)
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not identify user"})
+		_ = ctx.Error(err)
 		return
 	}
 
 	idStr := ctx.Param("id")
 
-	role, _ := ctx.Get("role")
-	isAdmin := role == string(Domain.RoleAdmin)
-
-	task, err := c.taskUseCase.GetTask(idStr, userID, isAdmin)
+	task, err := c.taskUseCase.GetTask(ctx.Request.Context(), idStr, policy)
 	if err != nil {
-		if err == Domain.ErrInvalidID {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
-			return
-		}
-		if err == Domain.ErrNotFound {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get task"})
+		respondError(ctx, err, taskMessages)
 		return
 	}
 
+	setTaskETag(ctx, task)
 	ctx.JSON(http.StatusOK, Domain.TaskResponse{Task: task})
 }
 
 func (c *Controller) HandleCreateTask(ctx *gin.Context) {
-	userID, err := c.authMiddleware.GetUserIDFromContext(ctx)
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not identify user"})
+		_ = ctx.Error(err)
 		return
 	}
 
 	var req Domain.CreateTaskRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = ctx.Error(Infrastructure.ValidationError(err))
 		return
 	}
 
-	task, err := c.taskUseCase.CreateTask(req, userID)
+	task, err := c.taskUseCase.CreateTask(ctx.Request.Context(), req, policy)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		respondError(ctx, err, taskMessages)
 		return
 	}
 
+	setTaskETag(ctx, task)
 	ctx.JSON(http.StatusCreated, Domain.TaskResponse{Task: task})
 }
 
 func (c *Controller) HandleUpdateTask(ctx *gin.Context) {
-	userID, err := c.authMiddleware.GetUserIDFromContext(ctx)
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not identify user"})
+		_ = ctx.Error(err)
 		return
 	}
 
 	idStr := ctx.Param("id")
 
+	revision, err := c.ifMatchRevision(ctx, policy)
+	if err != nil {
+		respondError(ctx, err, taskMessages)
+		return
+	}
+
 	var req Domain.UpdateTaskRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = ctx.Error(Infrastructure.ValidationError(err))
 		return
 	}
 
-	role, _ := ctx.Get("role")
-	isAdmin := role == string(Domain.RoleAdmin)
-
-	updatedTask, err := c.taskUseCase.UpdateTask(idStr, userID, isAdmin, req)
+	updatedTask, err := c.taskUseCase.UpdateTask(ctx.Request.Context(), idStr, policy, req, revision)
 	if err != nil {
-		if err == Domain.ErrInvalidID {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
-			return
-		}
-		if err == Domain.ErrNotFound {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		respondError(ctx, err, taskMessages)
 		return
 	}
 
+	setTaskETag(ctx, updatedTask)
 	ctx.JSON(http.StatusOK, Domain.TaskResponse{Task: updatedTask})
 }
 
+// HandlePatchTask applies a JSON Merge Patch or JSON Patch, chosen by the
+// Content-Type header. force=true in the query string allows completing a
+// blocked task.
+func (c *Controller) HandlePatchTask(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	format := Domain.PatchFormat(ctx.ContentType())
+	if format != Domain.PatchFormatMerge && format != Domain.PatchFormatJSON {
+		ctx.Header("Accept-Patch", string(Domain.PatchFormatMerge)+", "+string(Domain.PatchFormatJSON))
+		_ = ctx.Error(Domain.ErrUnsupportedFormat.WithMessage("Content-Type must be application/merge-patch+json or application/json-patch+json"))
+		return
+	}
+
+	revision, err := c.ifMatchRevision(ctx, policy)
+	if err != nil {
+		respondError(ctx, err, taskMessages)
+		return
+	}
+
+	document, err := ctx.GetRawData()
+	if err != nil {
+		_ = ctx.Error(Domain.ErrInvalidRequest.WithMessage("Could not read request body"))
+		return
+	}
+
+	task, err := c.taskUseCase.PatchTask(ctx.Request.Context(), ctx.Param("id"), policy, Domain.TaskPatch{
+		Format:   format,
+		Document: document,
+		Force:    ctx.Query("force") == "true",
+	}, revision)
+	if err != nil {
+		respondError(ctx, err, taskMessages)
+		return
+	}
+
+	setTaskETag(ctx, task)
+	ctx.JSON(http.StatusOK, Domain.TaskResponse{Task: task})
+}
+
 func (c *Controller) HandleDeleteTask(ctx *gin.Context) {
-	userID, err := c.authMiddleware.GetUserIDFromContext(ctx)
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not identify user"})
+		_ = ctx.Error(err)
 		return
 	}
 
 	idStr := ctx.Param("id")
 
-	role, _ := ctx.Get("role")
-	isAdmin := role == string(Domain.RoleAdmin)
+	revision, err := c.ifMatchRevision(ctx, policy)
+	if err != nil {
+		respondError(ctx, err, taskMessages)
+		return
+	}
 
-	err = c.taskUseCase.DeleteTask(idStr, userID, isAdmin)
+	err = c.taskUseCase.DeleteTask(ctx.Request.Context(), idStr, policy, revision)
 	if err != nil {
-		if err == Domain.ErrInvalidID {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
-			return
-		}
-		if err == Domain.ErrNotFound {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-			return
-		}
-		if err == Domain.ErrUnauthorized {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized to delete this task"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
+		respondError(ctx, err, taskMessages.with(Domain.ErrUnauthorized, "Unauthorized to delete this task"))
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
 }
+
+func (c *Controller) HandleShareTask(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	var req Domain.ShareTaskRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		_ = ctx.Error(Infrastructure.ValidationError(err))
+		return
+	}
+
+	task, err := c.taskUseCase.ShareTask(ctx.Request.Context(), ctx.Param("id"), policy, req)
+	if err != nil {
+		respondError(ctx, err, shareMessages)
+		return
+	}
+
+	setTaskETag(ctx, task)
+	ctx.JSON(http.StatusOK, Domain.TaskResponse{Task: task})
+}
+
+func (c *Controller) HandleUnshareTask(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	task, err := c.taskUseCase.UnshareTask(ctx.Request.Context(), ctx.Param("id"), policy, ctx.Param("userId"))
+	if err != nil {
+		respondError(ctx, err, taskMessages.with(Domain.ErrInvalidID, "Invalid task or user ID format"))
+		return
+	}
+
+	setTaskETag(ctx, task)
+	ctx.JSON(http.StatusOK, Domain.TaskResponse{Task: task})
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"maps"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/auth/Domain"
+)
+
+// errorMessages gives common errors messages that say what a handler was
+// working on, such as which kind of resource was not found
+type errorMessages map[*Domain.Error]string
+
+// with returns a copy of m that gives err the message
+func (m errorMessages) with(err *Domain.Error, message string) errorMessages {
+	copied := maps.Clone(m)
+	if copied == nil {
+		copied = errorMessages{}
+	}
+	copied[err] = message
+	return copied
+}
+
+var (
+	taskMessages = errorMessages{
+		Domain.ErrInvalidID:    "Invalid task ID format",
+		Domain.ErrInvalidInput: "Invalid priority, tags, blockers or recurrence",
+		Domain.ErrNotFound:     "Task not found",
+	}
+	shareMessages = errorMessages{
+		Domain.ErrInvalidID:    "Invalid task ID format",
+		Domain.ErrInvalidInput: "Level must be \"viewer\" or \"editor\", and tasks cannot be shared with their owner",
+		Domain.ErrNotFound:     "Task or user not found",
+	}
+	revisionMessages = taskMessages.with(Domain.ErrNotFound, "Task or revision not found")
+	listMessages     = errorMessages{
+		Domain.ErrInvalidInput: "Invalid list parameters",
+	}
+	userMessages = errorMessages{
+		Domain.ErrInvalidID: "Invalid user ID format",
+		Domain.ErrNotFound:  "User not found",
+		Domain.ErrForbidden: "Admins cannot change or delete their own account",
+	}
+	webhookMessages = errorMessages{
+		Domain.ErrNotFound: "Webhook not found",
+	}
+)
+
+// apply returns err with the message m has for it, if any
+func (m errorMessages) apply(err error) error {
+	if domainErr, ok := err.(*Domain.Error); ok {
+		if message, ok := m[domainErr]; ok {
+			return domainErr.WithMessage(message)
+		}
+	}
+	return err
+}
+
+// respondError records err for Infrastructure.ErrorHandler to answer, with
+// the message messages has for it, if any
+func respondError(ctx *gin.Context, err error, messages errorMessages) {
+	_ = ctx.Error(messages.apply(err))
+}
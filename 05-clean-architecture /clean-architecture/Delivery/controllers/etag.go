@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/auth/Domain"
+)
+
+// setTaskETag sends the task's revision as a strong ETag, e.g. "3", for
+// clients to send back in If-Match
+func setTaskETag(ctx *gin.Context, task *Domain.Task) {
+	ctx.Header("ETag", strconv.Quote(strconv.Itoa(task.Revision)))
+}
+
+var errInvalidIfMatch = Domain.ErrInvalidRequest.WithMessage("If-Match must be a list of ETags such as \"3\"")
+
+// parseIfMatch returns the task revisions an If-Match header lists, or nil
+// if the request is unconditional (no header, or "*" for any revision).
+// ETags that cannot be a task revision, including weak ones, are left out
+// since they never match, so a header of only such ETags returns an empty
+// list.
+func parseIfMatch(ctx *gin.Context) ([]int, error) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	revisions := []int{}
+	for rest := header; rest != ""; {
+		rest = strings.TrimLeft(rest, " \t,")
+		if rest == "" {
+			break
+		}
+
+		weak := strings.HasPrefix(rest, "W/")
+		if weak {
+			rest = rest[2:]
+		}
+
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, errInvalidIfMatch
+		}
+		end := strings.IndexByte(rest[1:], '"') + 1
+		if end == 0 {
+			return nil, errInvalidIfMatch
+		}
+		tag := rest[1:end]
+		rest = strings.TrimLeft(rest[end+1:], " \t")
+		if rest != "" && rest[0] != ',' {
+			return nil, errInvalidIfMatch
+		}
+
+		if revision, err := strconv.Atoi(tag); err == nil && revision >= 1 && !weak {
+			revisions = append(revisions, revision)
+		}
+	}
+	return revisions, nil
+}
+
+// ifMatchRevision returns the revision a write to the task is conditional
+// on, or 0 if it is unconditional. When If-Match lists several revisions,
+// the one the task is at is used, so the write goes through if any matches.
+func (c *Controller) ifMatchRevision(ctx *gin.Context, policy *Domain.Policy) (int, error) {
+	revisions, err := parseIfMatch(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	switch len(revisions) {
+	case 0:
+		if revisions == nil {
+			return 0, nil
+		}
+		return 0, Domain.ErrRevisionMismatch
+	case 1:
+		return revisions[0], nil
+	}
+
+	task, err := c.taskUseCase.GetTask(ctx.Request.Context(), ctx.Param("id"), policy)
+	if err != nil {
+		return 0, err
+	}
+	if !slices.Contains(revisions, task.Revision) {
+		return 0, Domain.ErrRevisionMismatch
+	}
+	return task.Revision, nil
+}
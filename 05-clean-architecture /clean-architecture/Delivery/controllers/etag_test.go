@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/auth/Domain"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    []int
+		wantErr error
+	}{
+		{"", nil, nil},
+		{"*", nil, nil},
+		{`"3"`, []int{3}, nil},
+		{` "3" `, []int{3}, nil},
+		{`"1", "2"`, []int{1, 2}, nil},
+		{`"1","2",,"3"`, []int{1, 2, 3}, nil},
+		{`W/"3", "4"`, []int{4}, nil},
+		{`W/"3"`, []int{}, nil},
+		{`"0"`, []int{}, nil},
+		{`"abc", "-1"`, []int{}, nil},
+		{`"a,b", "5"`, []int{5}, nil},
+
+		{`3`, nil, Domain.ErrInvalidRequest},
+		{`"3`, nil, Domain.ErrInvalidRequest},
+		{`"3" "4"`, nil, Domain.ErrInvalidRequest},
+		{`"1", *`, nil, Domain.ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("PUT", "/tasks/1", nil)
+			ctx.Request.Header.Set("If-Match", tt.header)
+
+			got, err := parseIfMatch(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseIfMatch error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIfMatch = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// parseTaskListOptions reads the GET /tasks query string:
+//
+//	limit, cursor, sort (e.g. "created_at" or "-updated_at"), completed,
+//	created_after, created_before, updated_after, updated_before, title,
+//	due_before, overdue, priority, tag, include_shared
+func parseTaskListOptions(ctx *gin.Context) (Domain.TaskListOptions, error) {
+	var opts Domain.TaskListOptions
+
+	if limit := ctx.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > Domain.MaxTaskPageSize {
+			return opts, invalidQuery("limit must be a number between 1 and %d", Domain.MaxTaskPageSize)
+		}
+		opts.Limit = n
+	}
+
+	opts.Cursor = ctx.Query("cursor")
+
+	if sortBy := ctx.Query("sort"); sortBy != "" {
+		if strings.HasPrefix(sortBy, "-") {
+			opts.SortDesc = true
+			sortBy = sortBy[1:]
+		}
+		opts.SortBy = Domain.TaskSortField(sortBy)
+		if !opts.SortBy.IsValid() {
+			return opts, invalidQuery("cannot sort by %q", sortBy)
+		}
+	}
+
+	if completed := ctx.Query("completed"); completed != "" {
+		value, err := strconv.ParseBool(completed)
+		if err != nil {
+			return opts, invalidQuery("completed must be true or false")
+		}
+		opts.Filter.Completed = &value
+	}
+
+	timeParams := []struct {
+		name   string
+		target **time.Time
+	}{
+		{"created_after", &opts.Filter.CreatedAfter},
+		{"created_before", &opts.Filter.CreatedBefore},
+		{"updated_after", &opts.Filter.UpdatedAfter},
+		{"updated_before", &opts.Filter.UpdatedBefore},
+		{"due_before", &opts.Filter.DueBefore},
+	}
+	for _, param := range timeParams {
+		value := ctx.Query(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return opts, invalidQuery("%s must be an RFC 3339 timestamp", param.name)
+		}
+		*param.target = &t
+	}
+
+	opts.Filter.TitleContains = ctx.Query("title")
+
+	if overdue := ctx.Query("overdue"); overdue != "" {
+		value, err := strconv.ParseBool(overdue)
+		if err != nil {
+			return opts, invalidQuery("overdue must be true or false")
+		}
+		opts.Filter.Overdue = value
+	}
+
+	if priority := ctx.Query("priority"); priority != "" {
+		opts.Filter.Priority = Domain.Priority(priority)
+		if !opts.Filter.Priority.IsValid() {
+			return opts, invalidQuery("priority must be low, medium, high or urgent")
+		}
+	}
+
+	opts.Filter.Tag = ctx.Query("tag")
+
+	if includeShared := ctx.Query("include_shared"); includeShared != "" {
+		value, err := strconv.ParseBool(includeShared)
+		if err != nil {
+			return opts, invalidQuery("include_shared must be true or false")
+		}
+		opts.IncludeShared = value
+	}
+
+	return opts, nil
+}
+
+// parseTaskSearchOptions reads the GET /tasks/search query string:
+//
+//	q, limit, include_shared
+func parseTaskSearchOptions(ctx *gin.Context) (Domain.TaskSearchOptions, error) {
+	opts := Domain.TaskSearchOptions{Query: ctx.Query("q")}
+
+	if strings.TrimSpace(opts.Query) == "" {
+		return opts, invalidQuery("q is required")
+	}
+
+	if limit := ctx.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > Domain.MaxSearchLimit {
+			return opts, invalidQuery("limit must be a number between 1 and %d", Domain.MaxSearchLimit)
+		}
+		opts.Limit = n
+	}
+
+	if includeShared := ctx.Query("include_shared"); includeShared != "" {
+		value, err := strconv.ParseBool(includeShared)
+		if err != nil {
+			return opts, invalidQuery("include_shared must be true or false")
+		}
+		opts.IncludeShared = value
+	}
+
+	return opts, nil
+}
+
+// parseUserListOptions reads the GET /admin/users query string:
+//
+//	limit, cursor, q (username substring), role, disabled
+func parseUserListOptions(ctx *gin.Context) (Domain.UserListOptions, error) {
+	var opts Domain.UserListOptions
+
+	if limit := ctx.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > Domain.MaxUserPageSize {
+			return opts, invalidQuery("limit must be a number between 1 and %d", Domain.MaxUserPageSize)
+		}
+		opts.Limit = n
+	}
+
+	opts.Cursor = ctx.Query("cursor")
+	opts.Filter.UsernameContains = ctx.Query("q")
+
+	if role := ctx.Query("role"); role != "" {
+		opts.Filter.Role = Domain.Role(role)
+		if !opts.Filter.Role.IsValid() {
+			return opts, invalidQuery("unknown role %q", role)
+		}
+	}
+
+	if disabled := ctx.Query("disabled"); disabled != "" {
+		value, err := strconv.ParseBool(disabled)
+		if err != nil {
+			return opts, invalidQuery("disabled must be true or false")
+		}
+		opts.Filter.Disabled = &value
+	}
+
+	return opts, nil
+}
+
+// parseAuditListOptions reads the audit log query parameters:
+//
+//	limit, cursor, actor_id, action, entity_type, entity_id, after, before
+func parseAuditListOptions(ctx *gin.Context) (Domain.AuditListOptions, error) {
+	var opts Domain.AuditListOptions
+
+	if limit := ctx.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > Domain.MaxAuditPageSize {
+			return opts, invalidQuery("limit must be a number between 1 and %d", Domain.MaxAuditPageSize)
+		}
+		opts.Limit = n
+	}
+
+	opts.Cursor = ctx.Query("cursor")
+	opts.Filter.Action = Domain.AuditAction(ctx.Query("action"))
+	opts.Filter.EntityType = ctx.Query("entity_type")
+
+	idParams := []struct {
+		name   string
+		target **primitive.ObjectID
+	}{
+		{"actor_id", &opts.Filter.ActorID},
+		{"entity_id", &opts.Filter.EntityID},
+	}
+	for _, param := range idParams {
+		value := ctx.Query(param.name)
+		if value == "" {
+			continue
+		}
+		id, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return opts, invalidQuery("%s must be a valid ID", param.name)
+		}
+		*param.target = &id
+	}
+
+	timeParams := []struct {
+		name   string
+		target **time.Time
+	}{
+		{"after", &opts.Filter.After},
+		{"before", &opts.Filter.Before},
+	}
+	for _, param := range timeParams {
+		value := ctx.Query(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return opts, invalidQuery("%s must be an RFC 3339 timestamp", param.name)
+		}
+		*param.target = &t
+	}
+
+	return opts, nil
+}
+
+// invalidQuery reports a query parameter that could not be read
+func invalidQuery(format string, args ...any) error {
+	return Domain.ErrInvalidRequest.WithMessage(fmt.Sprintf(format, args...))
+}
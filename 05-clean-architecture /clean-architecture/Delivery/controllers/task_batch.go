@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"taskmanager/auth/Domain"
+	"taskmanager/auth/Infrastructure"
+)
+
+// HandleTaskMethod serves custom methods on the task collection, such as
+// POST /tasks:batch. Gin cannot route a literal colon, so they share one
+// wildcard route and are told apart here.
+func (c *Controller) HandleTaskMethod(ctx *gin.Context) {
+	switch strings.TrimPrefix(ctx.Request.URL.Path, "/tasks") {
+	case ":batch":
+		c.HandleBatchTasks(ctx)
+	default:
+		_ = ctx.Error(Domain.ErrNotFound.WithMessage("Route not found"))
+	}
+}
+
+func (c *Controller) HandleBatchTasks(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	var req Domain.BatchTaskRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		_ = ctx.Error(Infrastructure.ValidationError(err))
+		return
+	}
+
+	batch := Domain.BatchRequest{Atomic: req.Atomic}
+	for i, op := range req.Operations {
+		operation, err := parseBatchOperation(op)
+		if err != nil {
+			_ = ctx.Error(err.InField(fmt.Sprintf("operations[%d]", i)))
+			return
+		}
+		batch.Operations = append(batch.Operations, operation)
+	}
+
+	results, err := c.taskUseCase.RunBatch(ctx.Request.Context(), policy, batch)
+	if err != nil {
+		respondError(ctx, err, errorMessages{Domain.ErrInvalidInput: fmt.Sprintf("A batch takes 1 to %d operations", Domain.MaxBatchOperations)})
+		return
+	}
+
+	response := Domain.BatchTaskResponse{Results: make([]Domain.BatchOperationResponse, len(results))}
+	for i, result := range results {
+		item := Domain.BatchOperationResponse{Index: i, Op: result.Op, Task: result.Task}
+		switch {
+		case result.Err != nil:
+			problem := taskProblem(result.Err)
+			item.Status, item.Code, item.Error = problem.Status, problem.Code, problem.Detail
+			response.Failed++
+		case result.Op == Domain.BatchCreate:
+			item.Status = http.StatusCreated
+			response.Succeeded++
+		default:
+			item.Status = http.StatusOK
+			response.Succeeded++
+		}
+		response.Results[i] = item
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// parseBatchOperation decodes and validates the task of one operation like
+// the single-task endpoints would. Errors name fields within the operation.
+func parseBatchOperation(op Domain.BatchTaskOperation) (Domain.BatchOperation, *Domain.Error) {
+	operation := Domain.BatchOperation{Op: op.Op, ID: op.ID, Revision: op.Revision}
+
+	if op.Op != Domain.BatchCreate && op.ID == "" {
+		return operation, Domain.InvalidFields(Domain.FieldError{Field: "id", Message: "is required"})
+	}
+
+	var task interface{}
+	switch op.Op {
+	case Domain.BatchCreate:
+		operation.Create = &Domain.CreateTaskRequest{}
+		task = operation.Create
+	case Domain.BatchUpdate:
+		operation.Update = &Domain.UpdateTaskRequest{}
+		task = operation.Update
+	default:
+		return operation, nil
+	}
+
+	if len(op.Task) == 0 {
+		return operation, Domain.InvalidFields(Domain.FieldError{Field: "task", Message: "is required"})
+	}
+	if err := json.Unmarshal(op.Task, task); err != nil {
+		return operation, Infrastructure.ValidationError(err).InField("task")
+	}
+	if err := binding.Validator.ValidateStruct(task); err != nil {
+		return operation, Infrastructure.ValidationError(err).InField("task")
+	}
+	return operation, nil
+}
+
+// taskProblem describes an error from a task operation the way the
+// single-task endpoints answer it
+func taskProblem(err error) Infrastructure.Problem {
+	return Infrastructure.NewProblem(taskMessages.apply(err))
+}
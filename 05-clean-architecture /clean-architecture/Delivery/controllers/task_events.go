@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"taskmanager/auth/Domain"
+)
+
+// eventHeartbeat is how often an idle event stream sends something, so
+// proxies keep it open. Streams check their session on each heartbeat.
+const eventHeartbeat = 20 * time.Second
+
+// eventResetMessage tells a resuming client that it missed events and
+// should reload its tasks
+var eventResetMessage = gin.H{"type": "reset"}
+
+// HandleTaskEvents streams task events as Server-Sent Events. Clients
+// resume with the Last-Event-ID header, which browsers send by themselves
+// when they reconnect, or the last_event_id parameter.
+func (c *Controller) HandleTaskEvents(ctx *gin.Context) {
+	lastEventID := ctx.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = ctx.Query("last_event_id")
+	}
+
+	sub, ok := c.subscribeTaskEvents(ctx, lastEventID)
+	if !ok {
+		return
+	}
+	defer sub.Cancel()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	fmt.Fprint(ctx.Writer, "retry: 3000\n\n")
+	if sub.Incomplete {
+		fmt.Fprint(ctx.Writer, "event: reset\ndata: {\"type\":\"reset\"}\n\n")
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	expiry := c.streamExpiry(ctx)
+	defer expiry.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-expiry.C:
+			return
+		case <-heartbeat.C:
+			if c.authMiddleware.CheckSession(ctx) != nil {
+				return
+			}
+			fmt.Fprint(ctx.Writer, ": heartbeat\n\n")
+		case event, ok := <-sub.Events:
+			// A closed stream makes the client reconnect and resume
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(ctx.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+// HandleTaskEventsWebSocket streams task events over a WebSocket as JSON
+// text messages. Clients resume with the last_event_id parameter.
+func (c *Controller) HandleTaskEventsWebSocket(ctx *gin.Context) {
+	sub, ok := c.subscribeTaskEvents(ctx, ctx.Query("last_event_id"))
+	if !ok {
+		return
+	}
+	defer sub.Cancel()
+
+	// Browsers cannot authenticate WebSockets with a header, so the token
+	// is what protects this endpoint rather than the origin
+	server := websocket.Server{Handler: func(conn *websocket.Conn) {
+		// Reading handles pings and tells when the client goes away;
+		// clients have nothing else to send
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			var message string
+			for websocket.Message.Receive(conn, &message) == nil {
+			}
+		}()
+
+		if sub.Incomplete && websocket.JSON.Send(conn, eventResetMessage) != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(eventHeartbeat)
+		defer heartbeat.Stop()
+		expiry := c.streamExpiry(ctx)
+		defer expiry.Stop()
+
+		for {
+			select {
+			case <-closed:
+				return
+			case <-expiry.C:
+				return
+			case <-heartbeat.C:
+				if c.authMiddleware.CheckSession(ctx) != nil {
+					return
+				}
+				conn.PayloadType = websocket.PingFrame
+				_, err := conn.Write(nil)
+				conn.PayloadType = websocket.TextFrame
+				if err != nil {
+					return
+				}
+			case event, ok := <-sub.Events:
+				if !ok {
+					return
+				}
+				if websocket.JSON.Send(conn, event) != nil {
+					return
+				}
+			}
+		}
+	}}
+	server.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// streamExpiry fires when the access token an event stream was opened with
+// expires. The stream ends then, and the client reconnects with a new token.
+func (c *Controller) streamExpiry(ctx *gin.Context) *time.Timer {
+	timer := time.NewTimer(0)
+	timer.Stop()
+
+	claims, err := c.authMiddleware.GetClaimsFromContext(ctx)
+	if err == nil && claims.ExpiresAt != nil {
+		timer.Reset(time.Until(claims.ExpiresAt.Time))
+	}
+	return timer
+}
+
+// subscribeTaskEvents subscribes to the events of the caller's tasks, or
+// answers the request with an error
+func (c *Controller) subscribeTaskEvents(ctx *gin.Context, lastEventID string) (*Domain.TaskEventSubscription, bool) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return nil, false
+	}
+
+	includeShared := false
+	if value := ctx.Query("include_shared"); value != "" {
+		if includeShared, err = strconv.ParseBool(value); err != nil {
+			_ = ctx.Error(invalidQuery("include_shared must be true or false"))
+			return nil, false
+		}
+	}
+
+	sub, err := c.taskUseCase.SubscribeTaskEvents(policy, lastEventID, includeShared)
+	if err != nil {
+		respondError(ctx, err, nil)
+		return nil, false
+	}
+	return sub, true
+}
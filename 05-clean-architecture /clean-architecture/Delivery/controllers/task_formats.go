@@ -0,0 +1,401 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"taskmanager/auth/Domain"
+)
+
+// taskCSVColumns are the columns of exported CSV files. Imports read the
+// editable ones and skip the rest, so exported files can be imported again.
+var taskCSVColumns = []string{
+	"id", "title", "description", "completed", "due_at", "priority", "tags",
+	"recurrence", "parent_id", "created_at", "updated_at",
+}
+
+var importedCSVColumns = map[string]bool{
+	"title": true, "description": true, "completed": true, "due_at": true,
+	"priority": true, "tags": true, "recurrence": true,
+}
+
+// csvTagSeparator joins the tags of a task into one CSV cell
+const csvTagSeparator = ";"
+
+// maxImportLine is the longest JSON Lines row an import accepts
+const maxImportLine = 1 << 20
+
+// taskWriter writes tasks to an export file one at a time
+type taskWriter interface {
+	Write(task Domain.Task) error
+	// Close finishes the file; it does not close the underlying writer
+	Close() error
+}
+
+// taskFormatContentTypes are the media types of the task formats
+var taskFormatContentTypes = map[Domain.TaskFormat]string{
+	Domain.TaskFormatCSV:   "text/csv; charset=utf-8",
+	Domain.TaskFormatJSONL: "application/x-ndjson",
+	Domain.TaskFormatICS:   "text/calendar; charset=utf-8",
+}
+
+func newTaskWriter(format Domain.TaskFormat, w io.Writer) (taskWriter, error) {
+	switch format {
+	case Domain.TaskFormatCSV:
+		writer := &csvTaskWriter{csv: csv.NewWriter(w)}
+		return writer, writer.csv.Write(taskCSVColumns)
+	case Domain.TaskFormatJSONL:
+		return &jsonlTaskWriter{json: json.NewEncoder(w)}, nil
+	case Domain.TaskFormatICS:
+		writer := &icsTaskWriter{w: bufio.NewWriter(w)}
+		writer.line("BEGIN:VCALENDAR")
+		writer.line("VERSION:2.0")
+		writer.line("PRODID:-//taskmanager//tasks//EN")
+		return writer, nil
+	default:
+		return nil, Domain.ErrInvalidInput
+	}
+}
+
+type csvTaskWriter struct {
+	csv *csv.Writer
+}
+
+func (w *csvTaskWriter) Write(task Domain.Task) error {
+	record := []string{
+		task.ID.Hex(),
+		csvEscape(task.Title),
+		csvEscape(task.Description),
+		strconv.FormatBool(task.Completed),
+		"",
+		string(task.Priority),
+		csvEscape(strings.Join(task.Tags, csvTagSeparator)),
+		task.Recurrence,
+		"",
+		task.CreatedAt.UTC().Format(time.RFC3339),
+		task.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	if task.DueAt != nil {
+		record[4] = task.DueAt.UTC().Format(time.RFC3339)
+	}
+	if task.ParentID != nil {
+		record[8] = task.ParentID.Hex()
+	}
+	return w.csv.Write(record)
+}
+
+// csvFormulaPrefixes start cells that spreadsheets run as formulas
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvEscape keeps spreadsheets from running a cell as a formula by putting
+// a ' in front of it. Cells that already start with ' and would otherwise
+// be unescaped on import get one too, so csvUnescape restores every cell.
+func csvEscape(cell string) string {
+	if csvNeedsEscape(cell) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// csvUnescape undoes csvEscape
+func csvUnescape(cell string) string {
+	if rest, ok := strings.CutPrefix(cell, "'"); ok && csvNeedsEscape(rest) {
+		return rest
+	}
+	return cell
+}
+
+func csvNeedsEscape(cell string) bool {
+	if cell == "" {
+		return false
+	}
+	if rest, ok := strings.CutPrefix(cell, "'"); ok {
+		return csvNeedsEscape(rest)
+	}
+	return strings.ContainsRune(csvFormulaPrefixes, rune(cell[0]))
+}
+
+func (w *csvTaskWriter) Close() error {
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+type jsonlTaskWriter struct {
+	json *json.Encoder
+}
+
+func (w *jsonlTaskWriter) Write(task Domain.Task) error {
+	return w.json.Encode(task)
+}
+
+func (w *jsonlTaskWriter) Close() error {
+	return nil
+}
+
+// icsTaskWriter writes tasks as the to-dos (VTODO) of an iCalendar file
+// (RFC 5545)
+type icsTaskWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// icsPriorities maps priorities to iCalendar's scale of 1 (highest) to 9
+var icsPriorities = map[Domain.Priority]int{
+	Domain.PriorityUrgent: 1,
+	Domain.PriorityHigh:   3,
+	Domain.PriorityMedium: 5,
+	Domain.PriorityLow:    9,
+}
+
+func (w *icsTaskWriter) Write(task Domain.Task) error {
+	priority := task.Priority
+	if priority == "" {
+		priority = Domain.PriorityMedium
+	}
+	status := "NEEDS-ACTION"
+	if task.Completed {
+		status = "COMPLETED"
+	}
+
+	w.line("BEGIN:VTODO")
+	w.line("UID:" + icsUID(task.ID.Hex()))
+	w.line("DTSTAMP:" + icsTime(task.UpdatedAt))
+	w.line("CREATED:" + icsTime(task.CreatedAt))
+	w.line("LAST-MODIFIED:" + icsTime(task.UpdatedAt))
+	w.line("SUMMARY:" + icsText(task.Title))
+	if task.Description != "" {
+		w.line("DESCRIPTION:" + icsText(task.Description))
+	}
+	if task.DueAt != nil {
+		w.line("DUE:" + icsTime(*task.DueAt))
+	}
+	w.line("STATUS:" + status)
+	w.line("PRIORITY:" + strconv.Itoa(icsPriorities[priority]))
+	if len(task.Tags) > 0 {
+		categories := make([]string, len(task.Tags))
+		for i, tag := range task.Tags {
+			categories[i] = icsText(tag)
+		}
+		w.line("CATEGORIES:" + strings.Join(categories, ","))
+	}
+	if task.Recurrence != "" {
+		w.line("RRULE:" + task.Recurrence)
+	}
+	if task.ParentID != nil {
+		w.line("RELATED-TO:" + icsUID(task.ParentID.Hex()))
+	}
+	w.line("END:VTODO")
+	return w.err
+}
+
+func (w *icsTaskWriter) Close() error {
+	w.line("END:VCALENDAR")
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// line writes a content line, folded after 75 bytes without splitting a
+// character
+func (w *icsTaskWriter) line(text string) {
+	if w.err != nil {
+		return
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range text {
+		size := utf8.RuneLen(r)
+		if width+size > 75 {
+			// The leading space of the continuation counts towards its width
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+
+	_, w.err = w.w.WriteString(b.String())
+}
+
+func icsUID(id string) string {
+	return id + "@taskmanager"
+}
+
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icsText escapes a TEXT value
+func icsText(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(text)
+}
+
+// rowError is why a row of an import file could not be read
+type rowError string
+
+func (e rowError) Error() string {
+	return string(e)
+}
+
+// readTaskRows reads the tasks of an import file. Rows that cannot be read
+// carry an error; files that cannot be read at all return one.
+func readTaskRows(format Domain.TaskFormat, r io.Reader) ([]Domain.TaskImportRow, error) {
+	switch format {
+	case Domain.TaskFormatCSV:
+		return readCSVTaskRows(r)
+	case Domain.TaskFormatJSONL:
+		return readJSONLTaskRows(r)
+	default:
+		return nil, fmt.Errorf("tasks can only be imported from csv or jsonl")
+	}
+}
+
+func readCSVTaskRows(r io.Reader) ([]Domain.TaskImportRow, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(taskCSVColumns, name) {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("the title column is required")
+	}
+
+	var rows []Domain.TaskImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && parseErr.Err == csv.ErrFieldCount {
+			rows = append(rows, Domain.TaskImportRow{
+				Line: parseErr.StartLine,
+				Err:  rowError(fmt.Sprintf("Row has %d columns instead of %d", len(record), len(header))),
+			})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := Domain.TaskImportRow{Line: line}
+
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && importedCSVColumns[name] {
+				return strings.TrimSpace(csvUnescape(record[i]))
+			}
+			return ""
+		}
+		row.Task, row.Err = parseCSVTask(cell)
+		rows = append(rows, row)
+	}
+}
+
+func parseCSVTask(cell func(name string) string) (Domain.CreateTaskRequest, error) {
+	task := Domain.CreateTaskRequest{
+		Title:       cell("title"),
+		Description: cell("description"),
+		Priority:    Domain.Priority(strings.ToLower(cell("priority"))),
+		Recurrence:  cell("recurrence"),
+	}
+
+	if completed := cell("completed"); completed != "" {
+		value, err := strconv.ParseBool(completed)
+		if err != nil {
+			return task, &Domain.FieldError{Field: "completed", Message: "must be true or false"}
+		}
+		task.Completed = value
+	}
+
+	if dueAt := cell("due_at"); dueAt != "" {
+		value, err := time.Parse(time.RFC3339, dueAt)
+		if err != nil {
+			return task, &Domain.FieldError{Field: "due_at", Message: "must be an RFC 3339 timestamp"}
+		}
+		task.DueAt = &value
+	}
+
+	if tags := cell("tags"); tags != "" {
+		task.Tags = strings.Split(tags, csvTagSeparator)
+	}
+
+	return task, nil
+}
+
+func readJSONLTaskRows(r io.Reader) ([]Domain.TaskImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxImportLine)
+
+	var rows []Domain.TaskImportRow
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := Domain.TaskImportRow{Line: line}
+		if err := json.Unmarshal(data, &row.Task); err != nil {
+			row.Err = jsonRowError(err)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			return nil, fmt.Errorf("lines must be at most %d bytes", maxImportLine)
+		}
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// jsonRowError describes why a JSON Lines row could not be decoded
+func jsonRowError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &Domain.FieldError{Field: typeErr.Field, Message: "has the wrong type"}
+	}
+	var timeErr *time.ParseError
+	if errors.As(err, &timeErr) {
+		return &Domain.FieldError{Field: "due_at", Message: "must be an RFC 3339 timestamp"}
+	}
+	return rowError("Row is not a JSON object")
+}
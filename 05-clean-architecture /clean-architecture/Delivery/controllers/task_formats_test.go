@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+func TestCSVFormulaEscaping(t *testing.T) {
+	tests := []struct {
+		title string
+		// want is the title cell as exported
+		want string
+	}{
+		{"Plain title", "Plain title"},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1", "'+1"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"\rcmd", "'\rcmd"},
+		{"'quoted", "'quoted"},
+		{"'=already escaped", "''=already escaped"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			var file bytes.Buffer
+			writer, err := newTaskWriter(Domain.TaskFormatCSV, &file)
+			if err != nil {
+				t.Fatalf("newTaskWriter: %v", err)
+			}
+			task := Domain.Task{ID: primitive.NewObjectID(), Title: tt.title, Description: tt.title, Tags: []string{"-x", "y"}}
+			if err := writer.Write(task); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			records, err := csv.NewReader(bytes.NewReader(file.Bytes())).ReadAll()
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if got := records[1][1]; got != tt.want {
+				t.Errorf("title cell = %q, want %q", got, tt.want)
+			}
+			if got := records[1][6]; got != "'-x;y" {
+				t.Errorf("tags cell = %q, want %q", got, "'-x;y")
+			}
+
+			// Imports take the escaping off again
+			rows, err := readTaskRows(Domain.TaskFormatCSV, bytes.NewReader(file.Bytes()))
+			if err != nil {
+				t.Fatalf("readTaskRows: %v", err)
+			}
+			// Cells are trimmed on import, like the title of a new task
+			want := strings.TrimSpace(tt.title)
+			if got := rows[0].Task; got.Title != want || got.Description != want || !slices.Equal(got.Tags, task.Tags) {
+				t.Errorf("imported %q, %q, %v; want %q, %q, %v", got.Title, got.Description, got.Tags, want, want, task.Tags)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/auth/Domain"
+)
+
+func (c *Controller) HandleGetTaskHistory(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	revisions, err := c.taskUseCase.GetTaskHistory(ctx.Request.Context(), ctx.Param("id"), policy)
+	if err != nil {
+		respondError(ctx, err, taskMessages)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Domain.TaskHistoryResponse{Revisions: revisions})
+}
+
+func (c *Controller) HandleGetTaskRevision(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	revision, ok := parseRevision(ctx)
+	if !ok {
+		return
+	}
+
+	rev, err := c.taskUseCase.GetTaskRevision(ctx.Request.Context(), ctx.Param("id"), revision, policy)
+	if err != nil {
+		respondError(ctx, err, revisionMessages)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Domain.TaskRevisionResponse{Revision: rev})
+}
+
+// HandleRestoreTaskRevision restores a revision. force=true in the query
+// string allows completing a blocked task.
+func (c *Controller) HandleRestoreTaskRevision(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	revision, ok := parseRevision(ctx)
+	if !ok {
+		return
+	}
+
+	task, err := c.taskUseCase.RestoreTaskRevision(ctx.Request.Context(), ctx.Param("id"), revision, policy, ctx.Query("force") == "true")
+	if err != nil {
+		respondError(ctx, err, revisionMessages)
+		return
+	}
+
+	setTaskETag(ctx, task)
+	ctx.JSON(http.StatusOK, Domain.TaskResponse{Task: task})
+}
+
+func (c *Controller) HandleGetTrash(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	opts, err := parseTaskListOptions(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	page, err := c.taskUseCase.ListTrash(ctx.Request.Context(), policy, opts)
+	if err != nil {
+		respondError(ctx, err, listMessages)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Domain.TaskListResponse{
+		Tasks:      page.Tasks,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	})
+}
+
+func (c *Controller) HandleUndeleteTask(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	task, err := c.taskUseCase.UndeleteTask(ctx.Request.Context(), ctx.Param("id"), policy)
+	if err != nil {
+		respondError(ctx, err, taskMessages.with(Domain.ErrNotFound, "Task not found in trash"))
+		return
+	}
+
+	setTaskETag(ctx, task)
+	ctx.JSON(http.StatusOK, Domain.TaskResponse{Task: task})
+}
+
+// parseRevision reads the :rev path parameter, answering 400 if it is not a
+// revision number
+func parseRevision(ctx *gin.Context) (int, bool) {
+	revision, err := strconv.Atoi(ctx.Param("rev"))
+	if err != nil || revision < 1 {
+		_ = ctx.Error(Domain.ErrInvalidRequest.WithMessage("Invalid revision number"))
+		return 0, false
+	}
+	return revision, true
+}
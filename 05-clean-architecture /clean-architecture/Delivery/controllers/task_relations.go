@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (c *Controller) HandleGetSubtree(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	node, err := c.taskUseCase.GetSubtree(ctx.Request.Context(), ctx.Param("id"), policy)
+	if err != nil {
+		respondError(ctx, err, taskMessages)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, node)
+}
+
+func (c *Controller) HandleGetDependencies(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	graph, err := c.taskUseCase.GetDependencyGraph(ctx.Request.Context(), ctx.Param("id"), policy)
+	if err != nil {
+		respondError(ctx, err, taskMessages)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, graph)
+}
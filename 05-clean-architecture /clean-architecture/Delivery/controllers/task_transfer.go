@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/auth/Domain"
+)
+
+// maxImportBytes is the largest import file accepted
+const maxImportBytes = 10 << 20
+
+// importMediaTypes are the content types an import format is recognized by
+// when there is no format parameter
+var importMediaTypes = map[string]Domain.TaskFormat{
+	"text/csv":             Domain.TaskFormatCSV,
+	"application/x-ndjson": Domain.TaskFormatJSONL,
+	"application/jsonl":    Domain.TaskFormatJSONL,
+}
+
+// HandleExportTasks streams the caller's tasks as a CSV, JSON Lines or
+// iCalendar file. Errors after the file has started can no longer change
+// the response, so they cut the file short instead.
+func (c *Controller) HandleExportTasks(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	format := Domain.TaskFormat(ctx.Query("format"))
+	if !format.IsValid() {
+		_ = ctx.Error(invalidQuery("format must be csv, jsonl or ics"))
+		return
+	}
+
+	opts, err := parseTaskListOptions(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	// Start the file once the first page has been read, so that errors
+	// reading it still get an error response
+	var writer taskWriter
+	start := func() error {
+		ctx.Header("Content-Type", taskFormatContentTypes[format])
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="tasks.%s"`, format))
+		ctx.Status(http.StatusOK)
+		writer, err = newTaskWriter(format, ctx.Writer)
+		return err
+	}
+
+	err = c.taskUseCase.ExportTasks(ctx.Request.Context(), policy, opts, func(task Domain.Task) error {
+		if writer == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return writer.Write(task)
+	})
+	if err == nil && writer == nil {
+		err = start()
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		return
+	}
+
+	if writer != nil {
+		_ = ctx.Error(err)
+		ctx.Abort()
+		return
+	}
+	respondError(ctx, err, listMessages)
+}
+
+// HandleImportTasks creates tasks from a CSV or JSON Lines file. Nothing is
+// imported unless every row is valid; with dry_run=true the rows are only
+// checked.
+func (c *Controller) HandleImportTasks(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	format := Domain.TaskFormat(ctx.Query("format"))
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+		format = importMediaTypes[mediaType]
+	}
+	if format != Domain.TaskFormatCSV && format != Domain.TaskFormatJSONL {
+		_ = ctx.Error(Domain.ErrUnsupportedFormat.WithMessage("Import a text/csv or application/x-ndjson file, or set format to csv or jsonl"))
+		return
+	}
+
+	dryRun := false
+	if value := ctx.Query("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			_ = ctx.Error(invalidQuery("dry_run must be true or false"))
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportBytes)
+	rows, err := readTaskRows(format, body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			_ = ctx.Error(Domain.ErrPayloadTooLarge.WithMessage(fmt.Sprintf("Import files must be at most %d MB", maxImportBytes>>20)))
+			return
+		}
+		_ = ctx.Error(Domain.ErrInvalidRequest.WithMessage("Invalid import file: " + err.Error()))
+		return
+	}
+
+	results, err := c.taskUseCase.ImportTasks(ctx.Request.Context(), policy, rows, dryRun)
+	if err != nil {
+		respondError(ctx, err, errorMessages{Domain.ErrInvalidInput: fmt.Sprintf("An import takes 1 to %d rows", Domain.MaxImportRows)})
+		return
+	}
+
+	response := Domain.TaskImportResponse{
+		DryRun: dryRun,
+		Rows:   len(results),
+		Errors: []Domain.TaskImportError{},
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			response.Errors = append(response.Errors, importError(result))
+		}
+		if result.Task != nil {
+			response.Tasks = append(response.Tasks, *result.Task)
+		}
+	}
+	response.Imported = len(response.Tasks)
+
+	switch {
+	case len(response.Errors) > 0:
+		ctx.JSON(http.StatusUnprocessableEntity, response)
+	case dryRun:
+		ctx.JSON(http.StatusOK, response)
+	default:
+		ctx.JSON(http.StatusCreated, response)
+	}
+}
+
+// importError describes why a row was not imported
+func importError(result Domain.TaskImportResult) Domain.TaskImportError {
+	var readErr rowError
+	if errors.As(result.Err, &readErr) {
+		return Domain.TaskImportError{Line: result.Line, Code: Domain.CodeInvalidInput, Error: readErr.Error()}
+	}
+
+	problem := taskProblem(result.Err)
+	item := Domain.TaskImportError{Line: result.Line, Code: problem.Code, Error: problem.Detail}
+	if len(problem.Errors) > 0 {
+		item.Field = problem.Errors[0].Field
+	}
+	return item
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/auth/Domain"
+	"taskmanager/auth/Infrastructure"
+)
+
+func (c *Controller) HandleCreateWebhook(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	var req Domain.CreateWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		_ = ctx.Error(Infrastructure.ValidationError(err))
+		return
+	}
+
+	webhook, secret, err := c.webhookUseCase.CreateWebhook(ctx.Request.Context(), req, policy)
+	if err != nil {
+		respondError(ctx, err, nil)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, Domain.WebhookSecretResponse{Webhook: webhook, Secret: secret})
+}
+
+func (c *Controller) HandleListWebhooks(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	webhooks, err := c.webhookUseCase.ListWebhooks(ctx.Request.Context(), policy)
+	if err != nil {
+		respondError(ctx, err, nil)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Domain.WebhookListResponse{Webhooks: webhooks})
+}
+
+func (c *Controller) HandleGetWebhook(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	webhook, err := c.webhookUseCase.GetWebhook(ctx.Request.Context(), ctx.Param("id"), policy)
+	if err != nil {
+		respondError(ctx, err, webhookMessages)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Domain.WebhookResponse{Webhook: webhook})
+}
+
+func (c *Controller) HandleDeleteWebhook(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	if err := c.webhookUseCase.DeleteWebhook(ctx.Request.Context(), ctx.Param("id"), policy); err != nil {
+		respondError(ctx, err, webhookMessages)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+func (c *Controller) HandleListWebhookDeliveries(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	limit, ok := parseWebhookDeliveryLimit(ctx)
+	if !ok {
+		return
+	}
+
+	status := Domain.WebhookDeliveryStatus(ctx.Query("status"))
+	if status != "" && !status.IsValid() {
+		_ = ctx.Error(invalidQuery("status must be pending, succeeded or failed"))
+		return
+	}
+
+	deliveries, err := c.webhookUseCase.ListDeliveries(ctx.Request.Context(), ctx.Param("id"), policy, status, limit)
+	if err != nil {
+		respondError(ctx, err, webhookMessages)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Domain.WebhookDeliveryListResponse{Deliveries: deliveries})
+}
+
+func (c *Controller) HandleListWebhookDeadLetters(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	limit, ok := parseWebhookDeliveryLimit(ctx)
+	if !ok {
+		return
+	}
+
+	deliveries, err := c.webhookUseCase.ListDeadLetters(ctx.Request.Context(), policy, limit)
+	if err != nil {
+		respondError(ctx, err, nil)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Domain.WebhookDeliveryListResponse{Deliveries: deliveries})
+}
+
+func (c *Controller) HandleRedeliverWebhook(ctx *gin.Context) {
+	policy, err := c.authMiddleware.GetPolicyFromContext(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	delivery, err := c.webhookUseCase.Redeliver(ctx.Request.Context(), ctx.Param("id"), policy)
+	if err != nil {
+		respondError(ctx, err, webhookMessages.with(Domain.ErrNotFound, "Delivery or its webhook not found"))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, Domain.WebhookDeliveryResponse{Delivery: delivery})
+}
+
+func parseWebhookDeliveryLimit(ctx *gin.Context) (int, bool) {
+	limit := ctx.Query("limit")
+	if limit == "" {
+		return 0, true
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 || n > Domain.MaxWebhookDeliveryPageSize {
+		_ = ctx.Error(invalidQuery("limit must be a number between 1 and %d", Domain.MaxWebhookDeliveryPageSize))
+		return 0, false
+	}
+	return n, true
+}
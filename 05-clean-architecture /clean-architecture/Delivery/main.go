@@ -2,14 +2,24 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"taskmanager/auth/Delivery/config"
 	"taskmanager/auth/Delivery/controllers"
 	"taskmanager/auth/Delivery/routers"
+	"taskmanager/auth/Delivery/schedulers"
+	"taskmanager/auth/Domain"
 	"taskmanager/auth/Infrastructure"
 	"taskmanager/auth/Repositories"
 	"taskmanager/auth/Usecases"
@@ -19,73 +29,247 @@ func main() {
 	// Setup context
 	ctx := context.Background()
 
-	// Load environment variables or use defaults
-	mongoURI := "mongodb://localhost:27017"
-	if envURI := os.Getenv("MONGODB_URI"); envURI != "" {
-		mongoURI = envURI
+	// Load the config file, environment variables and flags
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		log.Fatalf("Failed to load configuration: %v", err)
 	}
-
-	jwtSecret := "default-jwt-should-be-set-in-env-this-is-a-backup"
-	if envSecret := os.Getenv("JWT_SECRET"); envSecret != "" {
-		jwtSecret = envSecret
-	} else {
-		log.Println("Warning: Using default JWT secret. Set JWT_SECRET environment variable in production.")
+	if cfg.UsesDefaultJWTSecret() {
+		log.Println("Warning: Using default JWT secret. Set JWT_SECRET before leaving dev mode.")
 	}
 
-	// Setup MongoDB connection
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
-	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
-	}
-	defer func() {
-		if err := client.Disconnect(ctx); err != nil {
-			log.Printf("Error disconnecting from MongoDB: %v", err)
+	// Initialize repositories
+	var taskRepo Domain.TaskRepository
+	var revisionRepo Domain.TaskRevisionRepository
+	var userRepo Domain.UserRepository
+	var tokenRepo Domain.TokenRepository
+	var auditRepo Domain.AuditRepository
+	var taskTransactor Domain.TaskTransactor
+	var webhookRepo Domain.WebhookRepository
+	var deliveryRepo Domain.WebhookDeliveryRepository
+
+	// Recent events are kept for clients resuming a stream
+	eventBus := Infrastructure.NewEventBus(1000)
+	var taskEvents Domain.TaskEventBus = eventBus
+	var eventRelay *Repositories.TaskEventRelay
+	var mongoClient *mongo.Client
+
+	switch cfg.Storage.Backend {
+	case "memory":
+		log.Println("Using in-memory storage. Data will be lost on restart.")
+		memoryTaskRepo := Repositories.NewInMemoryTaskRepository()
+		memoryRevisionRepo := Repositories.NewInMemoryTaskRevisionRepository()
+		memoryAuditRepo := Repositories.NewInMemoryAuditRepository()
+
+		taskRepo = memoryTaskRepo
+		revisionRepo = memoryRevisionRepo
+		userRepo = Repositories.NewInMemoryUserRepository()
+		tokenRepo = Repositories.NewInMemoryTokenRepository()
+		auditRepo = memoryAuditRepo
+		taskTransactor = Repositories.NewInMemoryTaskTransactor(memoryTaskRepo, memoryRevisionRepo, memoryAuditRepo)
+		webhookRepo = Repositories.NewInMemoryWebhookRepository()
+		deliveryRepo = Repositories.NewInMemoryWebhookDeliveryRepository()
+	case "mongo":
+		// Setup MongoDB connection
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.Storage.MongoURI))
+		if err != nil {
+			log.Fatalf("Failed to connect to MongoDB: %v", err)
 		}
-	}()
+		mongoClient = client
 
-	// Ping the database
-	if err := client.Ping(ctx, nil); err != nil {
-		log.Fatalf("Failed to ping MongoDB: %v", err)
-	}
-	log.Println("Connected to MongoDB!")
+		// Ping the database
+		if err := client.Ping(ctx, nil); err != nil {
+			log.Fatalf("Failed to ping MongoDB: %v", err)
+		}
+		log.Println("Connected to MongoDB!")
 
-	// Initialize collections
-	taskCollection := client.Database("taskmanager").Collection("tasks")
-	userCollection := client.Database("taskmanager").Collection("users")
+		// Initialize collections
+		database := client.Database(cfg.Storage.Database)
+		taskCollection := database.Collection("tasks")
+		revisionCollection := database.Collection("task_revisions")
+		userCollection := database.Collection("users")
+		refreshTokenCollection := database.Collection("refresh_tokens")
+		revokedTokenCollection := database.Collection("revoked_tokens")
+		auditCollection := database.Collection("audit_log")
+		eventCollection := database.Collection("task_events")
+		webhookCollection := database.Collection("webhooks")
+		deliveryCollection := database.Collection("webhook_deliveries")
 
-	// Initialize repositories
-	taskRepo := Repositories.NewTaskRepository(taskCollection, ctx)
-	userRepo := Repositories.NewUserRepository(userCollection, ctx)
+		mongoUserRepo := Repositories.NewUserRepository(userCollection, cfg.Storage.OperationTimeout)
+
+		// Initialize user repository with unique index for usernames
+		if err := mongoUserRepo.Initialize(ctx); err != nil {
+			log.Fatalf("Failed to initialize user repository: %v", err)
+		}
+
+		mongoTaskRepo := Repositories.NewTaskRepository(taskCollection, cfg.Storage.OperationTimeout)
+
+		// Initialize task repository with indexes for sorted listings
+		if err := mongoTaskRepo.Initialize(ctx); err != nil {
+			log.Fatalf("Failed to initialize task repository: %v", err)
+		}
+
+		mongoRevisionRepo := Repositories.NewTaskRevisionRepository(revisionCollection, cfg.Storage.OperationTimeout)
+
+		// Initialize revision repository with a unique index per task revision
+		if err := mongoRevisionRepo.Initialize(ctx); err != nil {
+			log.Fatalf("Failed to initialize task revision repository: %v", err)
+		}
+
+		mongoTokenRepo := Repositories.NewTokenRepository(refreshTokenCollection, revokedTokenCollection, cfg.Storage.OperationTimeout)
+
+		// Initialize token repository with lookup and expiry indexes
+		if err := mongoTokenRepo.Initialize(ctx); err != nil {
+			log.Fatalf("Failed to initialize token repository: %v", err)
+		}
+
+		mongoAuditRepo := Repositories.NewAuditRepository(auditCollection, cfg.Storage.OperationTimeout)
+
+		// Initialize audit repository with indexes for filtered listings
+		if err := mongoAuditRepo.Initialize(ctx); err != nil {
+			log.Fatalf("Failed to initialize audit repository: %v", err)
+		}
+
+		mongoWebhookRepo := Repositories.NewWebhookRepository(webhookCollection, cfg.Storage.OperationTimeout)
 
-	// Initialize user repository with unique index for usernames
-	if err := userRepo.Initialize(); err != nil {
-		log.Fatalf("Failed to initialize user repository: %v", err)
+		// Initialize webhook repository with an index per user
+		if err := mongoWebhookRepo.Initialize(ctx); err != nil {
+			log.Fatalf("Failed to initialize webhook repository: %v", err)
+		}
+
+		mongoDeliveryRepo := Repositories.NewWebhookDeliveryRepository(deliveryCollection, cfg.Storage.OperationTimeout)
+
+		// Initialize delivery repository with dispatch, log and expiry indexes
+		if err := mongoDeliveryRepo.Initialize(ctx); err != nil {
+			log.Fatalf("Failed to initialize webhook delivery repository: %v", err)
+		}
+
+		taskRepo = mongoTaskRepo
+		revisionRepo = mongoRevisionRepo
+		userRepo = mongoUserRepo
+		tokenRepo = mongoTokenRepo
+		auditRepo = mongoAuditRepo
+		taskTransactor = Repositories.NewTaskTransactor(client, mongoTaskRepo, mongoRevisionRepo, mongoAuditRepo)
+		webhookRepo = mongoWebhookRepo
+		deliveryRepo = mongoDeliveryRepo
+
+		if cfg.Storage.EventsChangeStream {
+			eventRelay = Repositories.NewTaskEventRelay(eventCollection, eventBus, ctx, cfg.Storage.OperationTimeout)
+
+			// Initialize the event relay with an index expiring old events
+			if err := eventRelay.Initialize(ctx); err != nil {
+				log.Fatalf("Failed to initialize task event relay: %v", err)
+			}
+			eventRelay.Start()
+			taskEvents = eventRelay
+		}
 	}
 
 	// Initialize infrastructure services
-	jwtService := Infrastructure.NewJWTService(jwtSecret)
-	passwordService := Infrastructure.NewPasswordService()
-	authMiddleware := Infrastructure.NewAuthMiddleware(jwtService)
+	jwtService := Infrastructure.NewJWTService(cfg.Auth.JWTSecret, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
+	if cfg.Auth.JWTPrivateKeyFile != "" {
+		jwtService, err = Infrastructure.NewAsymmetricJWTService(cfg.Auth.JWTPrivateKeyFile, cfg.Auth.JWTPublicKeyFiles, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
+		if err != nil {
+			log.Fatalf("Failed to load JWT keys: %v", err)
+		}
+	}
+	passwordService := Infrastructure.NewPasswordService(cfg.Auth.BcryptCost)
+	webhookSender := Infrastructure.NewHTTPWebhookSender(cfg.Webhooks.Timeout, cfg.Webhooks.AllowPrivateAddresses)
+	authMiddleware := Infrastructure.NewAuthMiddleware(jwtService, tokenRepo, userRepo)
+
+	// Initialize use cases. Task events reach webhooks before subscribers.
+	webhookUseCase := Usecases.NewWebhookUseCase(webhookRepo, deliveryRepo, auditRepo, webhookSender, cfg.Webhooks.AllowPrivateAddresses)
+	taskUseCase := Usecases.NewTaskUseCase(taskRepo, revisionRepo, userRepo, auditRepo, taskTransactor, webhookUseCase.Publisher(taskEvents))
+	userUseCase := Usecases.NewUserUseCase(userRepo, tokenRepo, taskRepo, webhookRepo, passwordService, jwtService, auditRepo, cfg.Auth.AdminBootstrapToken)
+	auditUseCase := Usecases.NewAuditUseCase(auditRepo)
 
-	// Initialize use cases
-	taskUseCase := Usecases.NewTaskUseCase(taskRepo)
-	userUseCase := Usecases.NewUserUseCase(userRepo, passwordService, jwtService)
+	// Start the scheduler for recurring tasks
+	recurrenceScheduler := schedulers.NewRecurrenceScheduler(taskUseCase, cfg.Schedules.RecurrenceInterval)
+	recurrenceScheduler.Start()
+
+	// Start sending webhook deliveries
+	webhookDispatcher := schedulers.NewWebhookDispatcher(webhookUseCase, cfg.Schedules.WebhookDispatchInterval)
+	webhookDispatcher.Start()
 
 	// Initialize controllers
-	controller := controllers.NewController(taskUseCase, userUseCase, authMiddleware)
+	controller := controllers.NewController(taskUseCase, userUseCase, auditUseCase, webhookUseCase, authMiddleware, jwtService)
 
 	// Initialize and setup router
-	router := routers.NewRouter(controller, authMiddleware)
+	if err := Infrastructure.RegisterValidators(); err != nil {
+		log.Fatalf("Failed to register request validators: %v", err)
+	}
+	readiness := Infrastructure.NewReadiness()
+	router := routers.NewRouter(controller, authMiddleware, readiness)
 	r := router.Setup()
 
 	// Start the server
-	port := "8080"
-	if envPort := os.Getenv("PORT"); envPort != "" {
-		port = envPort
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Server.Port),
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+	}
+	// Event streams never finish by themselves, and WebSockets are not
+	// tracked by the server at all; ending them makes clients reconnect
+	// to another instance
+	server.RegisterOnShutdown(eventBus.Close)
+
+	// Only report ready once the port accepts connections
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on port %d: %v", cfg.Server.Port, err)
+	}
+	readiness.SetReady(true)
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on port %d", cfg.Server.Port)
+		serverErrors <- server.Serve(listener)
+	}()
+
+	// Wait for an interrupt, or for the server to fail
+	shutdown, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serverFailed := false
+	select {
+	case <-shutdown.Done():
+		// A second signal stops the server at once instead of draining
+		stop()
+		log.Println("Shutting down... (interrupt again to stop at once)")
+	case err := <-serverErrors:
+		log.Printf("Server failed: %v", err)
+		serverFailed = true
+	}
+
+	// Report not ready, then drain the requests in flight
+	readiness.SetReady(false)
+	if cfg.Server.ShutdownDelay > 0 {
+		time.Sleep(cfg.Server.ShutdownDelay)
+	}
+	drainCtx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(drainCtx); err != nil {
+		log.Printf("Requests did not finish in time, closing connections: %v", err)
+		server.Close()
+	}
+
+	// Stop background work; anything left over is picked up after a restart
+	recurrenceScheduler.Stop()
+	webhookDispatcher.Stop()
+	if eventRelay != nil {
+		eventRelay.Stop()
 	}
 
-	log.Printf("Server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	// MongoDB goes last, once nothing uses it any more
+	if mongoClient != nil {
+		if err := mongoClient.Disconnect(ctx); err != nil {
+			log.Printf("Error disconnecting from MongoDB: %v", err)
+		}
+	}
+	log.Println("Server stopped")
+	if serverFailed {
+		os.Exit(1)
 	}
 }
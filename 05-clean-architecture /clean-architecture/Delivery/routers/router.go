@@ -6,42 +6,125 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"taskmanager/auth/Delivery/controllers"
+	"taskmanager/auth/Domain"
 	"taskmanager/auth/Infrastructure"
 )
 
 type Router struct {
 	controller     *controllers.Controller
 	authMiddleware *Infrastructure.AuthMiddleware
+	readiness      *Infrastructure.Readiness
 }
 
-func NewRouter(controller *controllers.Controller, authMiddleware *Infrastructure.AuthMiddleware) *Router {
+func NewRouter(controller *controllers.Controller, authMiddleware *Infrastructure.AuthMiddleware, readiness *Infrastructure.Readiness) *Router {
 	return &Router{
 		controller:     controller,
 		authMiddleware: authMiddleware,
+		readiness:      readiness,
 	}
 }
 
 func (r *Router) Setup() *gin.Engine {
-	router := gin.Default()
+	router := gin.New()
+	router.Use(Infrastructure.AccessLogger(), Infrastructure.Recovery())
+	// Errors recorded by handlers and middleware are answered as problems
+	router.Use(Infrastructure.ErrorHandler())
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		Infrastructure.WriteProblem(c, Domain.ErrNotFound.WithMessage("Route not found"))
+	})
+	router.NoMethod(func(c *gin.Context) {
+		Infrastructure.WriteProblem(c, Domain.ErrMethodNotAllowed)
+	})
 
 	router.GET("/health", func(c *gin.Context) {
 		c.String(http.StatusOK, "OK")
 	})
 
+	// Fails while the server starts or drains, so no new requests are sent
+	router.GET("/ready", func(c *gin.Context) {
+		if !r.readiness.Ready() {
+			c.String(http.StatusServiceUnavailable, "Not ready")
+			return
+		}
+		c.String(http.StatusOK, "OK")
+	})
+
+	router.GET("/.well-known/jwks.json", r.controller.HandleJWKS)
+
 	// Public authentication routes
 	router.POST("/register", r.controller.HandleRegister)
 	router.POST("/login", r.controller.HandleLogin)
+	router.POST("/token/refresh", r.controller.HandleRefreshToken)
 
 	// Protected routes
 	api := router.Group("/")
 	api.Use(r.authMiddleware.JWTAuth())
 	{
-		// Routes available to all authenticated users (regular users & admins)
-		api.GET("/tasks", r.controller.HandleGetTasks)
-		api.GET("/tasks/:id", r.controller.HandleGetTask)
-		api.POST("/tasks", r.controller.HandleCreateTask)
-		api.PUT("/tasks/:id", r.controller.HandleUpdateTask)
-		api.DELETE("/tasks/:id", r.controller.HandleDeleteTask)
+		api.POST("/logout", r.controller.HandleLogout)
+		api.POST("/bootstrap/admin", r.controller.HandleBootstrapAdmin)
+
+		// Task routes, each guarded by the permission it needs
+		readTasks := r.authMiddleware.RequirePermission(Domain.PermTasksReadOwn, Domain.PermTasksReadAny)
+		createTasks := r.authMiddleware.RequirePermission(Domain.PermTasksWriteOwn)
+		writeTasks := r.authMiddleware.RequirePermission(Domain.PermTasksWriteOwn, Domain.PermTasksWriteAny)
+
+		api.GET("/tasks", readTasks, r.controller.HandleGetTasks)
+		api.GET("/tasks/search", readTasks, r.controller.HandleSearchTasks)
+		api.GET("/tasks/export", readTasks, r.controller.HandleExportTasks)
+		api.GET("/tasks/trash", writeTasks, r.controller.HandleGetTrash)
+		api.GET("/tasks/:id", readTasks, r.controller.HandleGetTask)
+		api.GET("/tasks/:id/subtree", readTasks, r.controller.HandleGetSubtree)
+		api.GET("/tasks/:id/dependencies", readTasks, r.controller.HandleGetDependencies)
+		api.GET("/tasks/:id/history", readTasks, r.controller.HandleGetTaskHistory)
+		api.GET("/tasks/:id/revisions/:rev", readTasks, r.controller.HandleGetTaskRevision)
+		api.POST("/tasks/:id/revisions/:rev/restore", writeTasks, r.controller.HandleRestoreTaskRevision)
+		api.POST("/tasks/:id/undelete", writeTasks, r.controller.HandleUndeleteTask)
+		api.POST("/tasks", createTasks, r.controller.HandleCreateTask)
+		api.POST("/tasks/import", createTasks, r.controller.HandleImportTasks)
+		// Custom methods such as POST /tasks:batch
+		api.POST("/tasks:method", writeTasks, r.controller.HandleTaskMethod)
+		api.PUT("/tasks/:id", writeTasks, r.controller.HandleUpdateTask)
+		api.PATCH("/tasks/:id", writeTasks, r.controller.HandlePatchTask)
+		api.DELETE("/tasks/:id", writeTasks, r.controller.HandleDeleteTask)
+		api.POST("/tasks/:id/share", writeTasks, r.controller.HandleShareTask)
+		api.DELETE("/tasks/:id/share/:userId", readTasks, r.controller.HandleUnshareTask)
+
+		// Webhooks receive the events of the tasks their user can read
+		api.POST("/webhooks", readTasks, r.controller.HandleCreateWebhook)
+		api.GET("/webhooks", readTasks, r.controller.HandleListWebhooks)
+		api.GET("/webhooks/dead-letters", readTasks, r.controller.HandleListWebhookDeadLetters)
+		api.GET("/webhooks/:id", readTasks, r.controller.HandleGetWebhook)
+		api.DELETE("/webhooks/:id", readTasks, r.controller.HandleDeleteWebhook)
+		api.GET("/webhooks/:id/deliveries", readTasks, r.controller.HandleListWebhookDeliveries)
+		api.POST("/webhooks/deliveries/:id/redeliver", readTasks, r.controller.HandleRedeliverWebhook)
+	}
+
+	// Task event streams, which browsers can only authenticate with a
+	// query parameter
+	streams := router.Group("/")
+	streams.Use(r.authMiddleware.QueryToken(), r.authMiddleware.JWTAuth())
+	{
+		readTasks := r.authMiddleware.RequirePermission(Domain.PermTasksReadOwn, Domain.PermTasksReadAny)
+
+		streams.GET("/tasks/events", readTasks, r.controller.HandleTaskEvents)
+		streams.GET("/tasks/events/ws", readTasks, r.controller.HandleTaskEventsWebSocket)
+	}
+
+	// User management
+	admin := api.Group("/admin")
+	{
+		readUsers := r.authMiddleware.RequirePermission(Domain.PermUsersRead)
+		manageUsers := r.authMiddleware.RequirePermission(Domain.PermUsersManage)
+		readAudit := r.authMiddleware.RequirePermission(Domain.PermAuditRead)
+
+		admin.GET("/users", readUsers, r.controller.HandleListUsers)
+		admin.GET("/users/:id", readUsers, r.controller.HandleGetUser)
+		admin.PUT("/users/:id/role", manageUsers, r.controller.HandleUpdateUserRole)
+		admin.POST("/users/:id/disable", manageUsers, r.controller.HandleDisableUser)
+		admin.POST("/users/:id/enable", manageUsers, r.controller.HandleEnableUser)
+		admin.DELETE("/users/:id", manageUsers, r.controller.HandleDeleteUser)
+		admin.GET("/audit", readAudit, r.controller.HandleListAuditLog)
 	}
 
 	return router
@@ -0,0 +1,46 @@
+package routers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"taskmanager/auth/Infrastructure"
+)
+
+func TestReady(t *testing.T) {
+	readiness := Infrastructure.NewReadiness()
+	router := NewRouter(nil, Infrastructure.NewAuthMiddleware(nil, nil, nil), readiness).Setup()
+
+	get := func(path string) int {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		return recorder.Code
+	}
+	if code := get("/ready"); code != http.StatusServiceUnavailable {
+		t.Errorf("GET /ready while starting = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	// The server is ready once it listens, and not ready again while it
+	// drains on shutdown
+	tests := []struct {
+		name  string
+		ready bool
+		want  int
+	}{
+		{"serving", true, http.StatusOK},
+		{"draining", false, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readiness.SetReady(tt.ready)
+			if code := get("/ready"); code != tt.want {
+				t.Errorf("GET /ready = %d, want %d", code, tt.want)
+			}
+			// Liveness does not depend on readiness
+			if code := get("/health"); code != http.StatusOK {
+				t.Errorf("GET /health = %d, want %d", code, http.StatusOK)
+			}
+		})
+	}
+}
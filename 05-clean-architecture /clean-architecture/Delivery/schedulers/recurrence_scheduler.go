@@ -0,0 +1,67 @@
+package schedulers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"taskmanager/auth/Usecases"
+)
+
+// RecurrenceScheduler periodically creates the next occurrence of recurring
+// tasks. Occurrences are stored with their position in the series, so
+// several instances or a restart mid-pass never create duplicates.
+type RecurrenceScheduler struct {
+	taskUseCase *Usecases.TaskUseCase
+	interval    time.Duration
+	ctx         context.Context
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+func NewRecurrenceScheduler(taskUseCase *Usecases.TaskUseCase, interval time.Duration) *RecurrenceScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RecurrenceScheduler{
+		taskUseCase: taskUseCase,
+		interval:    interval,
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+}
+
+// Start runs a pass right away and then once every interval, in the
+// background
+func (s *RecurrenceScheduler) Start() {
+	go s.run()
+}
+
+// Stop cancels the current pass, waits for it to end and stops the
+// scheduler. Tasks the pass did not get to are handled by the next one.
+func (s *RecurrenceScheduler) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+func (s *RecurrenceScheduler) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		handled, err := s.taskUseCase.ProcessRecurrences(s.ctx, time.Now())
+		if err != nil && s.ctx.Err() == nil {
+			log.Printf("Recurring tasks: %v", err)
+		}
+		if handled > 0 {
+			log.Printf("Recurring tasks: scheduled %d", handled)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
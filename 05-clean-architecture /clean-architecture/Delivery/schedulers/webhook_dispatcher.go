@@ -0,0 +1,69 @@
+package schedulers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"taskmanager/auth/Usecases"
+)
+
+// WebhookDispatcher sends webhook deliveries as soon as they are queued
+// and checks every interval for retries that have come due. Deliveries are
+// claimed before they are sent, so several instances can run one each.
+type WebhookDispatcher struct {
+	webhookUseCase *Usecases.WebhookUseCase
+	interval       time.Duration
+	ctx            context.Context
+	cancel         context.CancelFunc
+	done           chan struct{}
+}
+
+func NewWebhookDispatcher(webhookUseCase *Usecases.WebhookUseCase, interval time.Duration) *WebhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebhookDispatcher{
+		webhookUseCase: webhookUseCase,
+		interval:       interval,
+		ctx:            ctx,
+		cancel:         cancel,
+		done:           make(chan struct{}),
+	}
+}
+
+// Start runs a pass right away and then whenever deliveries are queued or
+// the interval passes, in the background
+func (d *WebhookDispatcher) Start() {
+	go d.run()
+}
+
+// Stop cancels the deliveries being sent, waits for the current pass to
+// end and stops the dispatcher. Canceled deliveries are sent again later,
+// by this instance after a restart or by another one.
+func (d *WebhookDispatcher) Stop() {
+	d.cancel()
+	<-d.done
+}
+
+func (d *WebhookDispatcher) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		attempted, err := d.webhookUseCase.DispatchDue(d.ctx, time.Now())
+		if err != nil && d.ctx.Err() == nil {
+			log.Printf("Webhooks: %v", err)
+		}
+		if attempted > 0 {
+			log.Printf("Webhooks: attempted %d deliveries", attempted)
+		}
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.webhookUseCase.Wake():
+		}
+	}
+}
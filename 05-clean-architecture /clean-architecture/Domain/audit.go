@@ -0,0 +1,117 @@
+package Domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditAction names a recorded change
+type AuditAction string
+
+const (
+	AuditTaskCreated     AuditAction = "task.created"
+	AuditTaskUpdated     AuditAction = "task.updated"
+	AuditTaskDeleted     AuditAction = "task.deleted"
+	AuditTaskUndeleted   AuditAction = "task.undeleted"
+	AuditTaskRestored    AuditAction = "task.restored"
+	AuditTaskShared      AuditAction = "task.shared"
+	AuditTaskUnshared    AuditAction = "task.unshared"
+	AuditUserRegistered  AuditAction = "user.registered"
+	AuditUserLogin       AuditAction = "user.login"
+	AuditUserLoginFailed AuditAction = "user.login_failed"
+	AuditUserRoleChanged AuditAction = "user.role_changed"
+	AuditUserDisabled    AuditAction = "user.disabled"
+	AuditUserEnabled     AuditAction = "user.enabled"
+	AuditUserDeleted     AuditAction = "user.deleted"
+	AuditWebhookCreated  AuditAction = "webhook.created"
+	AuditWebhookDeleted  AuditAction = "webhook.deleted"
+)
+
+// Kinds of audited entities
+const (
+	AuditEntityTask    = "task"
+	AuditEntityUser    = "user"
+	AuditEntityWebhook = "webhook"
+)
+
+// AuditChange is the value of a field before and after a change. Before is
+// unset for created entities and After for deleted ones.
+type AuditChange struct {
+	Before interface{} `json:"before,omitempty" bson:"before,omitempty"`
+	After  interface{} `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// AuditEntry records who changed what and when. Entries are never changed
+// or removed once written.
+type AuditEntry struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Action AuditAction        `json:"action" bson:"action"`
+	// ActorID is unset for changes the server makes on its own
+	ActorID *primitive.ObjectID `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	// ActorUsername is the username a failed login was attempted for
+	ActorUsername string                 `json:"actor_username,omitempty" bson:"actor_username,omitempty"`
+	EntityType    string                 `json:"entity_type" bson:"entity_type"`
+	EntityID      *primitive.ObjectID    `json:"entity_id,omitempty" bson:"entity_id,omitempty"`
+	Changes       map[string]AuditChange `json:"changes,omitempty" bson:"changes,omitempty"`
+	// Detail adds context, such as why a login failed
+	Detail    string    `json:"detail,omitempty" bson:"detail,omitempty"`
+	CreatedAt time.Time `json:"timestamp" bson:"created_at"`
+}
+
+// Audit log defaults and limits
+const (
+	DefaultAuditPageSize = 50
+	MaxAuditPageSize     = 200
+)
+
+// AuditFilter narrows down the entries returned by AuditRepository.List.
+// Zero values mean "no filter".
+type AuditFilter struct {
+	ActorID    *primitive.ObjectID
+	Action     AuditAction
+	EntityType string
+	EntityID   *primitive.ObjectID
+	After      *time.Time
+	Before     *time.Time
+}
+
+// AuditListOptions controls filtering and pagination of the audit log.
+// Entries are ordered newest first; Cursor is the NextCursor of a previous
+// page.
+type AuditListOptions struct {
+	Filter AuditFilter
+	Limit  int
+	Cursor string
+}
+
+// WithDefaults fills in the default page size
+func (o AuditListOptions) WithDefaults() AuditListOptions {
+	if o.Limit <= 0 {
+		o.Limit = DefaultAuditPageSize
+	}
+	if o.Limit > MaxAuditPageSize {
+		o.Limit = MaxAuditPageSize
+	}
+	return o
+}
+
+// AuditPage is one page of the audit log
+type AuditPage struct {
+	Entries    []AuditEntry
+	NextCursor string
+	Total      int64
+}
+
+// AuditRepository stores the audit log. It is append-only.
+type AuditRepository interface {
+	Append(ctx context.Context, entry *AuditEntry) error
+	List(ctx context.Context, opts AuditListOptions) (*AuditPage, error)
+}
+
+type AuditLogResponse struct {
+	Entries    []AuditEntry `json:"entries"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	Total      int64        `json:"total"`
+}
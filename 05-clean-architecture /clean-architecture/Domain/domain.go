@@ -1,41 +1,207 @@
 package Domain
 
 import (
-	"errors"
+	"context"
+	"encoding/json"
+	"strings"
 	"time"
+	"unicode"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Common errors
-var (
-	ErrNotFound          = errors.New("resource not found")
-	ErrInvalidID         = errors.New("invalid ID format")
-	ErrInvalidInput      = errors.New("invalid input")
-	ErrUsernameTaken     = errors.New("username already taken")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrUnauthorized      = errors.New("unauthorized")
-	ErrForbidden         = errors.New("forbidden")
-)
-
 // Role represents user role
 type Role string
 
 // Available roles
 const (
-	RoleAdmin Role = "admin"
-	RoleUser  Role = "user"
+	RoleAdmin   Role = "admin"
+	RoleUser    Role = "user"
+	RoleAuditor Role = "auditor" // read-only access to everything
 )
 
+// IsValid reports whether the role is one of the available roles
+func (r Role) IsValid() bool {
+	_, ok := RolePermissions[r]
+	return ok
+}
+
+// Priority of a task
+type Priority string
+
+// Available priorities
+const (
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// IsValid reports whether the priority is one of the available priorities
+func (p Priority) IsValid() bool {
+	switch p {
+	case PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent:
+		return true
+	}
+	return false
+}
+
+// Tag limits. The binding rules of the task DTOs repeat them, since struct
+// tags cannot refer to constants.
+const (
+	MaxTagsPerTask = 20
+	MaxTagLength   = 32
+)
+
+// Task field limits, in characters. The binding rules of the task DTOs
+// repeat them.
+const (
+	MaxTitleLength       = 200
+	MaxDescriptionLength = 10000
+	MaxRecurrenceLength  = 500
+)
+
+// Username and password rules for new accounts
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 32
+	MinPasswordLength = 8
+	// bcrypt only hashes the first 72 bytes
+	MaxPasswordLength = 72
+)
+
+// ValidUsername reports whether a new account may use the username: 3 to 32
+// ASCII letters, digits, dots, dashes or underscores
+func ValidUsername(username string) bool {
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return false
+	}
+	for _, r := range username {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidPassword reports whether a new password is strong enough: 8 to 72
+// bytes with at least one letter and one digit
+func ValidPassword(password string) bool {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return false
+	}
+	return strings.ContainsFunc(password, unicode.IsLetter) && strings.ContainsFunc(password, unicode.IsDigit)
+}
+
+// ShareLevel is how much a collaborator may do with a shared task
+type ShareLevel string
+
+// Available share levels
+const (
+	ShareLevelViewer ShareLevel = "viewer" // can read the task
+	ShareLevelEditor ShareLevel = "editor" // can read and update the task
+)
+
+// IsValid reports whether the level is one of the available share levels
+func (l ShareLevel) IsValid() bool {
+	return l == ShareLevelViewer || l == ShareLevelEditor
+}
+
+// Collaborator is a user a task has been shared with
+type Collaborator struct {
+	UserID   primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Level    ShareLevel         `json:"level" bson:"level"`
+	SharedAt time.Time          `json:"shared_at" bson:"shared_at"`
+}
+
 // Task entity represents a task in the system
 type Task struct {
-	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Title       string             `json:"title" bson:"title"`
-	Description string             `json:"description" bson:"description"`
-	Completed   bool               `json:"completed" bson:"completed"`
-	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
-	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Title         string             `json:"title" bson:"title"`
+	Description   string             `json:"description" bson:"description"`
+	Completed     bool               `json:"completed" bson:"completed"`
+	DueAt         *time.Time         `json:"due_at,omitempty" bson:"due_at,omitempty"`
+	Priority      Priority           `json:"priority,omitempty" bson:"priority,omitempty"`
+	Tags          []string           `json:"tags,omitempty" bson:"tags,omitempty"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at" bson:"updated_at"`
+	UserID        primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Collaborators []Collaborator     `json:"collaborators,omitempty" bson:"collaborators,omitempty"`
+	// ParentID makes the task a subtask of another task
+	ParentID *primitive.ObjectID `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	// BlockedBy lists the tasks that must be completed before this one
+	BlockedBy []primitive.ObjectID `json:"blocked_by,omitempty" bson:"blocked_by,omitempty"`
+	// Recurrence is an RRULE; the next occurrence is created once this one
+	// is completed or its due date passes
+	Recurrence string `json:"recurrence,omitempty" bson:"recurrence,omitempty"`
+	// SeriesID is the ID of the first task of a recurring series and
+	// Occurrence the task's 1-based position in it
+	SeriesID   *primitive.ObjectID `json:"series_id,omitempty" bson:"series_id,omitempty"`
+	Occurrence int                 `json:"occurrence,omitempty" bson:"occurrence,omitempty"`
+	// Recurred is set once the next occurrence has been taken care of
+	Recurred bool `json:"-" bson:"recurred,omitempty"`
+	// Revision counts the changes made to the task, starting at 1
+	Revision int `json:"revision" bson:"revision"`
+	// DeletedAt is set while the task is in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+}
+
+// TaskRevision is a snapshot of a task right after a change
+type TaskRevision struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TaskID   primitive.ObjectID `json:"task_id" bson:"task_id"`
+	Revision int                `json:"revision" bson:"revision"`
+	Action   AuditAction        `json:"action" bson:"action"`
+	// ActorID is unset for changes the server makes on its own
+	ActorID   *primitive.ObjectID `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	Task      Task                `json:"task" bson:"task"`
+	CreatedAt time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// MaxBlockersPerTask limits how many tasks a task can be blocked by
+const MaxBlockersPerTask = 50
+
+// TaskNode is a task together with its subtasks
+type TaskNode struct {
+	Task     Task       `json:"task"`
+	Subtasks []TaskNode `json:"subtasks"`
+}
+
+// DependencyEdge says that Task cannot be completed before BlockedBy
+type DependencyEdge struct {
+	TaskID    primitive.ObjectID `json:"task_id"`
+	BlockedBy primitive.ObjectID `json:"blocked_by"`
+}
+
+// DependencyGraph holds the tasks a task transitively depends on and the
+// tasks that transitively depend on it
+type DependencyGraph struct {
+	Tasks []Task           `json:"tasks"`
+	Edges []DependencyEdge `json:"edges"`
+}
+
+// ShareLevelFor returns the level the task is shared with the user at
+func (t Task) ShareLevelFor(userID primitive.ObjectID) (ShareLevel, bool) {
+	for _, c := range t.Collaborators {
+		if c.UserID == userID {
+			return c.Level, true
+		}
+	}
+	return "", false
+}
+
+// How a role was granted
+const (
+	GrantMethodAdmin     = "admin"     // changed by an admin through the API
+	GrantMethodBootstrap = "bootstrap" // first admin, created with the bootstrap token
+)
+
+// RoleGrant records a role change and who made it
+type RoleGrant struct {
+	Role      Role                `json:"role" bson:"role"`
+	Method    string              `json:"method" bson:"method"`
+	GrantedBy *primitive.ObjectID `json:"granted_by,omitempty" bson:"granted_by,omitempty"` // unset for bootstrap
+	GrantedAt time.Time           `json:"granted_at" bson:"granted_at"`
 }
 
 // User entity represents a user in the system
@@ -46,36 +212,316 @@ type User struct {
 	Role        Role               `json:"role" bson:"role"`
 	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
 	LastLoginAt time.Time          `json:"last_login_at" bson:"last_login_at"`
+	Disabled    bool               `json:"disabled" bson:"disabled"`
+	RoleGrants  []RoleGrant        `json:"role_grants,omitempty" bson:"role_grants,omitempty"`
+}
+
+// RefreshToken is a stored, single-use refresh token. Only a hash of the
+// token is kept. Tokens rotated from the same login share a FamilyID, which
+// is also the session ID carried by the access tokens issued with them.
+type RefreshToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	TokenHash string             `bson:"token_hash"`
+	FamilyID  string             `bson:"family_id"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	CreatedAt time.Time          `bson:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty"`
+	RevokedAt *time.Time         `bson:"revoked_at,omitempty"`
+}
+
+// Task list defaults and limits
+const (
+	DefaultTaskPageSize = 20
+	MaxTaskPageSize     = 100
+)
+
+// TaskSortField is a field tasks can be ordered by
+type TaskSortField string
+
+// Sortable task fields
+const (
+	SortByCreatedAt TaskSortField = "created_at"
+	SortByUpdatedAt TaskSortField = "updated_at"
+	SortByTitle     TaskSortField = "title"
+)
+
+// IsValid reports whether the field is one tasks can be sorted by
+func (f TaskSortField) IsValid() bool {
+	switch f {
+	case SortByCreatedAt, SortByUpdatedAt, SortByTitle:
+		return true
+	}
+	return false
+}
+
+// TaskFilter narrows down the tasks returned by TaskRepository.GetAll.
+// Zero values mean "no filter".
+type TaskFilter struct {
+	Completed     *bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	TitleContains string
+	DueBefore     *time.Time
+	Overdue       bool // due date has passed and the task is not completed
+	Priority      Priority
+	Tag           string
+}
+
+// TaskListOptions controls filtering, ordering and pagination of task lists.
+// Cursor is the opaque NextCursor of a previous page and must be used with
+// the same sort.
+type TaskListOptions struct {
+	Filter        TaskFilter
+	SortBy        TaskSortField
+	SortDesc      bool
+	Limit         int
+	Cursor        string
+	IncludeShared bool // also list tasks shared with the caller
+}
+
+// WithDefaults fills in the default sort and page size
+func (o TaskListOptions) WithDefaults() TaskListOptions {
+	if o.SortBy == "" {
+		o.SortBy = SortByCreatedAt
+	}
+	if o.Limit <= 0 {
+		o.Limit = DefaultTaskPageSize
+	}
+	if o.Limit > MaxTaskPageSize {
+		o.Limit = MaxTaskPageSize
+	}
+	return o
+}
+
+// TaskPage is one page of a task list
+type TaskPage struct {
+	Tasks      []Task
+	NextCursor string
+	Total      int64
 }
 
 // TaskRepository defines the interface for task data operations
+// Tasks outside the given scope are treated as not found.
 type TaskRepository interface {
-	GetByID(id primitive.ObjectID, userID primitive.ObjectID, isAdmin bool) (*Task, error)
-	GetAll(userID primitive.ObjectID, isAdmin bool) ([]Task, error)
-	Create(task *Task) error
-	Update(id primitive.ObjectID, userID primitive.ObjectID, isAdmin bool, updates map[string]interface{}) (*Task, error)
-	Delete(id primitive.ObjectID, userID primitive.ObjectID, isAdmin bool) error
+	GetByID(ctx context.Context, id primitive.ObjectID, scope TaskScope) (*Task, error)
+	GetAll(ctx context.Context, scope TaskScope, opts TaskListOptions) (*TaskPage, error)
+	// Search returns up to limit tasks in scope matching query, best first.
+	// Hits have no highlights.
+	Search(ctx context.Context, scope TaskScope, query SearchQuery, limit int) ([]TaskSearchHit, error)
+	Create(ctx context.Context, task *Task) error
+	// Update, Delete, Undelete and the collaborator methods bump the revision
+	Update(ctx context.Context, id primitive.ObjectID, scope TaskScope, updates map[string]interface{}) (*Task, error)
+	// Delete moves a task to the trash and Undelete takes it back out
+	Delete(ctx context.Context, id primitive.ObjectID, scope TaskScope) (*Task, error)
+	Undelete(ctx context.Context, id primitive.ObjectID, scope TaskScope) (*Task, error)
+	// SetCollaborator adds the collaborator or updates their level
+	SetCollaborator(ctx context.Context, id primitive.ObjectID, scope TaskScope, collaborator Collaborator) (*Task, error)
+	RemoveCollaborator(ctx context.Context, id primitive.ObjectID, scope TaskScope, userID primitive.ObjectID) (*Task, error)
+	// GetByIDs returns the tasks in scope among ids; missing ones are skipped
+	GetByIDs(ctx context.Context, ids []primitive.ObjectID, scope TaskScope) ([]Task, error)
+	// GetSubtasks returns the tasks in scope whose parent is one of parentIDs
+	GetSubtasks(ctx context.Context, parentIDs []primitive.ObjectID, scope TaskScope) ([]Task, error)
+	// GetDependents returns the tasks in scope blocked by one of ids
+	GetDependents(ctx context.Context, ids []primitive.ObjectID, scope TaskScope) ([]Task, error)
+	// CreateOccurrence creates the next task of a recurring series and
+	// returns ErrOccurrenceExists if the series already has it
+	CreateOccurrence(ctx context.Context, task *Task) error
+	// GetRecurrenceDue returns up to limit recurring tasks that are completed
+	// or due before now and have not recurred yet
+	GetRecurrenceDue(ctx context.Context, now time.Time, limit int) ([]Task, error)
+	MarkRecurred(ctx context.Context, id primitive.ObjectID) error
+}
+
+// TaskRevisionRepository stores task history. It is append-only.
+type TaskRevisionRepository interface {
+	Append(ctx context.Context, revision *TaskRevision) error
+	// List returns the revisions of a task, newest first
+	List(ctx context.Context, taskID primitive.ObjectID) ([]TaskRevision, error)
+	Get(ctx context.Context, taskID primitive.ObjectID, revision int) (*TaskRevision, error)
+}
+
+// TaskRepositories are the repositories task changes are written to
+type TaskRepositories struct {
+	Tasks     TaskRepository
+	Revisions TaskRevisionRepository
+	Audit     AuditRepository
+}
+
+// TaskTransactor runs task changes atomically. The repositories passed to
+// fn write inside the transaction when called with the context passed to
+// fn; if fn returns an error, none of its writes are kept. Databases that
+// cannot run transactions return ErrNoTransactions.
+type TaskTransactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context, repos TaskRepositories) error) error
+}
+
+// User list defaults and limits
+const (
+	DefaultUserPageSize = 20
+	MaxUserPageSize     = 100
+)
+
+// UserFilter narrows down the users returned by UserRepository.List.
+// Zero values mean "no filter".
+type UserFilter struct {
+	UsernameContains string
+	Role             Role
+	Disabled         *bool
+}
+
+// UserListOptions controls filtering and pagination of user lists. Users
+// are ordered by creation; Cursor is the NextCursor of a previous page.
+type UserListOptions struct {
+	Filter UserFilter
+	Limit  int
+	Cursor string
+}
+
+// WithDefaults fills in the default page size
+func (o UserListOptions) WithDefaults() UserListOptions {
+	if o.Limit <= 0 {
+		o.Limit = DefaultUserPageSize
+	}
+	if o.Limit > MaxUserPageSize {
+		o.Limit = MaxUserPageSize
+	}
+	return o
+}
+
+// UserPage is one page of a user list
+type UserPage struct {
+	Users      []User
+	NextCursor string
+	Total      int64
 }
 
 // UserRepository defines the interface for user data operations
 type UserRepository interface {
-	Create(user *User) error
-	GetByID(id primitive.ObjectID) (*User, error)
-	GetByUsername(username string) (*User, error)
-	UpdateLastLogin(id primitive.ObjectID) error
+	Create(ctx context.Context, user *User) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*User, error)
+	GetByUsername(ctx context.Context, username string) (*User, error)
+	UpdateLastLogin(ctx context.Context, id primitive.ObjectID) error
+	List(ctx context.Context, opts UserListOptions) (*UserPage, error)
+	// UpdateRole sets the user's role to grant.Role and appends the grant to
+	// the user's role history
+	UpdateRole(ctx context.Context, id primitive.ObjectID, grant RoleGrant) error
+	CountByRole(ctx context.Context, role Role) (int64, error)
+	SetDisabled(ctx context.Context, id primitive.ObjectID, disabled bool) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// TokenRepository defines the interface for refresh token storage and
+// access token revocation
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	// MarkRefreshTokenUsed atomically marks an unused, unrevoked token as
+	// used and returns ErrTokenReused otherwise
+	MarkRefreshTokenUsed(ctx context.Context, id primitive.ObjectID) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeTokenID(ctx context.Context, tokenID string, expiresAt time.Time) error
+	IsTokenIDRevoked(ctx context.Context, tokenID string) (bool, error)
 }
 
 // TaskRequest and Response DTOs
 type CreateTaskRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description"`
-	Completed   bool   `json:"completed"`
+	Title       string     `json:"title" binding:"required,max=200"`
+	Description string     `json:"description" binding:"max=10000"`
+	Completed   bool       `json:"completed"`
+	DueAt       *time.Time `json:"due_at"`
+	Priority    Priority   `json:"priority" binding:"omitempty,oneof=low medium high urgent"`
+	Tags        []string   `json:"tags" binding:"omitempty,max=20,dive,min=1,max=32"`
+	ParentID    string     `json:"parent_id"`
+	BlockedBy   []string   `json:"blocked_by" binding:"omitempty,max=50"`
+	Recurrence  string     `json:"recurrence" binding:"max=500"`
+	// Force allows creating a completed task with incomplete blockers
+	Force bool `json:"force"`
 }
 
+// UpdateTaskRequest only changes the fields that are set. An empty tags or
+// blocked_by array clears it, an empty parent_id detaches a subtask and an
+// empty recurrence stops a task from repeating.
 type UpdateTaskRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Completed   *bool  `json:"completed"`
+	Title       string     `json:"title" binding:"max=200"`
+	Description string     `json:"description" binding:"max=10000"`
+	Completed   *bool      `json:"completed"`
+	DueAt       *time.Time `json:"due_at"`
+	Priority    Priority   `json:"priority" binding:"omitempty,oneof=low medium high urgent"`
+	Tags        *[]string  `json:"tags" binding:"omitempty,max=20,dive,min=1,max=32"`
+	ParentID    *string    `json:"parent_id"`
+	BlockedBy   *[]string  `json:"blocked_by" binding:"omitempty,max=50"`
+	Recurrence  *string    `json:"recurrence" binding:"omitempty,max=500"`
+	// Force allows completing a task with incomplete blockers
+	Force bool `json:"force"`
+}
+
+// BatchOperationType is what one operation of a batch does
+type BatchOperationType string
+
+// Available batch operations
+const (
+	BatchCreate BatchOperationType = "create"
+	BatchUpdate BatchOperationType = "update"
+	BatchDelete BatchOperationType = "delete"
+)
+
+// MaxBatchOperations limits the size of a batch
+const MaxBatchOperations = 200
+
+// BatchOperation is one create, update or delete in a batch. Create is set
+// for creates and Update for updates; ID names the task to update or
+// delete.
+type BatchOperation struct {
+	Op     BatchOperationType
+	ID     string
+	Create *CreateTaskRequest
+	Update *UpdateTaskRequest
+	// Revision, when set, makes an update or delete conditional like If-Match
+	Revision int
+}
+
+// BatchRequest is a list of operations. Atomic batches run in a transaction
+// and stop at the first failure, leaving every task as it was.
+type BatchRequest struct {
+	Operations []BatchOperation
+	Atomic     bool
+}
+
+// BatchOperationResult is the outcome of one operation. Task is the created
+// or updated task; Err is nil if the operation succeeded.
+type BatchOperationResult struct {
+	Op   BatchOperationType
+	Task *Task
+	Err  error
+}
+
+// PatchFormat is the media type of a PATCH request body
+type PatchFormat string
+
+// Supported patch formats
+const (
+	PatchFormatMerge PatchFormat = "application/merge-patch+json" // RFC 7396
+	PatchFormatJSON  PatchFormat = "application/json-patch+json"  // RFC 6902
+)
+
+// TaskPatch is a patch document for a task. Patches apply to the task's
+// editable fields: title, description, completed, due_at, priority, tags,
+// parent_id, blocked_by and recurrence. Setting a field to null (or
+// removing it) clears it or resets it to its default; title cannot be
+// cleared.
+type TaskPatch struct {
+	Format   PatchFormat
+	Document []byte
+	// Force allows completing a task with incomplete blockers
+	Force bool
+}
+
+type ShareTaskRequest struct {
+	Username string     `json:"username" binding:"required"`
+	Level    ShareLevel `json:"level" binding:"required,oneof=viewer editor"`
 }
 
 type TaskResponse struct {
@@ -83,11 +529,57 @@ type TaskResponse struct {
 	Tasks []Task `json:"tasks,omitempty"`
 }
 
+type TaskListResponse struct {
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int64  `json:"total"`
+}
+
+type TaskHistoryResponse struct {
+	Revisions []TaskRevision `json:"revisions"`
+}
+
+type TaskRevisionResponse struct {
+	Revision *TaskRevision `json:"revision"`
+}
+
+// BatchTaskRequest is the body of POST /tasks:batch. Each operation's task
+// is decoded as a CreateTaskRequest or UpdateTaskRequest depending on op.
+type BatchTaskRequest struct {
+	Operations []BatchTaskOperation `json:"operations" binding:"required,min=1,max=200,dive"`
+	Atomic     bool                 `json:"atomic"`
+}
+
+type BatchTaskOperation struct {
+	Op       BatchOperationType `json:"op" binding:"required,oneof=create update delete"`
+	ID       string             `json:"id"`
+	Task     json.RawMessage    `json:"task"`
+	Revision int                `json:"if_match" binding:"min=0"`
+}
+
+type BatchOperationResponse struct {
+	Index  int                `json:"index"`
+	Op     BatchOperationType `json:"op"`
+	Status int                `json:"status"`
+	Task   *Task              `json:"task,omitempty"`
+	Code   ErrorCode          `json:"code,omitempty"`
+	Error  string             `json:"error,omitempty"`
+}
+
+type BatchTaskResponse struct {
+	Results   []BatchOperationResponse `json:"results"`
+	Succeeded int                      `json:"succeeded"`
+	Failed    int                      `json:"failed"`
+}
+
 // Auth Request and Response DTOs
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required,min=6"`
-	Role     Role   `json:"role"`
+	Username string `json:"username" binding:"required,username"`
+	Password string `json:"password" binding:"required,password"`
+}
+
+type BootstrapAdminRequest struct {
+	Token string `json:"token" binding:"required"`
 }
 
 type LoginRequest struct {
@@ -95,7 +587,28 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type UpdateRoleRequest struct {
+	Role Role `json:"role" binding:"required,oneof=admin user auditor"`
+}
+
+type UserListResponse struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int64  `json:"total"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// TokenPair is the access and refresh token handed out at login
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // access token lifetime in seconds
+}
+
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
-}
\ No newline at end of file
+	TokenPair
+	User User `json:"user"`
+}
@@ -0,0 +1,96 @@
+package Domain
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		want     bool
+	}{
+		{"bob", true},
+		{"jane.doe-99_x", true},
+		{strings.Repeat("a", MaxUsernameLength), true},
+		{"bo", false},
+		{strings.Repeat("a", MaxUsernameLength+1), false},
+		{"", false},
+		{"jane doe", false},
+		{"jane@example.com", false},
+		{"josé", false},
+		{"../admin", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			if got := ValidUsername(tt.username); got != tt.want {
+				t.Errorf("ValidUsername(%q) = %v, want %v", tt.username, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{"letters and digits", "password1", true},
+		{"shortest", "abcdefg1", true},
+		{"longest", strings.Repeat("a", MaxPasswordLength-1) + "1", true},
+		{"too short", "abcdef1", false},
+		{"too long", strings.Repeat("a", MaxPasswordLength) + "1", false},
+		{"no digit", "password", false},
+		{"no letter", "12345678", false},
+		{"non-ASCII letter", "pässwört1", true},
+		// 36 two-byte letters are 72 bytes, so the digit makes it too long
+		{"counted in bytes", strings.Repeat("é", MaxPasswordLength/2) + "1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidPassword(tt.password); got != tt.want {
+				t.Errorf("ValidPassword(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBindingLimits checks that the max binding rules of the task DTOs
+// agree with the limits use cases check tasks from other sources against
+func TestBindingLimits(t *testing.T) {
+	limits := []struct {
+		field string
+		// rule is the binding rule holding the limit, counted from the
+		// end, since dive rules apply to the items
+		rule int
+		want int
+	}{
+		{"Title", 1, MaxTitleLength},
+		{"Description", 1, MaxDescriptionLength},
+		{"Recurrence", 1, MaxRecurrenceLength},
+		{"Tags", 4, MaxTagsPerTask},
+		{"Tags", 1, MaxTagLength},
+	}
+	for _, dto := range []any{CreateTaskRequest{}, UpdateTaskRequest{}} {
+		dtoType := reflect.TypeOf(dto)
+		for _, limit := range limits {
+			t.Run(dtoType.Name()+"."+limit.field, func(t *testing.T) {
+				field, ok := dtoType.FieldByName(limit.field)
+				if !ok {
+					t.Fatalf("%s has no field %s", dtoType.Name(), limit.field)
+				}
+				rules := strings.Split(field.Tag.Get("binding"), ",")
+				if limit.rule > len(rules) {
+					t.Fatalf("binding rules %v are missing the limit", rules)
+				}
+				max, ok := strings.CutPrefix(rules[len(rules)-limit.rule], "max=")
+				if got, err := strconv.Atoi(max); !ok || err != nil || got != limit.want {
+					t.Errorf("binding rules %v, want max=%d", rules, limit.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package Domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorCode identifies a kind of error. Codes are sent to clients, which
+// switch on them, so a released code never changes meaning.
+type ErrorCode string
+
+const (
+	CodeInvalidRequest     ErrorCode = "invalid_request"
+	CodeInvalidInput       ErrorCode = "invalid_input"
+	CodeInvalidID          ErrorCode = "invalid_id"
+	CodeInvalidCursor      ErrorCode = "invalid_cursor"
+	CodeInvalidPatch       ErrorCode = "invalid_patch"
+	CodeNotFound           ErrorCode = "not_found"
+	CodeRelatedNotFound    ErrorCode = "related_not_found"
+	CodeUnauthorized       ErrorCode = "unauthorized"
+	CodeInvalidCredentials ErrorCode = "invalid_credentials"
+	CodeInvalidToken       ErrorCode = "invalid_token"
+	CodeTokenReused        ErrorCode = "token_reused"
+	CodeForbidden          ErrorCode = "forbidden"
+	CodeMethodNotAllowed   ErrorCode = "method_not_allowed"
+	CodeAccountDisabled    ErrorCode = "account_disabled"
+	CodeBootstrapDisabled  ErrorCode = "bootstrap_disabled"
+	CodeUsernameTaken      ErrorCode = "username_taken"
+	CodeAdminExists        ErrorCode = "admin_exists"
+	CodeUserInUse          ErrorCode = "user_in_use"
+	CodeDependencyCycle    ErrorCode = "dependency_cycle"
+	CodeTaskBlocked        ErrorCode = "task_blocked"
+	CodeOccurrenceExists   ErrorCode = "occurrence_exists"
+	CodePatchConflict      ErrorCode = "patch_conflict"
+	CodeTooManyWebhooks    ErrorCode = "too_many_webhooks"
+	CodeRevisionMismatch   ErrorCode = "revision_mismatch"
+	CodeBatchAborted       ErrorCode = "batch_aborted"
+	CodeNoTransactions     ErrorCode = "transactions_unsupported"
+	CodePayloadTooLarge    ErrorCode = "payload_too_large"
+	CodeUnsupportedFormat  ErrorCode = "unsupported_format"
+	CodeTimeout            ErrorCode = "timeout"
+	CodeCanceled           ErrorCode = "canceled"
+	CodeInternal           ErrorCode = "internal_error"
+)
+
+// Error is an error clients are told about: a stable code, a message for
+// people and, for invalid input, the fields at fault. Cause is the error
+// it was made from, if any, and is never shown to clients.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Fields  []FieldError
+	Cause   error
+}
+
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is matches any *Error with the same code, so errors.Is(err, ErrNotFound)
+// holds for copies made with WithMessage or Wrap
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns a copy of e with a message that says more about
+// what went wrong, such as which kind of resource was not found
+func (e *Error) WithMessage(message string) *Error {
+	copied := *e
+	copied.Message = message
+	return &copied
+}
+
+// Wrap returns a copy of e caused by cause
+func (e *Error) Wrap(cause error) *Error {
+	copied := *e
+	copied.Cause = cause
+	return &copied
+}
+
+// InvalidFields returns an invalid input error listing the fields, for
+// requests with several invalid values
+func InvalidFields(fields ...FieldError) *Error {
+	messages := make([]string, len(fields))
+	for i := range fields {
+		messages[i] = fields[i].Error()
+	}
+	return &Error{Code: CodeInvalidInput, Message: strings.Join(messages, "; "), Fields: fields}
+}
+
+// InField returns a copy of e about a value nested in field, so that
+// "title" of the first batch operation becomes "operations[0].task.title"
+func (e *Error) InField(field string) *Error {
+	if len(e.Fields) == 0 {
+		return e.WithMessage(field + ": " + e.Message)
+	}
+
+	fields := make([]FieldError, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = FieldError{Field: field + "." + f.Field, Message: f.Message}
+	}
+	nested := InvalidFields(fields...)
+	nested.Cause = e.Cause
+	return nested
+}
+
+// Common errors. Use cases return these as they are, so they can be
+// compared with ==.
+var (
+	ErrInvalidRequest     = NewError(CodeInvalidRequest, "Invalid request")
+	ErrNotFound           = NewError(CodeNotFound, "Resource not found")
+	ErrInvalidID          = NewError(CodeInvalidID, "Invalid ID format")
+	ErrInvalidInput       = NewError(CodeInvalidInput, "Invalid input")
+	ErrUsernameTaken      = NewError(CodeUsernameTaken, "Username already exists")
+	ErrInvalidCredentials = NewError(CodeInvalidCredentials, "Invalid username or password")
+	ErrUnauthorized       = NewError(CodeUnauthorized, "Unauthorized")
+	ErrForbidden          = NewError(CodeForbidden, "Permission denied")
+	ErrMethodNotAllowed   = NewError(CodeMethodNotAllowed, "Method not allowed for this route")
+	ErrInvalidCursor      = NewError(CodeInvalidCursor, "Invalid or expired cursor")
+	ErrInvalidToken       = NewError(CodeInvalidToken, "Invalid or expired token")
+	ErrTokenReused        = NewError(CodeTokenReused, "Refresh token already used")
+	ErrAccountDisabled    = NewError(CodeAccountDisabled, "Account is disabled")
+	ErrAdminExists        = NewError(CodeAdminExists, "An admin already exists")
+	ErrUserInUse          = NewError(CodeUserInUse, "User still has tasks or webhooks; remove them or disable the user instead")
+	ErrBootstrapDisabled  = NewError(CodeBootstrapDisabled, "Admin bootstrap is not enabled")
+	ErrDependencyCycle    = NewError(CodeDependencyCycle, "A task cannot depend on or contain itself")
+	ErrTaskBlocked        = NewError(CodeTaskBlocked, "Task is blocked by incomplete tasks; set force to complete it anyway")
+	ErrRelatedNotFound    = NewError(CodeRelatedNotFound, "Parent or blocking task not found")
+	ErrOccurrenceExists   = NewError(CodeOccurrenceExists, "Occurrence already exists")
+	ErrRevisionMismatch   = NewError(CodeRevisionMismatch, "Task has been changed since it was read")
+	ErrInvalidPatch       = NewError(CodeInvalidPatch, "Malformed patch document")
+	ErrPatchConflict      = NewError(CodePatchConflict, "Patch cannot be applied")
+	ErrBatchAborted       = NewError(CodeBatchAborted, "Not applied because another operation in the batch failed")
+	ErrNoTransactions     = NewError(CodeNoTransactions, "The database does not support transactions; MongoDB needs a replica set")
+	ErrTooManyWebhooks    = NewError(CodeTooManyWebhooks, fmt.Sprintf("Users can have at most %d webhooks", MaxWebhooksPerUser))
+	ErrPayloadTooLarge    = NewError(CodePayloadTooLarge, "Request body is too large")
+	ErrUnsupportedFormat  = NewError(CodeUnsupportedFormat, "Unsupported format")
+	ErrTimeout            = NewError(CodeTimeout, "Request timed out")
+	ErrCanceled           = NewError(CodeCanceled, "Request canceled")
+	ErrInternal           = NewError(CodeInternal, "An unexpected error occurred")
+)
+
+// FieldError reports an invalid value for one field of a request. It
+// matches ErrInvalidInput with errors.Is.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+func (e *FieldError) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == CodeInvalidInput
+}
+
+// AsError turns any error into an *Error. Errors wrapping an *Error keep
+// its code but take their own message, field errors become invalid input,
+// a context running out becomes a timeout or cancellation, and anything
+// else is an internal error.
+func AsError(err error) *Error {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		if domainErr == err {
+			return domainErr
+		}
+		return &Error{Code: domainErr.Code, Message: err.Error(), Fields: domainErr.Fields, Cause: err}
+	}
+
+	var fieldErr *FieldError
+	switch {
+	case errors.As(err, &fieldErr):
+		return &Error{Code: CodeInvalidInput, Message: fieldErr.Error(), Fields: []FieldError{*fieldErr}, Cause: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout.Wrap(err)
+	case errors.Is(err, context.Canceled):
+		return ErrCanceled.Wrap(err)
+	}
+	return ErrInternal.Wrap(err)
+}
@@ -0,0 +1,70 @@
+package Domain
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TaskEventType string
+
+const (
+	TaskEventCreated TaskEventType = "task.created"
+	TaskEventUpdated TaskEventType = "task.updated"
+	TaskEventDeleted TaskEventType = "task.deleted"
+)
+
+func (t TaskEventType) IsValid() bool {
+	switch t {
+	case TaskEventCreated, TaskEventUpdated, TaskEventDeleted:
+		return true
+	}
+	return false
+}
+
+// TaskEvent tells that a task changed. Undeleted tasks are created again
+// as far as events go.
+type TaskEvent struct {
+	// ID identifies the event to resume a stream after it
+	ID         string        `json:"id" bson:"_id"`
+	Type       TaskEventType `json:"type" bson:"type"`
+	Task       Task          `json:"task" bson:"task"`
+	OccurredAt time.Time     `json:"occurred_at" bson:"occurred_at"`
+	// Viewers are the owner and collaborators before and after the change,
+	// so users who lose access still hear of it
+	Viewers []primitive.ObjectID `json:"-" bson:"viewers"`
+}
+
+// VisibleTo tells whether a user reading tasks in scope may see the event
+func (e TaskEvent) VisibleTo(scope TaskScope) bool {
+	if scope.AllTasks {
+		return true
+	}
+	if scope.ExcludeShared {
+		return e.Task.UserID == scope.UserID
+	}
+	return slices.Contains(e.Viewers, scope.UserID)
+}
+
+// TaskEventSubscription receives the events of a TaskEventBus
+type TaskEventSubscription struct {
+	// Events is closed if the subscriber falls too far behind; it can
+	// subscribe again from the last event it got
+	Events <-chan TaskEvent
+	// Incomplete is set if events after the requested one are no longer
+	// kept, so some were missed
+	Incomplete bool
+	// Cancel ends the subscription
+	Cancel func()
+}
+
+// TaskEventBus passes task events on to the subscribers they are visible to
+type TaskEventBus interface {
+	// Publish sends an event to subscribers, giving it an ID and time if
+	// it has none. It never blocks on slow subscribers.
+	Publish(event TaskEvent)
+	// Subscribe receives the events accepted by filter that are published
+	// after the one with lastEventID, or from now on if it is empty
+	Subscribe(lastEventID string, filter func(event TaskEvent) bool) *TaskEventSubscription
+}
@@ -0,0 +1,146 @@
+package Domain
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Permission is a named capability, written as resource:action[:scope]
+type Permission string
+
+// Available permissions
+const (
+	PermTasksReadOwn  Permission = "tasks:read:own"
+	PermTasksReadAny  Permission = "tasks:read:any"
+	PermTasksWriteOwn Permission = "tasks:write:own"
+	PermTasksWriteAny Permission = "tasks:write:any"
+	PermUsersRead     Permission = "users:read"
+	PermUsersManage   Permission = "users:manage"
+	PermAuditRead     Permission = "audit:read"
+)
+
+// RolePermissions defines every role as the set of permissions it grants.
+// Adding a role only takes an entry here.
+var RolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermTasksReadOwn, PermTasksReadAny,
+		PermTasksWriteOwn, PermTasksWriteAny,
+		PermUsersRead, PermUsersManage,
+		PermAuditRead,
+	},
+	RoleUser: {
+		PermTasksReadOwn, PermTasksWriteOwn,
+	},
+	RoleAuditor: {
+		PermTasksReadOwn, PermTasksReadAny,
+		PermUsersRead,
+	},
+}
+
+// Permissions returns the permissions granted by the role
+func (r Role) Permissions() []Permission {
+	return RolePermissions[r]
+}
+
+// TaskAccess is the kind of access a task operation needs
+type TaskAccess int
+
+const (
+	TaskAccessRead   TaskAccess = iota // owner, viewers and editors
+	TaskAccessWrite                    // owner and editors
+	TaskAccessManage                   // owner only: deleting and sharing
+)
+
+// TaskScope tells a TaskRepository which tasks an operation may touch. It is
+// derived from a Policy, so repositories never reason about roles.
+type TaskScope struct {
+	UserID primitive.ObjectID
+	Access TaskAccess
+	// AllTasks lifts the ownership restriction
+	AllTasks bool
+	// ExcludeShared limits the scope to tasks the user owns
+	ExcludeShared bool
+	// Deleted selects tasks in the trash instead of live ones
+	Deleted bool
+	// AnyDeletedState selects live and trashed tasks alike, ignoring Deleted
+	AnyDeletedState bool
+	// Revision, when set, makes updates and deletes fail with
+	// ErrRevisionMismatch unless the task is still at that revision
+	Revision int
+}
+
+// Allows reports whether the task is inside the scope
+func (s TaskScope) Allows(task Task) bool {
+	if !s.AnyDeletedState && (task.DeletedAt != nil) != s.Deleted {
+		return false
+	}
+
+	if s.AllTasks || task.UserID == s.UserID {
+		return true
+	}
+
+	if s.ExcludeShared || s.Access == TaskAccessManage {
+		return false
+	}
+
+	level, shared := task.ShareLevelFor(s.UserID)
+	if !shared {
+		return false
+	}
+	return s.Access == TaskAccessRead || level == ShareLevelEditor
+}
+
+// Policy is what an authenticated user may do
+type Policy struct {
+	UserID      primitive.ObjectID
+	Role        Role
+	permissions map[Permission]bool
+}
+
+// NewPolicy builds the policy for a user with the given role
+func NewPolicy(userID primitive.ObjectID, role Role) *Policy {
+	permissions := make(map[Permission]bool)
+	for _, perm := range role.Permissions() {
+		permissions[perm] = true
+	}
+
+	return &Policy{
+		UserID:      userID,
+		Role:        role,
+		permissions: permissions,
+	}
+}
+
+// Can reports whether the policy grants the permission
+func (p *Policy) Can(perm Permission) bool {
+	return p.permissions[perm]
+}
+
+// CanAny reports whether the policy grants at least one of the permissions
+func (p *Policy) CanAny(perms ...Permission) bool {
+	for _, perm := range perms {
+		if p.Can(perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// TaskScope returns the tasks the user may access in the given way, or
+// ErrForbidden when they may not access tasks that way at all
+func (p *Policy) TaskScope(access TaskAccess) (TaskScope, error) {
+	ownPerm, anyPerm := PermTasksReadOwn, PermTasksReadAny
+	if access != TaskAccessRead {
+		ownPerm, anyPerm = PermTasksWriteOwn, PermTasksWriteAny
+	}
+
+	scope := TaskScope{UserID: p.UserID, Access: access}
+	switch {
+	case p.Can(anyPerm):
+		scope.AllTasks = true
+	case p.Can(ownPerm):
+	default:
+		return scope, ErrForbidden
+	}
+
+	return scope, nil
+}
@@ -0,0 +1,243 @@
+package Domain
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is how often a recurring task repeats
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "DAILY"
+	FrequencyWeekly  Frequency = "WEEKLY"
+	FrequencyMonthly Frequency = "MONTHLY"
+	FrequencyYearly  Frequency = "YEARLY"
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// RecurrenceRule is a subset of the iCalendar RRULE (RFC 5545): FREQ,
+// INTERVAL, BYDAY (weekly rules), BYMONTHDAY (monthly rules), COUNT and
+// UNTIL. For example "FREQ=WEEKLY;BYDAY=MO" or "FREQ=MONTHLY;BYMONTHDAY=1".
+type RecurrenceRule struct {
+	Frequency Frequency
+	Interval  int
+	// ByDay lists the weekdays a weekly rule falls on
+	ByDay []time.Weekday
+	// ByMonthDay lists the days a monthly rule falls on; -1 is the last day
+	ByMonthDay []int
+	// Count is the total number of occurrences, 0 for no limit
+	Count int
+	Until *time.Time
+}
+
+// ParseRecurrenceRule parses an RRULE value, with or without the "RRULE:"
+// prefix. It returns ErrInvalidInput for rules it does not support.
+func ParseRecurrenceRule(value string) (*RecurrenceRule, error) {
+	rule := &RecurrenceRule{Interval: 1}
+
+	value = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "RRULE:")
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, ErrInvalidInput
+		}
+
+		var err error
+		switch key {
+		case "FREQ":
+			rule.Frequency = Frequency(val)
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(val)
+			if rule.Interval < 1 {
+				err = ErrInvalidInput
+			}
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return nil, ErrInvalidInput
+				}
+				if !slices.Contains(rule.ByDay, day) {
+					rule.ByDay = append(rule.ByDay, day)
+				}
+			}
+		case "BYMONTHDAY":
+			for _, s := range strings.Split(val, ",") {
+				day, err := strconv.Atoi(s)
+				if err != nil || day == 0 || day < -1 || day > 31 {
+					return nil, ErrInvalidInput
+				}
+				if !slices.Contains(rule.ByMonthDay, day) {
+					rule.ByMonthDay = append(rule.ByMonthDay, day)
+				}
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(val)
+			if rule.Count < 1 {
+				err = ErrInvalidInput
+			}
+		case "UNTIL":
+			var until time.Time
+			if until, err = time.Parse("20060102T150405Z", val); err != nil {
+				until, err = time.Parse("20060102", val)
+			}
+			rule.Until = &until
+		default:
+			return nil, ErrInvalidInput
+		}
+		if err != nil {
+			return nil, ErrInvalidInput
+		}
+	}
+
+	switch rule.Frequency {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+	default:
+		return nil, ErrInvalidInput
+	}
+
+	if len(rule.ByDay) > 0 && rule.Frequency != FrequencyWeekly ||
+		len(rule.ByMonthDay) > 0 && rule.Frequency != FrequencyMonthly ||
+		rule.Count > 0 && rule.Until != nil {
+		return nil, ErrInvalidInput
+	}
+
+	return rule, nil
+}
+
+// String formats the rule as an RRULE value
+func (r *RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + string(r.Frequency)}
+
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for code, day := range weekdayCodes {
+			if slices.Contains(r.ByDay, day) {
+				days = append(days, code)
+			}
+		}
+		slices.SortFunc(days, func(a, b string) int {
+			return int(mondayFirst(weekdayCodes[a]) - mondayFirst(weekdayCodes[b]))
+		})
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence after the given one, keeping its time
+// of day. Monthly and yearly dates that do not exist in a month, such as
+// the 31st or February 29th, fall on the last day of that month. COUNT and
+// UNTIL are not applied here since they depend on the series.
+func (r *RecurrenceRule) Next(after time.Time) time.Time {
+	switch r.Frequency {
+	case FrequencyDaily:
+		return after.AddDate(0, 0, r.Interval)
+
+	case FrequencyWeekly:
+		if len(r.ByDay) == 0 {
+			return after.AddDate(0, 0, 7*r.Interval)
+		}
+		// Later in the same week (weeks start on Monday)...
+		weekday := mondayFirst(after.Weekday())
+		for offset := 1; weekday+offset < 7; offset++ {
+			if slices.Contains(r.ByDay, time.Weekday((weekday+offset+1)%7)) {
+				return after.AddDate(0, 0, offset)
+			}
+		}
+		// ...or on the first matching day INTERVAL weeks later
+		weekStart := after.AddDate(0, 0, 7*r.Interval-weekday)
+		for offset := 0; ; offset++ {
+			if slices.Contains(r.ByDay, time.Weekday((offset+1)%7)) {
+				return weekStart.AddDate(0, 0, offset)
+			}
+		}
+
+	case FrequencyMonthly:
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{after.Day()}
+		}
+		if next, ok := nextMonthDay(after, days, 0); ok {
+			return next
+		}
+		next, _ := nextMonthDay(after, days, r.Interval)
+		return next
+
+	default:
+		return dateIn(after, after.Year()+r.Interval, after.Month(), after.Day())
+	}
+}
+
+// nextMonthDay returns the earliest of days in the month monthOffset months
+// after t. In t's own month only days later than t count.
+func nextMonthDay(t time.Time, days []int, monthOffset int) (time.Time, bool) {
+	first := time.Date(t.Year(), t.Month()+time.Month(monthOffset), 1, 0, 0, 0, 0, t.Location())
+	last := daysIn(first.Year(), first.Month())
+
+	var best time.Time
+	found := false
+	for _, day := range days {
+		if day == -1 || day > last {
+			day = last
+		}
+		candidate := dateIn(t, first.Year(), first.Month(), day)
+		if monthOffset == 0 && !candidate.After(t) {
+			continue
+		}
+		if !found || candidate.Before(best) {
+			best, found = candidate, true
+		}
+	}
+	return best, found
+}
+
+// dateIn returns the date with t's time of day, clamping day to the month
+func dateIn(t time.Time, year int, month time.Month, day int) time.Time {
+	if last := daysIn(year, month); day > last {
+		day = last
+	}
+	return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// mondayFirst numbers weekdays from Monday (0) to Sunday (6)
+func mondayFirst(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
@@ -0,0 +1,99 @@
+package Domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrenceRule(t *testing.T) {
+	tests := []struct {
+		value string
+		// want is the rule in canonical form; empty if it is invalid
+		want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"RRULE:FREQ=DAILY", "FREQ=DAILY"},
+		{"  rrule:freq=daily;interval=2 ", "FREQ=DAILY;INTERVAL=2"},
+		{"FREQ=DAILY;INTERVAL=1", "FREQ=DAILY"},
+		{"FREQ=WEEKLY;BYDAY=FR,MO,WE,MO", "FREQ=WEEKLY;BYDAY=MO,WE,FR"},
+		{"FREQ=WEEKLY;BYDAY=SU,SA", "FREQ=WEEKLY;BYDAY=SA,SU"},
+		{"FREQ=MONTHLY;BYMONTHDAY=15,1,-1,15", "FREQ=MONTHLY;BYMONTHDAY=15,1,-1"},
+		{"FREQ=YEARLY;COUNT=5", "FREQ=YEARLY;COUNT=5"},
+		{"FREQ=DAILY;UNTIL=20240131T120000Z", "FREQ=DAILY;UNTIL=20240131T120000Z"},
+		{"FREQ=DAILY;UNTIL=20240131", "FREQ=DAILY;UNTIL=20240131T000000Z"},
+
+		{"", ""},
+		{"FREQ=HOURLY", ""},
+		{"INTERVAL=2", ""},
+		{"FREQ=DAILY;", ""},
+		{"FREQ=DAILY;INTERVAL", ""},
+		{"FREQ=DAILY;INTERVAL=0", ""},
+		{"FREQ=DAILY;INTERVAL=two", ""},
+		{"FREQ=WEEKLY;BYDAY=XX", ""},
+		{"FREQ=WEEKLY;BYDAY=1MO", ""},
+		{"FREQ=DAILY;BYDAY=MO", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=0", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=-2", ""},
+		{"FREQ=WEEKLY;BYMONTHDAY=1", ""},
+		{"FREQ=DAILY;COUNT=0", ""},
+		{"FREQ=DAILY;COUNT=3;UNTIL=20240131", ""},
+		{"FREQ=DAILY;UNTIL=2024-01-31", ""},
+		{"FREQ=DAILY;BYSETPOS=1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			rule, err := ParseRecurrenceRule(tt.value)
+			if tt.want == "" {
+				if err != ErrInvalidInput {
+					t.Fatalf("ParseRecurrenceRule = %v, %v; want ErrInvalidInput", rule, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRecurrenceRule: %v", err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceRuleNext(t *testing.T) {
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		rule  string
+		after time.Time
+		want  time.Time
+	}{
+		{"FREQ=DAILY", at(2024, 1, 31), at(2024, 2, 1)},
+		{"FREQ=DAILY;INTERVAL=3", at(2024, 2, 28), at(2024, 3, 2)},
+		{"FREQ=WEEKLY", at(2024, 1, 3), at(2024, 1, 10)},
+		// Wednesday 2024-01-03 to Friday, then to Monday of the next week
+		{"FREQ=WEEKLY;BYDAY=MO,FR", at(2024, 1, 3), at(2024, 1, 5)},
+		{"FREQ=WEEKLY;BYDAY=MO,FR", at(2024, 1, 5), at(2024, 1, 8)},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", at(2024, 1, 8), at(2024, 1, 22)},
+		{"FREQ=WEEKLY;BYDAY=SU", at(2024, 1, 7), at(2024, 1, 14)},
+		{"FREQ=MONTHLY", at(2024, 1, 15), at(2024, 2, 15)},
+		{"FREQ=MONTHLY;BYMONTHDAY=31", at(2024, 1, 31), at(2024, 2, 29)},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", at(2023, 2, 28), at(2023, 3, 31)},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,15", at(2024, 1, 1), at(2024, 1, 15)},
+		{"FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1", at(2024, 1, 1), at(2024, 4, 1)},
+		{"FREQ=YEARLY", at(2024, 2, 29), at(2025, 2, 28)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule+" after "+tt.after.Format("2006-01-02"), func(t *testing.T) {
+			rule, err := ParseRecurrenceRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRecurrenceRule: %v", err)
+			}
+			if got := rule.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package Domain
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Search limits
+const (
+	DefaultSearchLimit  = 20
+	MaxSearchLimit      = 100
+	MaxSearchQueryTerms = 10
+	MaxSearchQueryBytes = 256
+)
+
+// SearchQuery is a parsed full-text query. A task matches if it contains
+// every phrase and a word starting with every prefix. Without phrases or
+// prefixes, it must contain at least one of the words; otherwise words
+// only affect ranking.
+type SearchQuery struct {
+	Words    []string
+	Phrases  [][]string
+	Prefixes []string
+}
+
+// ParseSearchQuery parses a query such as `deploy "release notes" migr*`:
+// quoted text is a phrase and a trailing * makes a word a prefix. Words are
+// matched case-insensitively and split like SearchTokens splits text.
+// ErrInvalidInput is returned for queries with nothing to search for, more
+// than MaxSearchQueryTerms parts or more than MaxSearchQueryBytes.
+func ParseSearchQuery(q string) (SearchQuery, error) {
+	var query SearchQuery
+	if len(q) > MaxSearchQueryBytes {
+		return query, ErrInvalidInput
+	}
+
+	for i, part := range strings.Split(q, `"`) {
+		// Odd parts are inside quotes
+		if i%2 == 1 {
+			words := searchWords(part)
+			if len(words) > 0 {
+				query.Phrases = append(query.Phrases, words)
+			}
+			continue
+		}
+
+		for _, field := range strings.Fields(part) {
+			words := searchWords(field)
+			if len(words) > 0 && strings.HasSuffix(field, "*") {
+				query.Prefixes = append(query.Prefixes, words[len(words)-1])
+				words = words[:len(words)-1]
+			}
+			query.Words = append(query.Words, words...)
+		}
+	}
+
+	parts := len(query.Words) + len(query.Phrases) + len(query.Prefixes)
+	if parts == 0 || parts > MaxSearchQueryTerms {
+		return query, ErrInvalidInput
+	}
+	return query, nil
+}
+
+// SearchToken is a word of a text and where it is, in bytes
+type SearchToken struct {
+	Word       string
+	Start, End int
+}
+
+// SearchTokens splits text into lowercase words of letters and digits
+func SearchTokens(text string) []SearchToken {
+	var tokens []SearchToken
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			tokens = append(tokens, SearchToken{Word: strings.ToLower(text[start:i]), Start: start, End: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, SearchToken{Word: strings.ToLower(text[start:]), Start: start, End: len(text)})
+	}
+	return tokens
+}
+
+func searchWords(text string) []string {
+	tokens := SearchTokens(text)
+	words := make([]string, len(tokens))
+	for i, token := range tokens {
+		words[i] = token.Word
+	}
+	return words
+}
+
+// TaskSearchOptions are the parameters of a task search
+type TaskSearchOptions struct {
+	Query         string
+	Limit         int
+	IncludeShared bool
+}
+
+// TaskSearchHit is a task that matched a search. Highlights hold snippets
+// of the matching fields, HTML-escaped, with matches wrapped in <mark>.
+type TaskSearchHit struct {
+	Task       Task              `json:"task"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+type TaskSearchResponse struct {
+	Hits []TaskSearchHit `json:"hits"`
+}
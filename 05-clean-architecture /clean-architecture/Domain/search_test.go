@@ -0,0 +1,68 @@
+package Domain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		q       string
+		want    SearchQuery
+		wantErr bool
+	}{
+		{"words", "Deploy  the APP", SearchQuery{Words: []string{"deploy", "the", "app"}}, false},
+		{"phrase", `"Release Notes"`, SearchQuery{Phrases: [][]string{{"release", "notes"}}}, false},
+		{"prefix", "migr*", SearchQuery{Prefixes: []string{"migr"}}, false},
+		{"mixed", `deploy "release notes" migr*`, SearchQuery{
+			Words:    []string{"deploy"},
+			Phrases:  [][]string{{"release", "notes"}},
+			Prefixes: []string{"migr"},
+		}, false},
+		{"punctuation splits words", "e-mail", SearchQuery{Words: []string{"e", "mail"}}, false},
+		{"prefix after punctuation", "co-work*", SearchQuery{Words: []string{"co"}, Prefixes: []string{"work"}}, false},
+		{"unclosed quote is a phrase", `plan "next week`, SearchQuery{Words: []string{"plan"}, Phrases: [][]string{{"next", "week"}}}, false},
+		{"empty quotes are ignored", `"" report`, SearchQuery{Words: []string{"report"}}, false},
+		{"unicode", "Größe 日本", SearchQuery{Words: []string{"größe", "日本"}}, false},
+		{"ten terms", "a b c d e f g h i j", SearchQuery{Words: strings.Fields("a b c d e f g h i j")}, false},
+
+		{"empty", "", SearchQuery{}, true},
+		{"only punctuation", `-- * ""`, SearchQuery{}, true},
+		{"too many terms", "a b c d e f g h i j k", SearchQuery{}, true},
+		{"too long", strings.Repeat("a", MaxSearchQueryBytes+1), SearchQuery{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSearchQuery(tt.q)
+			if tt.wantErr {
+				if err != ErrInvalidInput {
+					t.Fatalf("ParseSearchQuery = %+v, %v; want ErrInvalidInput", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSearchQuery: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSearchQuery = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchTokens(t *testing.T) {
+	got := SearchTokens("Fix  the Café's log-in!")
+	want := []SearchToken{
+		{Word: "fix", Start: 0, End: 3},
+		{Word: "the", Start: 5, End: 8},
+		{Word: "café", Start: 9, End: 14},
+		{Word: "s", Start: 15, End: 16},
+		{Word: "log", Start: 17, End: 20},
+		{Word: "in", Start: 21, End: 23},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchTokens = %+v, want %+v", got, want)
+	}
+}
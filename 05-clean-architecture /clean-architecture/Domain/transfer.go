@@ -0,0 +1,55 @@
+package Domain
+
+// TaskFormat is a file format tasks are exported to or imported from
+type TaskFormat string
+
+const (
+	TaskFormatCSV   TaskFormat = "csv"
+	TaskFormatJSONL TaskFormat = "jsonl"
+	// TaskFormatICS is iCalendar, which tasks can only be exported to
+	TaskFormatICS TaskFormat = "ics"
+)
+
+func (f TaskFormat) IsValid() bool {
+	switch f {
+	case TaskFormatCSV, TaskFormatJSONL, TaskFormatICS:
+		return true
+	}
+	return false
+}
+
+// MaxImportRows is the most tasks one import may create
+const MaxImportRows = 1000
+
+// TaskImportRow is a task read from an import file. Err is set if the row
+// could not be read, such as a malformed date; it is reported as the row's
+// result.
+type TaskImportRow struct {
+	// Line is where the row starts in the file, counting from 1
+	Line int
+	Task CreateTaskRequest
+	Err  error
+}
+
+// TaskImportResult is the outcome of one row. Task is only set for rows
+// that were imported.
+type TaskImportResult struct {
+	Line int
+	Task *Task
+	Err  error
+}
+
+type TaskImportError struct {
+	Line  int       `json:"line"`
+	Field string    `json:"field,omitempty"`
+	Code  ErrorCode `json:"code"`
+	Error string    `json:"error"`
+}
+
+type TaskImportResponse struct {
+	DryRun   bool              `json:"dry_run"`
+	Rows     int               `json:"rows"`
+	Imported int               `json:"imported"`
+	Errors   []TaskImportError `json:"errors"`
+	Tasks    []Task            `json:"tasks,omitempty"`
+}
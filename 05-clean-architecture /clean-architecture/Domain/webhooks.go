@@ -0,0 +1,195 @@
+package Domain
+
+import (
+	"context"
+	"net/netip"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Webhook limits and retry policy
+const (
+	MaxWebhooksPerUser = 10
+	// MaxWebhookAttempts is how often a delivery is tried before it is
+	// given up and left in the dead-letter list
+	MaxWebhookAttempts = 8
+	// WebhookRetryDelay is the wait before the first retry; it doubles with
+	// every further one up to MaxWebhookRetryDelay
+	WebhookRetryDelay    = 30 * time.Second
+	MaxWebhookRetryDelay = time.Hour
+	// WebhookDeliveryLease is how long a delivery is reserved for the
+	// instance sending it
+	WebhookDeliveryLease = time.Minute
+
+	DefaultWebhookDeliveryPageSize = 50
+	MaxWebhookDeliveryPageSize     = 200
+)
+
+// Webhook sends the events of the tasks its user can see to a URL
+type Webhook struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id"`
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	URL    string             `json:"url" bson:"url"`
+	// Events are the event types to send; empty means all of them
+	Events []TaskEventType `json:"events" bson:"events"`
+	// Secret signs the deliveries. It is only shown when the webhook is
+	// created.
+	Secret    string    `json:"-" bson:"secret"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Wants tells whether the webhook subscribes to an event
+func (w Webhook) Wants(event TaskEvent) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, eventType := range w.Events {
+		if eventType == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryFailed deliveries ran out of attempts; they make up
+	// the dead-letter list
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+func (s WebhookDeliveryStatus) IsValid() bool {
+	switch s {
+	case WebhookDeliveryPending, WebhookDeliverySucceeded, WebhookDeliveryFailed:
+		return true
+	}
+	return false
+}
+
+// WebhookAttempt is one try at sending a delivery
+type WebhookAttempt struct {
+	At time.Time `json:"at" bson:"at"`
+	// StatusCode is unset if no response came back
+	StatusCode int    `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error      string `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms" bson:"duration_ms"`
+}
+
+// WebhookDelivery is an event on its way to a webhook
+type WebhookDelivery struct {
+	ID            primitive.ObjectID    `json:"id" bson:"_id"`
+	WebhookID     primitive.ObjectID    `json:"webhook_id" bson:"webhook_id"`
+	UserID        primitive.ObjectID    `json:"user_id" bson:"user_id"`
+	Event         TaskEvent             `json:"event" bson:"event"`
+	Status        WebhookDeliveryStatus `json:"status" bson:"status"`
+	Attempts      []WebhookAttempt      `json:"attempts" bson:"attempts"`
+	NextAttemptAt *time.Time            `json:"next_attempt_at,omitempty" bson:"next_attempt_at,omitempty"`
+	// LockedUntil reserves a pending delivery for the instance sending it
+	LockedUntil *time.Time `json:"-" bson:"locked_until,omitempty"`
+	// LeaseID identifies the claim holding the lock. Only that claim can
+	// save the outcome, even after its lease ran out and it was claimed again.
+	LeaseID   primitive.ObjectID `json:"-" bson:"lease_id,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// WebhookDeliveryFilter narrows down listed deliveries. Zero values mean
+// "no filter".
+type WebhookDeliveryFilter struct {
+	UserID    primitive.ObjectID
+	WebhookID *primitive.ObjectID
+	Status    WebhookDeliveryStatus
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *Webhook) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*Webhook, error)
+	// ListByUsers returns the webhooks of the users, oldest first
+	ListByUsers(ctx context.Context, userIDs []primitive.ObjectID) ([]Webhook, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *WebhookDelivery) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*WebhookDelivery, error)
+	// List returns up to limit matching deliveries, newest first
+	List(ctx context.Context, filter WebhookDeliveryFilter, limit int) ([]WebhookDelivery, error)
+	// ClaimDue locks and returns up to limit pending deliveries due at now
+	// that no other instance holds, until now plus lease
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookDelivery, error)
+	// Update saves the outcome of an attempt and releases the delivery. It
+	// returns ErrNotFound unless the delivery is still held by the claim
+	// that returned it.
+	Update(ctx context.Context, delivery *WebhookDelivery) error
+	DeleteByWebhook(ctx context.Context, webhookID primitive.ObjectID) error
+}
+
+// nonPublicPrefixes are ranges outside those IsPublicAddress checks by
+// kind that still do not reach the public internet
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+}
+
+// IsPublicAddress reports whether webhooks may be sent to an address.
+// Loopback, link-local, private, unspecified and multicast addresses are
+// refused, so a webhook cannot reach the server's own network.
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsPrivate() || addr.IsUnspecified() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// WebhookRequest is what a WebhookSender posts
+type WebhookRequest struct {
+	URL     string
+	Headers map[string]string
+	Body    []byte
+}
+
+// WebhookSender posts deliveries and returns the status code of the
+// response
+type WebhookSender interface {
+	Send(ctx context.Context, request WebhookRequest) (int, error)
+}
+
+// Webhook Request and Response DTOs
+type CreateWebhookRequest struct {
+	URL    string          `json:"url" binding:"required,url,max=2048"`
+	Events []TaskEventType `json:"events" binding:"omitempty,dive,oneof=task.created task.updated task.deleted"`
+	// Secret is generated if it is not set
+	Secret string `json:"secret" binding:"omitempty,min=16,max=256"`
+}
+
+// WebhookSecretResponse is the only response that includes the secret
+type WebhookSecretResponse struct {
+	Webhook *Webhook `json:"webhook"`
+	Secret  string   `json:"secret"`
+}
+
+type WebhookResponse struct {
+	Webhook *Webhook `json:"webhook"`
+}
+
+type WebhookListResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+type WebhookDeliveryResponse struct {
+	Delivery *WebhookDelivery `json:"delivery"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
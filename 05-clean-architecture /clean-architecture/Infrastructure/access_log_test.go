@@ -0,0 +1,25 @@
+package Infrastructure
+
+import "testing"
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/tasks/events", "/tasks/events"},
+		{"/tasks/events?include_shared=true", "/tasks/events?include_shared=true"},
+		{"/tasks/events?access_token=secret", "/tasks/events?access_token=REDACTED"},
+		{"/tasks/events?a=1&access_token=secret&b=2", "/tasks/events?a=1&access_token=REDACTED&b=2"},
+		{"/tasks/events?access%5Ftoken=secret", "/tasks/events?access%5Ftoken=REDACTED"},
+		{"/tasks/events?access_token", "/tasks/events?access_token=REDACTED"},
+		{"/tasks/events?my_access_token=x", "/tasks/events?my_access_token=x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := redactQuery(tt.path); got != tt.want {
+				t.Errorf("redactQuery = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,10 @@
 package Infrastructure
 
 import (
+	"context"
 	"errors"
-	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,13 +12,24 @@ import (
 	"taskmanager/auth/Domain"
 )
 
+// Handlers behind JWTAuth always find the user and session in the context,
+// so not finding them is a server error
+var (
+	errNoUser    = Domain.ErrInternal.WithMessage("Could not identify user")
+	errNoSession = Domain.ErrInternal.WithMessage("Could not identify session")
+)
+
 type AuthMiddleware struct {
 	jwtService *JWTService
+	tokenRepo  Domain.TokenRepository
+	userRepo   Domain.UserRepository
 }
 
-func NewAuthMiddleware(jwtService *JWTService) *AuthMiddleware {
+func NewAuthMiddleware(jwtService *JWTService, tokenRepo Domain.TokenRepository, userRepo Domain.UserRepository) *AuthMiddleware {
 	return &AuthMiddleware{
 		jwtService: jwtService,
+		tokenRepo:  tokenRepo,
+		userRepo:   userRepo,
 	}
 }
 
@@ -35,39 +47,124 @@ func (m *AuthMiddleware) extractTokenFromHeader(c *gin.Context) (string, error)
 	return parts[1], nil
 }
 
+// QueryToken lets clients that cannot set headers, such as browsers opening
+// an EventSource or a WebSocket, send their access token in the
+// access_token query parameter. It must run before JWTAuth.
+func (m *AuthMiddleware) QueryToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
+
 func (m *AuthMiddleware) JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString, err := m.extractTokenFromHeader(c)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			abortWithError(c, Domain.ErrUnauthorized.WithMessage(err.Error()))
 			return
 		}
 
 		claims, err := m.jwtService.ValidateToken(tokenString)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			abortWithError(c, Domain.ErrInvalidToken)
+			return
+		}
+
+		// Tokens without an ID cannot be revoked, so they are not accepted
+		if claims.ID == "" {
+			abortWithError(c, Domain.ErrInvalidToken)
+			return
+		}
+
+		user, err := m.checkSession(c.Request.Context(), claims)
+		if err != nil {
+			abortWithError(c, err)
 			return
 		}
 
 		// Set claims in context for later use
+		c.Set("claims", claims)
 		c.Set("userID", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("role", claims.Role)
+		c.Set("username", user.Username)
+		c.Set("role", string(user.Role))
+		c.Set("policy", Domain.NewPolicy(user.ID, user.Role))
 
 		c.Next()
 	}
 }
 
-func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
+// checkSession returns the user of a valid token, or an error if the token
+// has been revoked or its user deleted or disabled since it was issued
+func (m *AuthMiddleware) checkSession(ctx context.Context, claims *JWTClaims) (*Domain.User, error) {
+	revoked, err := m.tokenRepo.IsTokenIDRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, Domain.ErrInvalidToken.WithMessage("Token has been revoked")
+	}
+
+	// Deleted and disabled users lose access right away, and role
+	// changes apply without waiting for a new token
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return nil, Domain.ErrInvalidToken
+	}
+
+	user, err := m.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if err == Domain.ErrNotFound {
+			return nil, Domain.ErrInvalidToken.WithMessage("User no longer exists")
+		}
+		return nil, err
+	}
+
+	if user.Disabled {
+		return nil, Domain.ErrAccountDisabled
+	}
+	return user, nil
+}
+
+// CheckSession checks again that the request's token is still good. Long
+// requests such as event streams call it from time to time, since JWTAuth
+// only checks the token when they start.
+func (m *AuthMiddleware) CheckSession(c *gin.Context) error {
+	claims, err := m.GetClaimsFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	if claims.ExpiresAt != nil && !time.Now().Before(claims.ExpiresAt.Time) {
+		return Domain.ErrInvalidToken
+	}
+
+	user, err := m.checkSession(c.Request.Context(), claims)
+	if err != nil {
+		return err
+	}
+
+	// What the request may see was decided with the old role
+	if string(user.Role) != c.GetString("role") {
+		return Domain.ErrInvalidToken.WithMessage("Role has changed")
+	}
+	return nil
+}
+
+// RequirePermission lets the request through when the user's role grants
+// at least one of the permissions
+func (m *AuthMiddleware) RequirePermission(perms ...Domain.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		policy, err := m.GetPolicyFromContext(c)
+		if err != nil {
+			abortWithError(c, Domain.ErrUnauthorized.WithMessage("Authentication required"))
 			return
 		}
 
-		if role != string(Domain.RoleAdmin) {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		if !policy.CanAny(perms...) {
+			abortWithError(c, Domain.ErrForbidden)
 			return
 		}
 
@@ -78,13 +175,31 @@ func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
 func (m *AuthMiddleware) GetUserIDFromContext(c *gin.Context) (primitive.ObjectID, error) {
 	userIDStr, exists := c.Get("userID")
 	if !exists {
-		return primitive.ObjectID{}, errors.New("user ID not found in context")
+		return primitive.ObjectID{}, errNoUser
 	}
 
 	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
 	if err != nil {
-		return primitive.ObjectID{}, errors.New("invalid user ID format")
+		return primitive.ObjectID{}, errNoUser
 	}
 
 	return userID, nil
 }
+
+func (m *AuthMiddleware) GetClaimsFromContext(c *gin.Context) (*JWTClaims, error) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		return nil, errNoSession
+	}
+
+	return claims.(*JWTClaims), nil
+}
+
+func (m *AuthMiddleware) GetPolicyFromContext(c *gin.Context) (*Domain.Policy, error) {
+	policy, exists := c.Get("policy")
+	if !exists {
+		return nil, errNoUser
+	}
+
+	return policy.(*Domain.Policy), nil
+}
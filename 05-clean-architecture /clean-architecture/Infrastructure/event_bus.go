@@ -0,0 +1,129 @@
+package Infrastructure
+
+import (
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// it is dropped
+const subscriberBuffer = 64
+
+type eventSubscriber struct {
+	events chan Domain.TaskEvent
+	filter func(event Domain.TaskEvent) bool
+}
+
+// EventBus is an in-process Domain.TaskEventBus. It keeps the most recent
+// events so that subscribers can resume after reconnecting.
+type EventBus struct {
+	mu          sync.Mutex
+	history     []Domain.TaskEvent
+	historySize int
+	subscribers map[*eventSubscriber]bool
+	closed      bool
+}
+
+func NewEventBus(historySize int) *EventBus {
+	return &EventBus{
+		historySize: historySize,
+		subscribers: make(map[*eventSubscriber]bool),
+	}
+}
+
+func (b *EventBus) Publish(event Domain.TaskEvent) {
+	if event.ID == "" {
+		event.ID = primitive.NewObjectID().Hex()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			// Too far behind; it can resume from history
+			b.remove(sub)
+		}
+	}
+}
+
+func (b *EventBus) Subscribe(lastEventID string, filter func(event Domain.TaskEvent) bool) *Domain.TaskEventSubscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []Domain.TaskEvent
+	incomplete := false
+	if lastEventID != "" {
+		incomplete = true
+		for i := len(b.history) - 1; i >= 0; i-- {
+			if b.history[i].ID == lastEventID {
+				missed = b.history[i+1:]
+				incomplete = false
+				break
+			}
+		}
+	}
+
+	sub := &eventSubscriber{
+		events: make(chan Domain.TaskEvent, len(missed)+subscriberBuffer),
+		filter: filter,
+	}
+	for _, event := range missed {
+		if filter(event) {
+			sub.events <- event
+		}
+	}
+	if b.closed {
+		close(sub.events)
+	} else {
+		b.subscribers[sub] = true
+	}
+
+	return &Domain.TaskEventSubscription{
+		Events:     sub.events,
+		Incomplete: incomplete,
+		Cancel: func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.remove(sub)
+		},
+	}
+}
+
+// Close ends every subscription, and any made afterwards right away, so
+// that streams finish and their clients reconnect to another instance
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.remove(sub)
+	}
+}
+
+// remove drops a subscriber and closes its channel; it must be called with
+// the lock held
+func (b *EventBus) remove(sub *eventSubscriber) {
+	if b.subscribers[sub] {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
@@ -0,0 +1,207 @@
+package Infrastructure
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const minRSAKeyBits = 2048
+
+// signingKey is a private key tokens are signed with
+type signingKey struct {
+	id     string
+	method jwt.SigningMethod
+	key    crypto.PrivateKey
+}
+
+// verificationKey is a public key tokens are checked against
+type verificationKey struct {
+	id     string
+	method jwt.SigningMethod
+	key    crypto.PublicKey
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// loadSigningKey reads an RSA or Ed25519 private key from a PEM file
+func loadSigningKey(path string) (*signingKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key crypto.PrivateKey
+	if key, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("%s: unsupported private key format", path)
+		}
+	}
+
+	var public crypto.PublicKey
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		public = &k.PublicKey
+	case ed25519.PrivateKey:
+		public = k.Public()
+	default:
+		return nil, fmt.Errorf("%s: only RSA and Ed25519 keys are supported", path)
+	}
+
+	vk, err := newVerificationKey(public)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &signingKey{id: vk.id, method: vk.method, key: key}, nil
+}
+
+// loadVerificationKey reads an RSA or Ed25519 public key from a PEM file.
+// A private key file is accepted too; only its public half is kept.
+func loadVerificationKey(path string) (*verificationKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY", "RSA PRIVATE KEY":
+		signing, err := loadSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return signing.verificationKey(), nil
+	}
+
+	var key crypto.PublicKey
+	if key, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+		if key, err = x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("%s: unsupported public key format", path)
+		}
+	}
+
+	vk, err := newVerificationKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return vk, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+	return block, nil
+}
+
+func newVerificationKey(key crypto.PublicKey) (*verificationKey, error) {
+	vk := &verificationKey{key: key}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA keys must be at least %d bits", minRSAKeyBits)
+		}
+		vk.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		vk.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+
+	vk.id = vk.thumbprint()
+	return vk, nil
+}
+
+func (k *signingKey) verificationKey() *verificationKey {
+	var public crypto.PublicKey
+	switch key := k.key.(type) {
+	case *rsa.PrivateKey:
+		public = &key.PublicKey
+	case ed25519.PrivateKey:
+		public = key.Public()
+	}
+	return &verificationKey{id: k.id, method: k.method, key: public}
+}
+
+// JWK returns the key in JSON Web Key format
+func (k *verificationKey) JWK() JWK {
+	jwk := JWK{
+		KeyID:     k.id,
+		Use:       "sig",
+		Algorithm: k.method.Alg(),
+	}
+
+	switch key := k.key.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+	}
+
+	return jwk
+}
+
+// thumbprint derives the key ID from the key itself (RFC 7638), so the same
+// key always gets the same kid without extra configuration
+func (k *verificationKey) thumbprint() string {
+	jwk := k.JWK()
+
+	// Required members only, in lexicographic order
+	var members interface{}
+	if jwk.KeyType == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	}
+
+	raw, _ := json.Marshal(members)
+	sum := sha256.Sum256(raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -1,44 +1,107 @@
 package Infrastructure
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type JWTClaims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
+// JWTService signs tokens either with a shared HMAC secret or, when created
+// with NewAsymmetricJWTService, with an RSA/Ed25519 private key whose public
+// half other services can fetch from the JWKS endpoint
 type JWTService struct {
-	secretKey       string
-	tokenExpiration time.Duration
+	secretKey         string
+	signingKey        *signingKey
+	verificationKeys  map[string]*verificationKey
+	tokenExpiration   time.Duration
+	refreshExpiration time.Duration
 }
 
-func NewJWTService(secretKey string) *JWTService {
+// NewJWTService signs tokens with secretKey. Access tokens are valid for
+// tokenExpiration and refresh tokens for refreshExpiration.
+func NewJWTService(secretKey string, tokenExpiration, refreshExpiration time.Duration) *JWTService {
 	return &JWTService{
-		secretKey:       secretKey,
-		tokenExpiration: 24 * time.Hour,
+		secretKey:         secretKey,
+		tokenExpiration:   tokenExpiration,
+		refreshExpiration: refreshExpiration,
 	}
 }
 
-func (s *JWTService) GenerateToken(userID, username, role string) (string, error) {
+// NewAsymmetricJWTService signs tokens with the private key in privateKeyFile
+// and accepts tokens signed by it or by any of the keys in publicKeyFiles.
+// Keeping a retired key in publicKeyFiles lets its tokens live out their
+// lifetime after rotating to a new signing key.
+func NewAsymmetricJWTService(privateKeyFile string, publicKeyFiles []string, tokenExpiration, refreshExpiration time.Duration) (*JWTService, error) {
+	signing, err := loadSigningKey(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	verificationKeys := map[string]*verificationKey{
+		signing.id: signing.verificationKey(),
+	}
+	for _, path := range publicKeyFiles {
+		key, err := loadVerificationKey(path)
+		if err != nil {
+			return nil, err
+		}
+		verificationKeys[key.id] = key
+	}
+
+	service := NewJWTService("", tokenExpiration, refreshExpiration)
+	service.signingKey = signing
+	service.verificationKeys = verificationKeys
+	return service, nil
+}
+
+func (s *JWTService) TokenExpiration() time.Duration {
+	return s.tokenExpiration
+}
+
+func (s *JWTService) RefreshExpiration() time.Duration {
+	return s.refreshExpiration
+}
+
+// GenerateToken issues a short-lived access token. Every token gets a unique
+// ID (jti) so it can be revoked, and carries the session it belongs to.
+func (s *JWTService) GenerateToken(userID, username, role, sessionID string) (string, error) {
+	now := time.Now()
+
 	// Create claims with user information
 	claims := JWTClaims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.tokenExpiration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        primitive.NewObjectID().Hex(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.tokenExpiration)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
+	if s.signingKey != nil {
+		token := jwt.NewWithClaims(s.signingKey.method, claims)
+		token.Header["kid"] = s.signingKey.id
+		return token.SignedString(s.signingKey.key)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	tokenString, err := token.SignedString([]byte(s.secretKey))
@@ -50,13 +113,7 @@ func (s *JWTService) GenerateToken(userID, username, role string) (string, error
 }
 
 func (s *JWTService) ValidateToken(tokenString string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing algorithm
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.secretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, s.keyFunc)
 
 	if err != nil {
 		return nil, err
@@ -68,3 +125,63 @@ func (s *JWTService) ValidateToken(tokenString string) (*JWTClaims, error) {
 
 	return nil, errors.New("invalid token")
 }
+
+func (s *JWTService) keyFunc(token *jwt.Token) (interface{}, error) {
+	if s.signingKey == nil {
+		// Validate the signing algorithm
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.secretKey), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.verificationKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID: %q", kid)
+	}
+
+	// The algorithm must be the one the key is for, never the token's choice
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.key, nil
+}
+
+// JWKS returns the public keys tokens can be verified with. It is empty
+// when tokens are signed with a shared secret.
+func (s *JWTService) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if s.signingKey == nil {
+		return set
+	}
+
+	for _, key := range s.verificationKeys {
+		set.Keys = append(set.Keys, key.JWK())
+	}
+
+	// Current signing key first, then stable order
+	sort.Slice(set.Keys, func(i, j int) bool {
+		if (set.Keys[i].KeyID == s.signingKey.id) != (set.Keys[j].KeyID == s.signingKey.id) {
+			return set.Keys[i].KeyID == s.signingKey.id
+		}
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+
+	return set
+}
+
+// GenerateRefreshToken returns a random opaque refresh token
+func (s *JWTService) GenerateRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashRefreshToken returns the value refresh tokens are stored and looked up by
+func (s *JWTService) HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -4,13 +4,22 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// MinHashCost and MaxHashCost bound the bcrypt cost a PasswordService
+// accepts
+const (
+	MinHashCost = bcrypt.MinCost
+	MaxHashCost = bcrypt.MaxCost
+)
+
 type PasswordService struct {
 	hashCost int
 }
 
-func NewPasswordService() *PasswordService {
+// NewPasswordService hashes passwords with the given bcrypt cost. Each step
+// up doubles the time a hash takes; existing hashes keep their own cost.
+func NewPasswordService(hashCost int) *PasswordService {
 	return &PasswordService{
-		hashCost: bcrypt.DefaultCost,
+		hashCost: hashCost,
 	}
 }
 
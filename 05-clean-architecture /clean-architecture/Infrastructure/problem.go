@@ -0,0 +1,122 @@
+package Infrastructure
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/auth/Domain"
+)
+
+// ProblemContentType is the media type of error responses (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// StatusClientClosedRequest is the status logged for requests the client
+// gave up on before they were answered, as nginx does
+const StatusClientClosedRequest = 499
+
+// errorStatuses maps error codes to HTTP statuses; codes missing here are
+// server errors
+var errorStatuses = map[Domain.ErrorCode]int{
+	Domain.CodeInvalidRequest:     http.StatusBadRequest,
+	Domain.CodeInvalidInput:       http.StatusBadRequest,
+	Domain.CodeInvalidID:          http.StatusBadRequest,
+	Domain.CodeInvalidCursor:      http.StatusBadRequest,
+	Domain.CodeInvalidPatch:       http.StatusBadRequest,
+	Domain.CodeDependencyCycle:    http.StatusBadRequest,
+	Domain.CodeRelatedNotFound:    http.StatusBadRequest,
+	Domain.CodeUnauthorized:       http.StatusUnauthorized,
+	Domain.CodeInvalidCredentials: http.StatusUnauthorized,
+	Domain.CodeInvalidToken:       http.StatusUnauthorized,
+	Domain.CodeTokenReused:        http.StatusUnauthorized,
+	Domain.CodeForbidden:          http.StatusForbidden,
+	Domain.CodeAccountDisabled:    http.StatusForbidden,
+	Domain.CodeNotFound:           http.StatusNotFound,
+	Domain.CodeBootstrapDisabled:  http.StatusNotFound,
+	Domain.CodeMethodNotAllowed:   http.StatusMethodNotAllowed,
+	Domain.CodeUsernameTaken:      http.StatusConflict,
+	Domain.CodeAdminExists:        http.StatusConflict,
+	Domain.CodeUserInUse:          http.StatusConflict,
+	Domain.CodeTaskBlocked:        http.StatusConflict,
+	Domain.CodeOccurrenceExists:   http.StatusConflict,
+	Domain.CodePatchConflict:      http.StatusConflict,
+	Domain.CodeTooManyWebhooks:    http.StatusConflict,
+	Domain.CodeRevisionMismatch:   http.StatusPreconditionFailed,
+	Domain.CodePayloadTooLarge:    http.StatusRequestEntityTooLarge,
+	Domain.CodeUnsupportedFormat:  http.StatusUnsupportedMediaType,
+	Domain.CodeBatchAborted:       http.StatusFailedDependency,
+	Domain.CodeNoTransactions:     http.StatusNotImplemented,
+	Domain.CodeCanceled:           StatusClientClosedRequest,
+	Domain.CodeTimeout:            http.StatusGatewayTimeout,
+}
+
+// Problem is an RFC 7807 problem details object. Code is the stable
+// Domain.ErrorCode clients switch on, and Errors lists the invalid fields.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail"`
+	Instance string              `json:"instance,omitempty"`
+	Code     Domain.ErrorCode    `json:"code"`
+	Errors   []Domain.FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem describes err, using Domain.AsError to classify it
+func NewProblem(err error) Problem {
+	domainErr := Domain.AsError(err)
+	status, ok := errorStatuses[domainErr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	title := http.StatusText(status)
+	if status == StatusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+
+	return Problem{
+		Type:   "about:blank",
+		Title:  title,
+		Status: status,
+		Detail: domainErr.Message,
+		Code:   domainErr.Code,
+		Errors: domainErr.Fields,
+	}
+}
+
+// WriteProblem answers the request with err as a problem and stops it
+func WriteProblem(c *gin.Context, err error) {
+	problem := NewProblem(err)
+	problem.Instance = c.Request.URL.Path
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// ErrorHandler answers requests that recorded an error with c.Error and
+// wrote nothing else with a problem for the last error. The error itself
+// stays in c.Errors, which the request log shows.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		WriteProblem(c, c.Errors.Last().Err)
+	}
+}
+
+// Recovery answers requests whose handler panicked with an internal error
+// problem. The panic and its stack are logged, as with gin.Recovery.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		WriteProblem(c, Domain.ErrInternal)
+	})
+}
+
+// abortWithError stops the request, leaving ErrorHandler to answer it
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
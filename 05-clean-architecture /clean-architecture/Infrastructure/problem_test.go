@@ -0,0 +1,123 @@
+package Infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/auth/Domain"
+)
+
+func TestNewProblem(t *testing.T) {
+	fieldErr := &Domain.FieldError{Field: "title", Message: "is required"}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantTitle  string
+		wantCode   Domain.ErrorCode
+		wantDetail string
+		wantFields []Domain.FieldError
+	}{
+		{"invalid input", Domain.ErrInvalidInput, 400, "Bad Request", Domain.CodeInvalidInput, "Invalid input", nil},
+		{"unauthorized", Domain.ErrInvalidToken, 401, "Unauthorized", Domain.CodeInvalidToken, "Invalid or expired token", nil},
+		{"forbidden", Domain.ErrAccountDisabled, 403, "Forbidden", Domain.CodeAccountDisabled, "Account is disabled", nil},
+		{"not found with a message", Domain.ErrNotFound.WithMessage("Task not found"), 404, "Not Found", Domain.CodeNotFound, "Task not found", nil},
+		{"method not allowed", Domain.ErrMethodNotAllowed, 405, "Method Not Allowed", Domain.CodeMethodNotAllowed, "Method not allowed for this route", nil},
+		{"conflict", Domain.ErrUserInUse, 409, "Conflict", Domain.CodeUserInUse, Domain.ErrUserInUse.Message, nil},
+		{"precondition failed", Domain.ErrRevisionMismatch, 412, "Precondition Failed", Domain.CodeRevisionMismatch, "Task has been changed since it was read", nil},
+		{"batch aborted", Domain.ErrBatchAborted, 424, "Failed Dependency", Domain.CodeBatchAborted, Domain.ErrBatchAborted.Message, nil},
+		{"no transactions", Domain.ErrNoTransactions, 501, "Not Implemented", Domain.CodeNoTransactions, Domain.ErrNoTransactions.Message, nil},
+		{"wrapped domain error", fmt.Errorf("loading task: %w", Domain.ErrNotFound), 404, "Not Found", Domain.CodeNotFound,
+			"loading task: Resource not found", nil},
+		{"field error", fieldErr, 400, "Bad Request", Domain.CodeInvalidInput, "title is required", []Domain.FieldError{*fieldErr}},
+		{"several fields", Domain.InvalidFields(*fieldErr, Domain.FieldError{Field: "tags[0]", Message: "is too long"}), 400, "Bad Request",
+			Domain.CodeInvalidInput, "title is required; tags[0] is too long", []Domain.FieldError{*fieldErr, {Field: "tags[0]", Message: "is too long"}}},
+		{"deadline", fmt.Errorf("finding task: %w", context.DeadlineExceeded), 504, "Gateway Timeout", Domain.CodeTimeout, "Request timed out", nil},
+		{"canceled", fmt.Errorf("finding task: %w", context.Canceled), StatusClientClosedRequest, "Client Closed Request", Domain.CodeCanceled,
+			"Request canceled", nil},
+		{"unknown error", errors.New("connection reset"), 500, "Internal Server Error", Domain.CodeInternal, "An unexpected error occurred", nil},
+		{"unmapped code", Domain.NewError("brand_new", "Something new"), 500, "Internal Server Error", "brand_new", "Something new", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := NewProblem(tt.err)
+			if problem.Status != tt.wantStatus || problem.Title != tt.wantTitle {
+				t.Errorf("status = %d %q, want %d %q", problem.Status, problem.Title, tt.wantStatus, tt.wantTitle)
+			}
+			if problem.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", problem.Code, tt.wantCode)
+			}
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("detail = %q, want %q", problem.Detail, tt.wantDetail)
+			}
+			if !slices.Equal(problem.Errors, tt.wantFields) {
+				t.Errorf("errors = %v, want %v", problem.Errors, tt.wantFields)
+			}
+			if problem.Type != "about:blank" {
+				t.Errorf("type = %q, want about:blank", problem.Type)
+			}
+		})
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	router := gin.New()
+	router.Use(Recovery(), ErrorHandler())
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		WriteProblem(c, Domain.ErrNotFound.WithMessage("Route not found"))
+	})
+	router.NoMethod(func(c *gin.Context) {
+		WriteProblem(c, Domain.ErrMethodNotAllowed)
+	})
+	router.GET("/invalid", func(c *gin.Context) {
+		_ = c.Error(&Domain.FieldError{Field: "title", Message: "is required"})
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("something broke")
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		want   Problem
+	}{
+		{"GET", "/invalid", Problem{Type: "about:blank", Title: "Bad Request", Status: 400, Detail: "title is required",
+			Instance: "/invalid", Code: Domain.CodeInvalidInput, Errors: []Domain.FieldError{{Field: "title", Message: "is required"}}}},
+		{"GET", "/panic", Problem{Type: "about:blank", Title: "Internal Server Error", Status: 500, Detail: "An unexpected error occurred",
+			Instance: "/panic", Code: Domain.CodeInternal}},
+		{"GET", "/missing", Problem{Type: "about:blank", Title: "Not Found", Status: 404, Detail: "Route not found",
+			Instance: "/missing", Code: Domain.CodeNotFound}},
+		{"POST", "/invalid", Problem{Type: "about:blank", Title: "Method Not Allowed", Status: 405, Detail: "Method not allowed for this route",
+			Instance: "/invalid", Code: Domain.CodeMethodNotAllowed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if recorder.Code != tt.want.Status {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.want.Status)
+			}
+			if contentType := recorder.Header().Get("Content-Type"); contentType != ProblemContentType {
+				t.Errorf("Content-Type = %q, want %q", contentType, ProblemContentType)
+			}
+			var got Problem
+			if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decoding %s: %v", recorder.Body, err)
+			}
+			if got.Type != tt.want.Type || got.Title != tt.want.Title || got.Status != tt.want.Status || got.Detail != tt.want.Detail ||
+				got.Instance != tt.want.Instance || got.Code != tt.want.Code || !slices.Equal(got.Errors, tt.want.Errors) {
+				t.Errorf("body = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
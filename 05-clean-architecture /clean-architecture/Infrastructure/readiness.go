@@ -0,0 +1,22 @@
+package Infrastructure
+
+import "sync/atomic"
+
+// Readiness tells whether the server should be sent new requests. It
+// starts out not ready, and goes back to not ready while the server drains
+// on shutdown.
+type Readiness struct {
+	ready atomic.Bool
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+func (r *Readiness) SetReady(ready bool) {
+	r.ready.Store(ready)
+}
+
+func (r *Readiness) Ready() bool {
+	return r.ready.Load()
+}
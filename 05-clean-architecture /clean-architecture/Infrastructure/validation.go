@@ -0,0 +1,135 @@
+package Infrastructure
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"taskmanager/auth/Domain"
+)
+
+// RegisterValidators adds the custom binding rules the Domain DTOs use and
+// makes validation errors name fields as they are spelled in JSON. It must
+// run before any request is bound.
+func RegisterValidators() error {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("binding validator is not go-playground/validator")
+	}
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return field.Name
+		}
+		return name
+	})
+
+	rules := map[string]func(string) bool{
+		"username": Domain.ValidUsername,
+		"password": Domain.ValidPassword,
+	}
+	for tag, valid := range rules {
+		err := validate.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+			return valid(fl.Field().String())
+		})
+		if err != nil {
+			return fmt.Errorf("registering %s rule: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// ValidationError describes an error from binding a request body: broken
+// binding rules and values of the wrong type become invalid input naming
+// each field, anything else an invalid request
+func ValidationError(err error) *Domain.Error {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]Domain.FieldError, len(validationErrs))
+		for i, fieldErr := range validationErrs {
+			fields[i] = Domain.FieldError{Field: fieldPath(fieldErr), Message: fieldMessage(fieldErr)}
+		}
+		return Domain.InvalidFields(fields...)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return Domain.InvalidFields(Domain.FieldError{Field: typeErr.Field, Message: "has the wrong type"})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return Domain.ErrInvalidRequest.WithMessage("Request body is not valid JSON")
+	case errors.Is(err, io.EOF):
+		return Domain.ErrInvalidRequest.WithMessage("Request body is empty")
+	}
+	return Domain.ErrInvalidRequest.WithMessage(err.Error())
+}
+
+// fieldPath is where the field is in the request, such as "tags[2]" or
+// "operations[0].op", without the name of the DTO
+func fieldPath(fieldErr validator.FieldError) string {
+	_, path, ok := strings.Cut(fieldErr.Namespace(), ".")
+	if !ok {
+		return fieldErr.Field()
+	}
+	return path
+}
+
+// fieldMessage says what the field should have been, completing a sentence
+// that starts with the field's name
+func fieldMessage(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + fieldSize(fieldErr.Kind(), param)
+	case "max":
+		return "must be at most " + fieldSize(fieldErr.Kind(), param)
+	case "len":
+		return "must be exactly " + fieldSize(fieldErr.Kind(), param)
+	case "oneof":
+		return "must be " + orList(strings.Fields(param))
+	case "url":
+		return "must be a URL"
+	case "username":
+		return fmt.Sprintf("must be %d to %d letters, digits, dots, dashes or underscores", Domain.MinUsernameLength, Domain.MaxUsernameLength)
+	case "password":
+		return fmt.Sprintf("must be %d to %d bytes long with at least one letter and one digit", Domain.MinPasswordLength, Domain.MaxPasswordLength)
+	}
+	return "is invalid"
+}
+
+// fieldSize phrases a min, max or len parameter: a number of characters for
+// strings, of items for lists and the number itself otherwise
+func fieldSize(kind reflect.Kind, param string) string {
+	unit := ""
+	switch kind {
+	case reflect.String:
+		unit = "character"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = "item"
+	default:
+		return param
+	}
+	if param != "1" {
+		unit += "s"
+	}
+	return param + " " + unit
+}
+
+// orList joins choices as "a, b or c"
+func orList(choices []string) string {
+	if len(choices) < 2 {
+		return strings.Join(choices, "")
+	}
+	return strings.Join(choices[:len(choices)-1], ", ") + " or " + choices[len(choices)-1]
+}
@@ -0,0 +1,110 @@
+package Infrastructure
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+
+	"taskmanager/auth/Domain"
+)
+
+func TestValidationError(t *testing.T) {
+	if err := RegisterValidators(); err != nil {
+		t.Fatalf("RegisterValidators: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		// body is bound into dto
+		body string
+		dto  any
+		want *Domain.Error
+	}{
+		{"valid", `{"title":"Write report","tags":["work"]}`, &Domain.CreateTaskRequest{}, nil},
+		{"required", `{"description":"No title"}`, &Domain.CreateTaskRequest{},
+			Domain.InvalidFields(Domain.FieldError{Field: "title", Message: "is required"})},
+		{"string too long", `{"title":"` + strings.Repeat("a", Domain.MaxTitleLength+1) + `"}`, &Domain.CreateTaskRequest{},
+			Domain.InvalidFields(Domain.FieldError{Field: "title", Message: "must be at most 200 characters"})},
+		{"too many items", `{"title":"Task","tags":[` + strings.Repeat(`"a",`, Domain.MaxTagsPerTask) + `"a"]}`, &Domain.CreateTaskRequest{},
+			Domain.InvalidFields(Domain.FieldError{Field: "tags", Message: "must be at most 20 items"})},
+		{"item too short", `{"title":"Task","tags":["work",""]}`, &Domain.CreateTaskRequest{},
+			Domain.InvalidFields(Domain.FieldError{Field: "tags[1]", Message: "must be at least 1 character"})},
+		{"one of", `{"title":"Task","priority":"someday"}`, &Domain.CreateTaskRequest{},
+			Domain.InvalidFields(Domain.FieldError{Field: "priority", Message: "must be low, medium, high or urgent"})},
+		{"every field", `{"username":"x","password":"short"}`, &Domain.RegisterRequest{},
+			Domain.InvalidFields(
+				Domain.FieldError{Field: "username", Message: "must be 3 to 32 letters, digits, dots, dashes or underscores"},
+				Domain.FieldError{Field: "password", Message: "must be 8 to 72 bytes long with at least one letter and one digit"},
+			)},
+		{"wrong type", `{"title":42}`, &Domain.CreateTaskRequest{},
+			Domain.InvalidFields(Domain.FieldError{Field: "title", Message: "has the wrong type"})},
+		{"not JSON", `{"title":`, &Domain.CreateTaskRequest{}, Domain.ErrInvalidRequest.WithMessage("Request body is not valid JSON")},
+		{"syntax error", `{"title" "Task"}`, &Domain.CreateTaskRequest{}, Domain.ErrInvalidRequest.WithMessage("Request body is not valid JSON")},
+		{"empty", ``, &Domain.CreateTaskRequest{}, Domain.ErrInvalidRequest.WithMessage("Request body is empty")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := binding.JSON.BindBody([]byte(tt.body), tt.dto)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("BindBody = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("BindBody succeeded, want %v", tt.want)
+			}
+
+			got := ValidationError(err)
+			if got.Code != tt.want.Code || got.Message != tt.want.Message || !slices.Equal(got.Fields, tt.want.Fields) {
+				t.Errorf("ValidationError = %q %q %v, want %q %q %v", got.Code, got.Message, got.Fields, tt.want.Code, tt.want.Message, tt.want.Fields)
+			}
+		})
+	}
+
+	other := errors.New("http: request body too large")
+	if got := ValidationError(other); got.Code != Domain.CodeInvalidRequest || got.Message != other.Error() {
+		t.Errorf("ValidationError(%v) = %q %q, want invalid_request with its message", other, got.Code, got.Message)
+	}
+}
+
+func TestFieldMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		dto  any
+		want string
+	}{
+		{"characters", &struct {
+			Name string `binding:"max=3"`
+		}{Name: "abcd"}, "Name must be at most 3 characters"},
+		{"one character", &struct {
+			Name string `binding:"min=1"`
+		}{}, "Name must be at least 1 character"},
+		{"items", &struct {
+			List []int `binding:"len=2"`
+		}{List: []int{1}}, "List must be exactly 2 items"},
+		{"number", &struct {
+			Count int `binding:"min=5"`
+		}{Count: 1}, "Count must be at least 5"},
+		{"url", &struct {
+			Link string `binding:"url"`
+		}{Link: "not a url"}, "Link must be a URL"},
+		{"other rule", &struct {
+			Mail string `binding:"email"`
+		}{Mail: "nobody"}, "Mail is invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := binding.Validator.ValidateStruct(tt.dto)
+			if err == nil {
+				t.Fatal("ValidateStruct succeeded, want an error")
+			}
+			if got := ValidationError(err).Message; got != tt.want {
+				t.Errorf("message = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
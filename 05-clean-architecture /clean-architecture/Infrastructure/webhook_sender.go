@@ -0,0 +1,85 @@
+package Infrastructure
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"taskmanager/auth/Domain"
+)
+
+// HTTPWebhookSender is a Domain.WebhookSender that posts deliveries over
+// HTTP. Redirects are not followed, so a receiver has to answer itself, and
+// unless private addresses are allowed, connections are only made to
+// public addresses (see Domain.IsPublicAddress). That is checked on the
+// address actually dialed, so a host that resolved to a public address
+// when the webhook was created cannot be pointed at a private one later.
+type HTTPWebhookSender struct {
+	client *http.Client
+}
+
+// NewHTTPWebhookSender gives receivers timeout to answer a delivery
+func NewHTTPWebhookSender(timeout time.Duration, allowPrivateAddresses bool) *HTTPWebhookSender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateAddresses {
+		dialer.Control = checkPublicAddress
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be the address dialed, not the receiver
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &HTTPWebhookSender{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// checkPublicAddress refuses to connect to an address that is not public
+func checkPublicAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !Domain.IsPublicAddress(addr) {
+		return fmt.Errorf("webhook address %s is not public", addr)
+	}
+	return nil
+}
+
+func (s *HTTPWebhookSender) Send(ctx context.Context, request Domain.WebhookRequest) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, request.URL, bytes.NewReader(request.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "taskmanager-webhooks")
+	for name, value := range request.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Drain a little of the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,68 @@
+package Repositories
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+
+	"taskmanager/auth/Domain"
+)
+
+// newAuditPage trims the extra look-ahead entry off a page and derives the
+// cursor for the next one
+func newAuditPage(entries []Domain.AuditEntry, total int64, opts Domain.AuditListOptions) *Domain.AuditPage {
+	page := &Domain.AuditPage{Entries: entries, Total: total}
+
+	if len(entries) > opts.Limit {
+		page.Entries = entries[:opts.Limit]
+		page.NextCursor = page.Entries[opts.Limit-1].ID.Hex()
+	}
+
+	return page
+}
+
+// auditFilterQuery translates an AuditFilter into a Mongo query
+func auditFilterQuery(filter Domain.AuditFilter) bson.M {
+	query := bson.M{}
+
+	if filter.ActorID != nil {
+		query["actor_id"] = *filter.ActorID
+	}
+
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+
+	if filter.EntityType != "" {
+		query["entity_type"] = filter.EntityType
+	}
+
+	if filter.EntityID != nil {
+		query["entity_id"] = *filter.EntityID
+	}
+
+	if createdAt := timeRange(filter.After, filter.Before); createdAt != nil {
+		query["created_at"] = createdAt
+	}
+
+	return query
+}
+
+// matchesAuditFilter is the in-memory equivalent of auditFilterQuery
+func matchesAuditFilter(entry Domain.AuditEntry, filter Domain.AuditFilter) bool {
+	if filter.ActorID != nil && (entry.ActorID == nil || *entry.ActorID != *filter.ActorID) {
+		return false
+	}
+
+	if filter.Action != "" && entry.Action != filter.Action {
+		return false
+	}
+
+	if filter.EntityType != "" && entry.EntityType != filter.EntityType {
+		return false
+	}
+
+	if filter.EntityID != nil && (entry.EntityID == nil || *entry.EntityID != *filter.EntityID) {
+		return false
+	}
+
+	return inTimeRange(entry.CreatedAt, filter.After, filter.Before)
+}
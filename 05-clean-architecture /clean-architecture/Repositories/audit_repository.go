@@ -0,0 +1,90 @@
+package Repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"taskmanager/auth/Domain"
+)
+
+type AuditRepository struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+func NewAuditRepository(collection *mongo.Collection, timeout time.Duration) *AuditRepository {
+	// Decode nested change values as maps rather than ordered documents, so
+	// they render as plain JSON objects
+	collection = collection.Database().Collection(collection.Name(), options.Collection().
+		SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true}))
+
+	return &AuditRepository{
+		collection: collection,
+		timeout:    timeout,
+	}
+}
+
+func (r *AuditRepository) Initialize(ctx context.Context) error {
+	// Create indexes backing the filtered, newest first listings
+	indexModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "entity_type", Value: 1}, {Key: "entity_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexModels)
+	return err
+}
+
+func (r *AuditRepository) Append(ctx context.Context, entry *Domain.AuditEntry) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, entry)
+	return err
+}
+
+func (r *AuditRepository) List(ctx context.Context, opts Domain.AuditListOptions) (*Domain.AuditPage, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	opts = opts.WithDefaults()
+
+	filter := auditFilterQuery(opts.Filter)
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Cursor != "" {
+		before, err := decodeIDCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$lt": before}}}}
+	}
+
+	// Fetch one extra entry to find out whether there is a next page
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(opts.Limit + 1))
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []Domain.AuditEntry{}
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return newAuditPage(entries, total, opts), nil
+}
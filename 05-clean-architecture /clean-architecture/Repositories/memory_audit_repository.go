@@ -0,0 +1,75 @@
+package Repositories
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// InMemoryAuditRepository is a thread-safe Domain.AuditRepository that keeps
+// the audit log in process memory. It is meant for development and tests.
+type InMemoryAuditRepository struct {
+	mu      sync.RWMutex
+	entries []Domain.AuditEntry // oldest first
+}
+
+func NewInMemoryAuditRepository() *InMemoryAuditRepository {
+	return &InMemoryAuditRepository{}
+}
+
+func (r *InMemoryAuditRepository) Append(ctx context.Context, entry *Domain.AuditEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+func (r *InMemoryAuditRepository) List(ctx context.Context, opts Domain.AuditListOptions) (*Domain.AuditPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	opts = opts.WithDefaults()
+
+	var before primitive.ObjectID
+	if opts.Cursor != "" {
+		var err error
+		if before, err = decodeIDCursor(opts.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var total int64
+	entries := []Domain.AuditEntry{}
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		entry := r.entries[i]
+		if !matchesAuditFilter(entry, opts.Filter) {
+			continue
+		}
+		total++
+
+		if opts.Cursor != "" && entry.ID.Hex() >= before.Hex() {
+			continue
+		}
+		// Keep one extra entry to find out whether there is a next page
+		if len(entries) <= opts.Limit {
+			entries = append(entries, entry)
+		}
+	}
+
+	return newAuditPage(entries, total, opts), nil
+}
@@ -0,0 +1,92 @@
+package Repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+func TestInMemoryRepositoriesStopWhenContextIsDone(t *testing.T) {
+	users := NewInMemoryUserRepository()
+	tokens := NewInMemoryTokenRepository()
+	audit := NewInMemoryAuditRepository()
+	revisions := NewInMemoryTaskRevisionRepository()
+	webhooks := NewInMemoryWebhookRepository()
+	deliveries := NewInMemoryWebhookDeliveryRepository()
+
+	user := &Domain.User{ID: primitive.NewObjectID(), Username: "someone", Role: Domain.RoleUser}
+	if err := users.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	id := primitive.NewObjectID()
+	calls := map[string]func() error{
+		"users.Create":          func() error { return users.Create(ctx, &Domain.User{Username: "other"}) },
+		"users.GetByID":         func() error { _, err := users.GetByID(ctx, user.ID); return err },
+		"users.GetByUsername":   func() error { _, err := users.GetByUsername(ctx, user.Username); return err },
+		"users.UpdateLastLogin": func() error { return users.UpdateLastLogin(ctx, user.ID) },
+		"users.List":            func() error { _, err := users.List(ctx, Domain.UserListOptions{}); return err },
+		"users.UpdateRole": func() error {
+			return users.UpdateRole(ctx, user.ID, Domain.RoleGrant{Role: Domain.RoleAdmin})
+		},
+		"users.CountByRole": func() error { _, err := users.CountByRole(ctx, Domain.RoleUser); return err },
+		"users.SetDisabled": func() error { return users.SetDisabled(ctx, user.ID, true) },
+		"users.Delete":      func() error { return users.Delete(ctx, user.ID) },
+		"tokens.CreateRefreshToken": func() error {
+			return tokens.CreateRefreshToken(ctx, &Domain.RefreshToken{UserID: user.ID})
+		},
+		"tokens.GetRefreshToken":      func() error { _, err := tokens.GetRefreshToken(ctx, "hash"); return err },
+		"tokens.MarkRefreshTokenUsed": func() error { return tokens.MarkRefreshTokenUsed(ctx, id) },
+		"tokens.RevokeFamily":         func() error { return tokens.RevokeFamily(ctx, "family") },
+		"tokens.RevokeTokenID":        func() error { return tokens.RevokeTokenID(ctx, "token", time.Now().Add(time.Hour)) },
+		"tokens.IsTokenIDRevoked":     func() error { _, err := tokens.IsTokenIDRevoked(ctx, "token"); return err },
+		"audit.Append":                func() error { return audit.Append(ctx, &Domain.AuditEntry{Action: Domain.AuditTaskCreated}) },
+		"audit.List":                  func() error { _, err := audit.List(ctx, Domain.AuditListOptions{}); return err },
+		"revisions.Append":            func() error { return revisions.Append(ctx, &Domain.TaskRevision{TaskID: id, Revision: 1}) },
+		"revisions.List":              func() error { _, err := revisions.List(ctx, id); return err },
+		"revisions.Get":               func() error { _, err := revisions.Get(ctx, id, 1); return err },
+		"webhooks.Create": func() error {
+			return webhooks.Create(ctx, &Domain.Webhook{UserID: user.ID, URL: "https://example.com/hook"})
+		},
+		"webhooks.GetByID":     func() error { _, err := webhooks.GetByID(ctx, id); return err },
+		"webhooks.ListByUsers": func() error { _, err := webhooks.ListByUsers(ctx, []primitive.ObjectID{user.ID}); return err },
+		"webhooks.Delete":      func() error { return webhooks.Delete(ctx, id) },
+		"deliveries.Create": func() error {
+			return deliveries.Create(ctx, &Domain.WebhookDelivery{WebhookID: id})
+		},
+		"deliveries.GetByID": func() error { _, err := deliveries.GetByID(ctx, id); return err },
+		"deliveries.List": func() error {
+			_, err := deliveries.List(ctx, Domain.WebhookDeliveryFilter{UserID: user.ID}, 10)
+			return err
+		},
+		"deliveries.ClaimDue": func() error {
+			_, err := deliveries.ClaimDue(ctx, time.Now(), Domain.WebhookDeliveryLease, 10)
+			return err
+		},
+		"deliveries.Update":          func() error { return deliveries.Update(ctx, &Domain.WebhookDelivery{ID: id}) },
+		"deliveries.DeleteByWebhook": func() error { return deliveries.DeleteByWebhook(ctx, id) },
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			if err := call(); !errors.Is(err, context.Canceled) {
+				t.Errorf("%s = %v, want context.Canceled", name, err)
+			}
+		})
+	}
+
+	stored, err := users.GetByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.Role != Domain.RoleUser || stored.Disabled {
+		t.Errorf("user changed after canceled calls: %+v", stored)
+	}
+}
@@ -0,0 +1,193 @@
+package Repositories
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// titleSearchWeight ranks a word in a title as highly as this many in a
+// description, like the weights of the Mongo text index
+const titleSearchWeight = 3
+
+// searchPositions are the word positions of a word in each field of a task
+type searchPositions struct {
+	title       []int
+	description []int
+}
+
+// searchIndex is an inverted index of task titles and descriptions for the
+// in-memory repository. It is not safe for concurrent use; the repository's
+// lock guards it.
+type searchIndex struct {
+	// postings maps a word to the tasks containing it
+	postings map[string]map[primitive.ObjectID]*searchPositions
+	// words lists each task's indexed words, to remove them when it changes
+	words map[primitive.ObjectID][]string
+}
+
+func newSearchIndex(tasks map[primitive.ObjectID]Domain.Task) *searchIndex {
+	index := &searchIndex{
+		postings: make(map[string]map[primitive.ObjectID]*searchPositions),
+		words:    make(map[primitive.ObjectID][]string),
+	}
+	for _, task := range tasks {
+		index.set(task)
+	}
+	return index
+}
+
+// set indexes a new task or reindexes a changed one
+func (idx *searchIndex) set(task Domain.Task) {
+	idx.remove(task.ID)
+
+	fields := []struct {
+		text      string
+		positions func(p *searchPositions) *[]int
+	}{
+		{task.Title, func(p *searchPositions) *[]int { return &p.title }},
+		{task.Description, func(p *searchPositions) *[]int { return &p.description }},
+	}
+	for _, field := range fields {
+		for i, token := range Domain.SearchTokens(field.text) {
+			tasks := idx.postings[token.Word]
+			if tasks == nil {
+				tasks = make(map[primitive.ObjectID]*searchPositions)
+				idx.postings[token.Word] = tasks
+			}
+			positions := tasks[task.ID]
+			if positions == nil {
+				positions = &searchPositions{}
+				tasks[task.ID] = positions
+				idx.words[task.ID] = append(idx.words[task.ID], token.Word)
+			}
+			p := field.positions(positions)
+			*p = append(*p, i)
+		}
+	}
+}
+
+func (idx *searchIndex) remove(id primitive.ObjectID) {
+	for _, word := range idx.words[id] {
+		delete(idx.postings[word], id)
+		if len(idx.postings[word]) == 0 {
+			delete(idx.postings, word)
+		}
+	}
+	delete(idx.words, id)
+}
+
+// match returns the IDs of the tasks matching query with their tf-idf
+// scores
+func (idx *searchIndex) match(query Domain.SearchQuery) map[primitive.ObjectID]float64 {
+	var candidates map[primitive.ObjectID]bool
+	required := len(query.Phrases) > 0 || len(query.Prefixes) > 0
+
+	// Narrow down to the tasks having every phrase and prefix...
+	for _, phrase := range query.Phrases {
+		candidates = intersect(candidates, idx.phraseMatches(phrase))
+	}
+	prefixWords := make([][]string, len(query.Prefixes))
+	for i, prefix := range query.Prefixes {
+		prefixWords[i] = idx.wordsWithPrefix(prefix)
+		matches := make(map[primitive.ObjectID]bool)
+		for _, word := range prefixWords[i] {
+			for id := range idx.postings[word] {
+				matches[id] = true
+			}
+		}
+		candidates = intersect(candidates, matches)
+	}
+
+	// ...or to the tasks having any of the words
+	if !required {
+		candidates = make(map[primitive.ObjectID]bool)
+		for _, word := range query.Words {
+			for id := range idx.postings[word] {
+				candidates[id] = true
+			}
+		}
+	}
+
+	// Every word of the query counts once towards the score
+	scored := slices.Clone(query.Words)
+	for _, phrase := range query.Phrases {
+		scored = append(scored, phrase...)
+	}
+	for _, words := range prefixWords {
+		scored = append(scored, words...)
+	}
+	slices.Sort(scored)
+	scored = slices.Compact(scored)
+
+	scores := make(map[primitive.ObjectID]float64, len(candidates))
+	for id := range candidates {
+		scores[id] = 0
+	}
+	for _, word := range scored {
+		tasks := idx.postings[word]
+		idf := math.Log(1 + float64(len(idx.words))/float64(len(tasks)))
+		for id, positions := range tasks {
+			if _, ok := scores[id]; ok {
+				tf := titleSearchWeight*len(positions.title) + len(positions.description)
+				scores[id] += float64(tf) * idf
+			}
+		}
+	}
+	return scores
+}
+
+// phraseMatches returns the tasks with the words of phrase one after
+// another in the same field
+func (idx *searchIndex) phraseMatches(phrase []string) map[primitive.ObjectID]bool {
+	matches := make(map[primitive.ObjectID]bool)
+	for id, first := range idx.postings[phrase[0]] {
+		if idx.hasPhrase(id, phrase, first.title, func(p *searchPositions) []int { return p.title }) ||
+			idx.hasPhrase(id, phrase, first.description, func(p *searchPositions) []int { return p.description }) {
+			matches[id] = true
+		}
+	}
+	return matches
+}
+
+func (idx *searchIndex) hasPhrase(id primitive.ObjectID, phrase []string, starts []int, field func(p *searchPositions) []int) bool {
+next:
+	for _, start := range starts {
+		for offset, word := range phrase[1:] {
+			positions := idx.postings[word][id]
+			if positions == nil || !slices.Contains(field(positions), start+offset+1) {
+				continue next
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (idx *searchIndex) wordsWithPrefix(prefix string) []string {
+	var words []string
+	for word := range idx.postings {
+		if strings.HasPrefix(word, prefix) {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// intersect narrows a candidate set to matches; a nil set has no
+// constraint yet
+func intersect(candidates, matches map[primitive.ObjectID]bool) map[primitive.ObjectID]bool {
+	if candidates == nil {
+		return matches
+	}
+	for id := range candidates {
+		if !matches[id] {
+			delete(candidates, id)
+		}
+	}
+	return candidates
+}
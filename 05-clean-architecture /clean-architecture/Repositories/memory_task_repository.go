@@ -0,0 +1,387 @@
+package Repositories
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// InMemoryTaskRepository is a thread-safe Domain.TaskRepository that keeps
+// tasks in process memory. It is meant for development and tests. Like the
+// MongoDB repository, it returns the context's error once ctx is done.
+type InMemoryTaskRepository struct {
+	mu    sync.RWMutex
+	tasks map[primitive.ObjectID]Domain.Task
+	index *searchIndex
+}
+
+func NewInMemoryTaskRepository() *InMemoryTaskRepository {
+	tasks := make(map[primitive.ObjectID]Domain.Task)
+	return &InMemoryTaskRepository{
+		tasks: tasks,
+		index: newSearchIndex(tasks),
+	}
+}
+
+func (r *InMemoryTaskRepository) GetByID(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope) (*Domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, ok := r.tasks[id]
+	if !ok || !scope.Allows(task) {
+		return nil, Domain.ErrNotFound
+	}
+
+	return &task, nil
+}
+
+func (r *InMemoryTaskRepository) GetAll(ctx context.Context, scope Domain.TaskScope, opts Domain.TaskListOptions) (*Domain.TaskPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	opts = opts.WithDefaults()
+
+	var cursor *taskCursor
+	if opts.Cursor != "" {
+		var err error
+		if cursor, err = decodeTaskCursor(opts.Cursor, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matching []Domain.Task
+	for _, task := range r.tasks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if scope.Allows(task) && matchesTaskFilter(task, opts.Filter) {
+			matching = append(matching, task)
+		}
+	}
+
+	sort.Slice(matching, func(i, j int) bool {
+		cmp := compareTasks(matching[i], matching[j], opts.SortBy)
+		if opts.SortDesc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	tasks := []Domain.Task{}
+	for _, task := range matching {
+		if cursor != nil && !isAfterCursor(task, cursor) {
+			continue
+		}
+		tasks = append(tasks, task)
+		// Keep one extra task to find out whether there is a next page
+		if len(tasks) > opts.Limit {
+			break
+		}
+	}
+
+	return newTaskPage(tasks, int64(len(matching)), opts), nil
+}
+
+func (r *InMemoryTaskRepository) Search(ctx context.Context, scope Domain.TaskScope, query Domain.SearchQuery, limit int) ([]Domain.TaskSearchHit, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hits := []Domain.TaskSearchHit{}
+	for id, score := range r.index.match(query) {
+		if task := r.tasks[id]; scope.Allows(task) {
+			hits = append(hits, Domain.TaskSearchHit{Task: task, Score: score})
+		}
+	}
+
+	// Best first, then newest first like prefix-only Mongo searches
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return compareTasks(hits[i].Task, hits[j].Task, Domain.SortByUpdatedAt) > 0
+	})
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+func (r *InMemoryTaskRepository) Create(ctx context.Context, task *Domain.Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if task.ID.IsZero() {
+		task.ID = primitive.NewObjectID()
+	}
+	return r.store(*task)
+}
+
+func (r *InMemoryTaskRepository) Update(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope, updates map[string]interface{}) (*Domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, ok := r.tasks[id]
+	if !ok || !scope.Allows(task) {
+		return nil, Domain.ErrNotFound
+	}
+	if scope.Revision != 0 && task.Revision != scope.Revision {
+		return nil, Domain.ErrRevisionMismatch
+	}
+
+	// Set updatedAt time
+	updates["updated_at"] = time.Now()
+
+	updatedTask, err := applyUpdates(task, updates)
+	if err != nil {
+		return nil, err
+	}
+	updatedTask.Revision++
+	r.tasks[id] = updatedTask
+	r.index.set(updatedTask)
+
+	return &updatedTask, nil
+}
+
+func (r *InMemoryTaskRepository) Delete(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope) (*Domain.Task, error) {
+	return r.modify(ctx, id, scope, func(task *Domain.Task) {
+		now := time.Now()
+		task.DeletedAt = &now
+	})
+}
+
+func (r *InMemoryTaskRepository) Undelete(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope) (*Domain.Task, error) {
+	scope.Deleted = true
+	return r.modify(ctx, id, scope, func(task *Domain.Task) {
+		task.DeletedAt = nil
+	})
+}
+
+// applyUpdates applies a $set style update map to a copy of the document by
+// round-tripping it through BSON, so keys match the Mongo field names.
+func applyUpdates[T any](doc T, updates map[string]interface{}) (T, error) {
+	var result T
+
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return result, err
+	}
+
+	var fields bson.M
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return result, err
+	}
+	for key, value := range updates {
+		fields[key] = value
+	}
+
+	raw, err = bson.Marshal(fields)
+	if err != nil {
+		return result, err
+	}
+	if err := bson.Unmarshal(raw, &result); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *InMemoryTaskRepository) SetCollaborator(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope, collaborator Domain.Collaborator) (*Domain.Task, error) {
+	return r.modify(ctx, id, scope, func(task *Domain.Task) {
+		collaborators := []Domain.Collaborator{}
+		for _, c := range task.Collaborators {
+			if c.UserID == collaborator.UserID {
+				// Keep when it was first shared, like the Mongo update does
+				collaborator.SharedAt = c.SharedAt
+				continue
+			}
+			collaborators = append(collaborators, c)
+		}
+		task.Collaborators = append(collaborators, collaborator)
+	})
+}
+
+func (r *InMemoryTaskRepository) RemoveCollaborator(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope, userID primitive.ObjectID) (*Domain.Task, error) {
+	return r.modify(ctx, id, scope, func(task *Domain.Task) {
+		collaborators := []Domain.Collaborator{}
+		for _, c := range task.Collaborators {
+			if c.UserID != userID {
+				collaborators = append(collaborators, c)
+			}
+		}
+		task.Collaborators = collaborators
+	})
+}
+
+func (r *InMemoryTaskRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID, scope Domain.TaskScope) ([]Domain.Task, error) {
+	return r.findInScope(ctx, scope, func(task Domain.Task) bool {
+		return slices.Contains(ids, task.ID)
+	})
+}
+
+func (r *InMemoryTaskRepository) GetSubtasks(ctx context.Context, parentIDs []primitive.ObjectID, scope Domain.TaskScope) ([]Domain.Task, error) {
+	return r.findInScope(ctx, scope, func(task Domain.Task) bool {
+		return task.ParentID != nil && slices.Contains(parentIDs, *task.ParentID)
+	})
+}
+
+func (r *InMemoryTaskRepository) GetDependents(ctx context.Context, ids []primitive.ObjectID, scope Domain.TaskScope) ([]Domain.Task, error) {
+	return r.findInScope(ctx, scope, func(task Domain.Task) bool {
+		return slices.ContainsFunc(task.BlockedBy, func(id primitive.ObjectID) bool {
+			return slices.Contains(ids, id)
+		})
+	})
+}
+
+func (r *InMemoryTaskRepository) CreateOccurrence(ctx context.Context, task *Domain.Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.tasks {
+		if existing.SeriesID != nil && task.SeriesID != nil &&
+			*existing.SeriesID == *task.SeriesID && existing.Occurrence == task.Occurrence {
+			return Domain.ErrOccurrenceExists
+		}
+	}
+
+	if task.ID.IsZero() {
+		task.ID = primitive.NewObjectID()
+	}
+	return r.store(*task)
+}
+
+func (r *InMemoryTaskRepository) GetRecurrenceDue(ctx context.Context, now time.Time, limit int) ([]Domain.Task, error) {
+	tasks, err := r.findInScope(ctx, Domain.TaskScope{AllTasks: true}, func(task Domain.Task) bool {
+		return task.Recurrence != "" && !task.Recurred &&
+			(task.Completed || task.DueAt != nil && !task.DueAt.After(now))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+	return tasks, nil
+}
+
+func (r *InMemoryTaskRepository) MarkRecurred(ctx context.Context, id primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, ok := r.tasks[id]
+	if !ok {
+		return Domain.ErrNotFound
+	}
+
+	task.Recurred = true
+	r.tasks[id] = task
+	return nil
+}
+
+// findInScope returns the matching tasks in scope, oldest first like the
+// Mongo repository
+func (r *InMemoryTaskRepository) findInScope(ctx context.Context, scope Domain.TaskScope, match func(task Domain.Task) bool) ([]Domain.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tasks := []Domain.Task{}
+	for _, task := range r.tasks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if scope.Allows(task) && match(task) {
+			tasks = append(tasks, task)
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return compareTasks(tasks[i], tasks[j], Domain.SortByCreatedAt) < 0
+	})
+	return tasks, nil
+}
+
+// get returns the task with the ID, in any scope
+func (r *InMemoryTaskRepository) get(id primitive.ObjectID) (Domain.Task, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, ok := r.tasks[id]
+	return task, ok
+}
+
+// store saves the task the way Mongo would return it, with timestamps
+// rounded to milliseconds
+func (r *InMemoryTaskRepository) store(task Domain.Task) error {
+	stored, err := applyUpdates(task, nil)
+	if err != nil {
+		return err
+	}
+	r.tasks[task.ID] = stored
+	r.index.set(stored)
+	return nil
+}
+
+// modify applies a change to a copy of a task in scope, bumps its revision
+// and stores it
+func (r *InMemoryTaskRepository) modify(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope, apply func(task *Domain.Task)) (*Domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, ok := r.tasks[id]
+	if !ok || !scope.Allows(task) {
+		return nil, Domain.ErrNotFound
+	}
+	if scope.Revision != 0 && task.Revision != scope.Revision {
+		return nil, Domain.ErrRevisionMismatch
+	}
+
+	apply(&task)
+	task.UpdatedAt = time.Now()
+	task.Revision++
+	if err := r.store(task); err != nil {
+		return nil, err
+	}
+
+	task = r.tasks[id]
+	return &task, nil
+}
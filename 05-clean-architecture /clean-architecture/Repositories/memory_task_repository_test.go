@@ -0,0 +1,67 @@
+package Repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+func TestInMemoryTaskRepositoryStopsWhenContextIsDone(t *testing.T) {
+	repo := NewInMemoryTaskRepository()
+	task := &Domain.Task{ID: primitive.NewObjectID(), Title: "Task", Recurrence: "FREQ=DAILY", Completed: true}
+	if err := repo.Create(context.Background(), task); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	all := Domain.TaskScope{AllTasks: true}
+	ids := []primitive.ObjectID{task.ID}
+	calls := map[string]func() error{
+		"GetByID": func() error { _, err := repo.GetByID(ctx, task.ID, all); return err },
+		"GetAll":  func() error { _, err := repo.GetAll(ctx, all, Domain.TaskListOptions{}); return err },
+		"Search": func() error {
+			_, err := repo.Search(ctx, all, Domain.SearchQuery{Words: []string{"task"}}, 10)
+			return err
+		},
+		"Create": func() error { return repo.Create(ctx, &Domain.Task{Title: "New"}) },
+		"Update": func() error {
+			_, err := repo.Update(ctx, task.ID, all, map[string]interface{}{"title": "Changed"})
+			return err
+		},
+		"Delete":          func() error { _, err := repo.Delete(ctx, task.ID, all); return err },
+		"Undelete":        func() error { _, err := repo.Undelete(ctx, task.ID, all); return err },
+		"SetCollaborator": func() error { _, err := repo.SetCollaborator(ctx, task.ID, all, Domain.Collaborator{}); return err },
+		"RemoveCollaborator": func() error {
+			_, err := repo.RemoveCollaborator(ctx, task.ID, all, primitive.NewObjectID())
+			return err
+		},
+		"GetByIDs":         func() error { _, err := repo.GetByIDs(ctx, ids, all); return err },
+		"GetSubtasks":      func() error { _, err := repo.GetSubtasks(ctx, ids, all); return err },
+		"GetDependents":    func() error { _, err := repo.GetDependents(ctx, ids, all); return err },
+		"CreateOccurrence": func() error { return repo.CreateOccurrence(ctx, &Domain.Task{Title: "Next"}) },
+		"GetRecurrenceDue": func() error { _, err := repo.GetRecurrenceDue(ctx, time.Now(), 10); return err },
+		"MarkRecurred":     func() error { return repo.MarkRecurred(ctx, task.ID) },
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			if err := call(); !errors.Is(err, context.Canceled) {
+				t.Errorf("%s = %v, want context.Canceled", name, err)
+			}
+		})
+	}
+
+	stored, err := repo.GetByID(context.Background(), task.ID, all)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.Title != "Task" || stored.Revision != task.Revision || stored.Recurred || stored.DeletedAt != nil {
+		t.Errorf("task changed after canceled calls: %+v", stored)
+	}
+}
@@ -0,0 +1,71 @@
+package Repositories
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// InMemoryTaskRevisionRepository is a thread-safe
+// Domain.TaskRevisionRepository that keeps task history in process memory.
+// It is meant for development and tests.
+type InMemoryTaskRevisionRepository struct {
+	mu        sync.RWMutex
+	revisions map[primitive.ObjectID][]Domain.TaskRevision // oldest first
+}
+
+func NewInMemoryTaskRevisionRepository() *InMemoryTaskRevisionRepository {
+	return &InMemoryTaskRevisionRepository{
+		revisions: make(map[primitive.ObjectID][]Domain.TaskRevision),
+	}
+}
+
+func (r *InMemoryTaskRevisionRepository) Append(ctx context.Context, revision *Domain.TaskRevision) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if revision.ID.IsZero() {
+		revision.ID = primitive.NewObjectID()
+	}
+	r.revisions[revision.TaskID] = append(r.revisions[revision.TaskID], *revision)
+	return nil
+}
+
+func (r *InMemoryTaskRevisionRepository) List(ctx context.Context, taskID primitive.ObjectID) ([]Domain.TaskRevision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history := r.revisions[taskID]
+	revisions := make([]Domain.TaskRevision, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		revisions = append(revisions, history[i])
+	}
+	return revisions, nil
+}
+
+func (r *InMemoryTaskRevisionRepository) Get(ctx context.Context, taskID primitive.ObjectID, revision int) (*Domain.TaskRevision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, rev := range r.revisions[taskID] {
+		if rev.Revision == revision {
+			return &rev, nil
+		}
+	}
+	return nil, Domain.ErrNotFound
+}
@@ -0,0 +1,237 @@
+package Repositories
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// InMemoryTaskTransactor is a Domain.TaskTransactor for the in-memory
+// repositories. fn writes through repositories that keep an undo log of
+// what it changed, and a rollback takes back only those changes, so writes
+// other requests make meanwhile are kept. Transactions run one at a time.
+type InMemoryTaskTransactor struct {
+	mu        sync.Mutex
+	tasks     *InMemoryTaskRepository
+	revisions *InMemoryTaskRevisionRepository
+	audit     *InMemoryAuditRepository
+}
+
+func NewInMemoryTaskTransactor(tasks *InMemoryTaskRepository, revisions *InMemoryTaskRevisionRepository, audit *InMemoryAuditRepository) *InMemoryTaskTransactor {
+	return &InMemoryTaskTransactor{
+		tasks:     tasks,
+		revisions: revisions,
+		audit:     audit,
+	}
+}
+
+func (t *InMemoryTaskTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repos Domain.TaskRepositories) error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	undo := &undoLog{tasks: make(map[primitive.ObjectID]*undoTask)}
+	err := fn(ctx, Domain.TaskRepositories{
+		Tasks:     &undoTaskRepository{InMemoryTaskRepository: t.tasks, undo: undo},
+		Revisions: &undoTaskRevisionRepository{InMemoryTaskRevisionRepository: t.revisions, undo: undo},
+		Audit:     &undoAuditRepository{InMemoryAuditRepository: t.audit, undo: undo},
+	})
+	if err != nil {
+		undo.rollback(t.tasks, t.revisions, t.audit)
+	}
+	return err
+}
+
+// undoLog is what a transaction wrote, to take it back on rollback
+type undoLog struct {
+	mu        sync.Mutex
+	tasks     map[primitive.ObjectID]*undoTask
+	revisions []primitive.ObjectID
+	entries   []primitive.ObjectID
+}
+
+// undoTask is a task before the transaction first wrote it (nil if the
+// transaction created it) and the revision the transaction left it at
+type undoTask struct {
+	before   *Domain.Task
+	revision int
+}
+
+// saveTask remembers the task as it was before the transaction's first
+// write to it
+func (u *undoLog) saveTask(tasks *InMemoryTaskRepository, id primitive.ObjectID) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if _, ok := u.tasks[id]; ok {
+		return
+	}
+	entry := &undoTask{}
+	if task, ok := tasks.get(id); ok {
+		entry.before = &task
+		entry.revision = task.Revision
+	}
+	u.tasks[id] = entry
+}
+
+// wroteTask records the revision the transaction left a task at
+func (u *undoLog) wroteTask(task *Domain.Task) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if entry, ok := u.tasks[task.ID]; ok {
+		entry.revision = task.Revision
+	} else {
+		u.tasks[task.ID] = &undoTask{revision: task.Revision}
+	}
+}
+
+// rollback restores the tasks the transaction wrote and removes the
+// revisions and audit entries it appended. Tasks another request has
+// changed since are left alone, so its write is not lost.
+func (u *undoLog) rollback(tasks *InMemoryTaskRepository, revisions *InMemoryTaskRevisionRepository, audit *InMemoryAuditRepository) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	tasks.mu.Lock()
+	for id, entry := range u.tasks {
+		current, ok := tasks.tasks[id]
+		if !ok || current.Revision != entry.revision {
+			continue
+		}
+		if entry.before == nil {
+			delete(tasks.tasks, id)
+			tasks.index.remove(id)
+		} else {
+			tasks.tasks[id] = *entry.before
+			tasks.index.set(*entry.before)
+		}
+	}
+	tasks.mu.Unlock()
+
+	revisions.mu.Lock()
+	for taskID, history := range revisions.revisions {
+		revisions.revisions[taskID] = slices.DeleteFunc(history, func(rev Domain.TaskRevision) bool {
+			return slices.Contains(u.revisions, rev.ID)
+		})
+	}
+	revisions.mu.Unlock()
+
+	audit.mu.Lock()
+	audit.entries = slices.DeleteFunc(audit.entries, func(entry Domain.AuditEntry) bool {
+		return slices.Contains(u.entries, entry.ID)
+	})
+	audit.mu.Unlock()
+}
+
+// undoTaskRepository is the task repository of a transaction
+type undoTaskRepository struct {
+	*InMemoryTaskRepository
+	undo *undoLog
+}
+
+func (r *undoTaskRepository) Create(ctx context.Context, task *Domain.Task) error {
+	if err := r.InMemoryTaskRepository.Create(ctx, task); err != nil {
+		return err
+	}
+	r.undo.wroteTask(task)
+	return nil
+}
+
+func (r *undoTaskRepository) CreateOccurrence(ctx context.Context, task *Domain.Task) error {
+	if err := r.InMemoryTaskRepository.CreateOccurrence(ctx, task); err != nil {
+		return err
+	}
+	r.undo.wroteTask(task)
+	return nil
+}
+
+func (r *undoTaskRepository) Update(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope, updates map[string]interface{}) (*Domain.Task, error) {
+	return r.write(id, func() (*Domain.Task, error) {
+		return r.InMemoryTaskRepository.Update(ctx, id, scope, updates)
+	})
+}
+
+func (r *undoTaskRepository) Delete(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope) (*Domain.Task, error) {
+	return r.write(id, func() (*Domain.Task, error) {
+		return r.InMemoryTaskRepository.Delete(ctx, id, scope)
+	})
+}
+
+func (r *undoTaskRepository) Undelete(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope) (*Domain.Task, error) {
+	return r.write(id, func() (*Domain.Task, error) {
+		return r.InMemoryTaskRepository.Undelete(ctx, id, scope)
+	})
+}
+
+func (r *undoTaskRepository) SetCollaborator(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope, collaborator Domain.Collaborator) (*Domain.Task, error) {
+	return r.write(id, func() (*Domain.Task, error) {
+		return r.InMemoryTaskRepository.SetCollaborator(ctx, id, scope, collaborator)
+	})
+}
+
+func (r *undoTaskRepository) RemoveCollaborator(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope, userID primitive.ObjectID) (*Domain.Task, error) {
+	return r.write(id, func() (*Domain.Task, error) {
+		return r.InMemoryTaskRepository.RemoveCollaborator(ctx, id, scope, userID)
+	})
+}
+
+func (r *undoTaskRepository) MarkRecurred(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.write(id, func() (*Domain.Task, error) {
+		if err := r.InMemoryTaskRepository.MarkRecurred(ctx, id); err != nil {
+			return nil, err
+		}
+		task, ok := r.get(id)
+		if !ok {
+			return nil, Domain.ErrNotFound
+		}
+		return &task, nil
+	})
+	return err
+}
+
+// write saves the task for rollback before write changes it
+func (r *undoTaskRepository) write(id primitive.ObjectID, write func() (*Domain.Task, error)) (*Domain.Task, error) {
+	r.undo.saveTask(r.InMemoryTaskRepository, id)
+	task, err := write()
+	if err != nil {
+		return nil, err
+	}
+	r.undo.wroteTask(task)
+	return task, nil
+}
+
+// undoTaskRevisionRepository is the revision repository of a transaction
+type undoTaskRevisionRepository struct {
+	*InMemoryTaskRevisionRepository
+	undo *undoLog
+}
+
+func (r *undoTaskRevisionRepository) Append(ctx context.Context, revision *Domain.TaskRevision) error {
+	if err := r.InMemoryTaskRevisionRepository.Append(ctx, revision); err != nil {
+		return err
+	}
+	r.undo.mu.Lock()
+	r.undo.revisions = append(r.undo.revisions, revision.ID)
+	r.undo.mu.Unlock()
+	return nil
+}
+
+// undoAuditRepository is the audit repository of a transaction
+type undoAuditRepository struct {
+	*InMemoryAuditRepository
+	undo *undoLog
+}
+
+func (r *undoAuditRepository) Append(ctx context.Context, entry *Domain.AuditEntry) error {
+	if err := r.InMemoryAuditRepository.Append(ctx, entry); err != nil {
+		return err
+	}
+	r.undo.mu.Lock()
+	r.undo.entries = append(r.undo.entries, entry.ID)
+	r.undo.mu.Unlock()
+	return nil
+}
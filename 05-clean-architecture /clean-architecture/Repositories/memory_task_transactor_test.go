@@ -0,0 +1,102 @@
+package Repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// TestInMemoryTransactionRollback fails a transaction after it created,
+// updated and deleted tasks and appended revisions and audit entries, and
+// checks that only its own writes are taken back
+func TestInMemoryTransactionRollback(t *testing.T) {
+	ctx := context.Background()
+	tasks := NewInMemoryTaskRepository()
+	revisions := NewInMemoryTaskRevisionRepository()
+	audit := NewInMemoryAuditRepository()
+	transactor := NewInMemoryTaskTransactor(tasks, revisions, audit)
+
+	updated := &Domain.Task{ID: primitive.NewObjectID(), Title: "Updated in the transaction", Revision: 1}
+	deleted := &Domain.Task{ID: primitive.NewObjectID(), Title: "Deleted in the transaction", Revision: 1}
+	other := &Domain.Task{ID: primitive.NewObjectID(), Title: "Updated by another request", Revision: 1}
+	for _, task := range []*Domain.Task{updated, deleted, other} {
+		if err := tasks.Create(ctx, task); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	if err := revisions.Append(ctx, &Domain.TaskRevision{TaskID: updated.ID, Revision: 1, Task: *updated}); err != nil {
+		t.Fatalf("Append revision: %v", err)
+	}
+	if err := audit.Append(ctx, &Domain.AuditEntry{Action: Domain.AuditTaskCreated, EntityID: &updated.ID}); err != nil {
+		t.Fatalf("Append audit entry: %v", err)
+	}
+
+	all := Domain.TaskScope{AllTasks: true}
+	failure := errors.New("a later operation failed")
+	var created *Domain.Task
+	err := transactor.WithinTransaction(ctx, func(ctx context.Context, repos Domain.TaskRepositories) error {
+		created = &Domain.Task{ID: primitive.NewObjectID(), Title: "Created in the transaction", Revision: 1}
+		if err := repos.Tasks.Create(ctx, created); err != nil {
+			return err
+		}
+		if _, err := repos.Tasks.Update(ctx, updated.ID, all, map[string]interface{}{"title": "Changed", "revision": 2}); err != nil {
+			return err
+		}
+		if err := repos.Revisions.Append(ctx, &Domain.TaskRevision{TaskID: updated.ID, Revision: 2}); err != nil {
+			return err
+		}
+		if _, err := repos.Tasks.Delete(ctx, deleted.ID, all); err != nil {
+			return err
+		}
+		if err := repos.Audit.Append(ctx, &Domain.AuditEntry{Action: Domain.AuditTaskUpdated, EntityID: &updated.ID}); err != nil {
+			return err
+		}
+
+		// Another request's write, which the rollback must keep
+		if _, err := tasks.Update(context.Background(), other.ID, all, map[string]interface{}{"title": "Kept", "revision": 2}); err != nil {
+			return err
+		}
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("WithinTransaction = %v, want %v", err, failure)
+	}
+
+	if _, err := tasks.GetByID(ctx, created.ID, Domain.TaskScope{AllTasks: true, AnyDeletedState: true}); err != Domain.ErrNotFound {
+		t.Errorf("created task: GetByID = %v, want ErrNotFound", err)
+	}
+	if task, err := tasks.GetByID(ctx, updated.ID, all); err != nil || task.Title != updated.Title || task.Revision != 1 {
+		t.Errorf("updated task = %+v, %v, want %q at revision 1", task, err, updated.Title)
+	}
+	if task, err := tasks.GetByID(ctx, deleted.ID, all); err != nil || task.DeletedAt != nil {
+		t.Errorf("deleted task = %+v, %v, want it out of the trash", task, err)
+	}
+	if task, err := tasks.GetByID(ctx, other.ID, all); err != nil || task.Title != "Kept" {
+		t.Errorf("task changed by another request = %+v, %v, want its change kept", task, err)
+	}
+
+	history, err := revisions.List(ctx, updated.ID)
+	if err != nil {
+		t.Fatalf("List revisions: %v", err)
+	}
+	if len(history) != 1 || history[0].Revision != 1 {
+		t.Errorf("revisions = %+v, want only revision 1", history)
+	}
+	page, err := audit.List(ctx, Domain.AuditListOptions{})
+	if err != nil {
+		t.Fatalf("List audit entries: %v", err)
+	}
+	if len(page.Entries) != 1 || page.Entries[0].Action != Domain.AuditTaskCreated {
+		t.Errorf("audit entries = %+v, want only the one written before", page.Entries)
+	}
+
+	// The search index follows the rollback too
+	query, _ := Domain.ParseSearchQuery("changed")
+	if hits, err := tasks.Search(ctx, all, query, Domain.MaxSearchLimit); err != nil || len(hits) != 0 {
+		t.Errorf("Search for the rolled back title = %d hits, %v, want none", len(hits), err)
+	}
+}
@@ -0,0 +1,144 @@
+package Repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// InMemoryTokenRepository is a thread-safe Domain.TokenRepository that keeps
+// refresh tokens and revoked token IDs in process memory.
+type InMemoryTokenRepository struct {
+	mu      sync.Mutex
+	tokens  map[primitive.ObjectID]Domain.RefreshToken
+	byHash  map[string]primitive.ObjectID
+	revoked map[string]time.Time
+}
+
+func NewInMemoryTokenRepository() *InMemoryTokenRepository {
+	return &InMemoryTokenRepository{
+		tokens:  make(map[primitive.ObjectID]Domain.RefreshToken),
+		byHash:  make(map[string]primitive.ObjectID),
+		revoked: make(map[string]time.Time),
+	}
+}
+
+func (r *InMemoryTokenRepository) CreateRefreshToken(ctx context.Context, token *Domain.RefreshToken) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.purgeExpired()
+
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	r.tokens[token.ID] = *token
+	r.byHash[token.TokenHash] = token.ID
+	return nil
+}
+
+func (r *InMemoryTokenRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*Domain.RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, ok := r.byHash[tokenHash]
+	if !ok {
+		return nil, Domain.ErrNotFound
+	}
+	token := r.tokens[id]
+	return &token, nil
+}
+
+func (r *InMemoryTokenRepository) MarkRefreshTokenUsed(ctx context.Context, id primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return Domain.ErrTokenReused
+	}
+
+	now := time.Now()
+	token.UsedAt = &now
+	r.tokens[id] = token
+	return nil
+}
+
+func (r *InMemoryTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			r.tokens[id] = token
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryTokenRepository) RevokeTokenID(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.purgeExpired()
+
+	r.revoked[tokenID] = expiresAt
+	return nil
+}
+
+func (r *InMemoryTokenRepository) IsTokenIDRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, revoked := r.revoked[tokenID]
+	return revoked, nil
+}
+
+// purgeExpired drops entries past their expiry, standing in for Mongo's TTL
+// indexes. Callers must hold the lock.
+func (r *InMemoryTokenRepository) purgeExpired() {
+	now := time.Now()
+
+	for id, token := range r.tokens {
+		if now.After(token.ExpiresAt) {
+			delete(r.byHash, token.TokenHash)
+			delete(r.tokens, id)
+		}
+	}
+
+	for tokenID, expiresAt := range r.revoked {
+		if now.After(expiresAt) {
+			delete(r.revoked, tokenID)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package Repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// InMemoryUserRepository is a thread-safe Domain.UserRepository that keeps
+// users in process memory. It is meant for development and tests.
+type InMemoryUserRepository struct {
+	mu         sync.RWMutex
+	users      map[primitive.ObjectID]Domain.User
+	byUsername map[string]primitive.ObjectID
+}
+
+func NewInMemoryUserRepository() *InMemoryUserRepository {
+	return &InMemoryUserRepository{
+		users:      make(map[primitive.ObjectID]Domain.User),
+		byUsername: make(map[string]primitive.ObjectID),
+	}
+}
+
+// Initialize exists for parity with UserRepository; usernames are always
+// unique in memory so there is nothing to set up.
+func (r *InMemoryUserRepository) Initialize(ctx context.Context) error {
+	return nil
+}
+
+func (r *InMemoryUserRepository) Create(ctx context.Context, user *Domain.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, taken := r.byUsername[user.Username]; taken {
+		return Domain.ErrUsernameTaken
+	}
+
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	r.users[user.ID] = *user
+	r.byUsername[user.Username] = user.ID
+	return nil
+}
+
+func (r *InMemoryUserRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*Domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, Domain.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *InMemoryUserRepository) GetByUsername(ctx context.Context, username string) (*Domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byUsername[username]
+	if !ok {
+		return nil, Domain.ErrNotFound
+	}
+	user := r.users[id]
+	return &user, nil
+}
+
+func (r *InMemoryUserRepository) UpdateLastLogin(ctx context.Context, id primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return r.update(id, func(user *Domain.User) {
+		user.LastLoginAt = time.Now()
+	})
+}
+
+func (r *InMemoryUserRepository) List(ctx context.Context, opts Domain.UserListOptions) (*Domain.UserPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	opts = opts.WithDefaults()
+
+	var after primitive.ObjectID
+	if opts.Cursor != "" {
+		var err error
+		if after, err = decodeIDCursor(opts.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matching []Domain.User
+	for _, user := range r.users {
+		if matchesUserFilter(user, opts.Filter) {
+			matching = append(matching, user)
+		}
+	}
+
+	sort.Slice(matching, func(i, j int) bool {
+		return matching[i].ID.Hex() < matching[j].ID.Hex()
+	})
+
+	users := []Domain.User{}
+	for _, user := range matching {
+		if opts.Cursor != "" && user.ID.Hex() <= after.Hex() {
+			continue
+		}
+		users = append(users, user)
+		// Keep one extra user to find out whether there is a next page
+		if len(users) > opts.Limit {
+			break
+		}
+	}
+
+	return newUserPage(users, int64(len(matching)), opts), nil
+}
+
+func (r *InMemoryUserRepository) UpdateRole(ctx context.Context, id primitive.ObjectID, grant Domain.RoleGrant) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return r.update(id, func(user *Domain.User) {
+		user.Role = grant.Role
+		user.RoleGrants = append(append([]Domain.RoleGrant{}, user.RoleGrants...), grant)
+	})
+}
+
+func (r *InMemoryUserRepository) CountByRole(ctx context.Context, role Domain.Role) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, user := range r.users {
+		if user.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryUserRepository) SetDisabled(ctx context.Context, id primitive.ObjectID, disabled bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return r.update(id, func(user *Domain.User) {
+		user.Disabled = disabled
+	})
+}
+
+func (r *InMemoryUserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return Domain.ErrNotFound
+	}
+
+	delete(r.byUsername, user.Username)
+	delete(r.users, id)
+	return nil
+}
+
+func (r *InMemoryUserRepository) update(id primitive.ObjectID, apply func(user *Domain.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return Domain.ErrNotFound
+	}
+
+	apply(&user)
+	r.users[id] = user
+	return nil
+}
@@ -0,0 +1,237 @@
+package Repositories
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// InMemoryWebhookRepository is a thread-safe Domain.WebhookRepository that
+// keeps webhooks in process memory. It is meant for development and tests.
+type InMemoryWebhookRepository struct {
+	mu       sync.RWMutex
+	webhooks []Domain.Webhook // oldest first
+}
+
+func NewInMemoryWebhookRepository() *InMemoryWebhookRepository {
+	return &InMemoryWebhookRepository{}
+}
+
+func (r *InMemoryWebhookRepository) Create(ctx context.Context, webhook *Domain.Webhook) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if webhook.ID.IsZero() {
+		webhook.ID = primitive.NewObjectID()
+	}
+	r.webhooks = append(r.webhooks, cloneWebhook(*webhook))
+	return nil
+}
+
+func (r *InMemoryWebhookRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*Domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, webhook := range r.webhooks {
+		if webhook.ID == id {
+			webhook = cloneWebhook(webhook)
+			return &webhook, nil
+		}
+	}
+	return nil, Domain.ErrNotFound
+}
+
+func (r *InMemoryWebhookRepository) ListByUsers(ctx context.Context, userIDs []primitive.ObjectID) ([]Domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhooks := []Domain.Webhook{}
+	for _, webhook := range r.webhooks {
+		if slices.Contains(userIDs, webhook.UserID) {
+			webhooks = append(webhooks, cloneWebhook(webhook))
+		}
+	}
+	return webhooks, nil
+}
+
+func (r *InMemoryWebhookRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, webhook := range r.webhooks {
+		if webhook.ID == id {
+			r.webhooks = slices.Delete(r.webhooks, i, i+1)
+			return nil
+		}
+	}
+	return Domain.ErrNotFound
+}
+
+func cloneWebhook(webhook Domain.Webhook) Domain.Webhook {
+	webhook.Events = slices.Clone(webhook.Events)
+	return webhook
+}
+
+// InMemoryWebhookDeliveryRepository is a thread-safe
+// Domain.WebhookDeliveryRepository that keeps deliveries in process memory.
+// It is meant for development and tests.
+type InMemoryWebhookDeliveryRepository struct {
+	mu         sync.RWMutex
+	deliveries []Domain.WebhookDelivery // oldest first
+}
+
+func NewInMemoryWebhookDeliveryRepository() *InMemoryWebhookDeliveryRepository {
+	return &InMemoryWebhookDeliveryRepository{}
+}
+
+func (r *InMemoryWebhookDeliveryRepository) Create(ctx context.Context, delivery *Domain.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if delivery.ID.IsZero() {
+		delivery.ID = primitive.NewObjectID()
+	}
+	r.deliveries = append(r.deliveries, cloneDelivery(*delivery))
+	return nil
+}
+
+func (r *InMemoryWebhookDeliveryRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*Domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if i := r.indexOf(id); i >= 0 {
+		delivery := cloneDelivery(r.deliveries[i])
+		return &delivery, nil
+	}
+	return nil, Domain.ErrNotFound
+}
+
+func (r *InMemoryWebhookDeliveryRepository) List(ctx context.Context, filter Domain.WebhookDeliveryFilter, limit int) ([]Domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := []Domain.WebhookDelivery{}
+	for i := len(r.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		delivery := r.deliveries[i]
+		if !filter.UserID.IsZero() && delivery.UserID != filter.UserID {
+			continue
+		}
+		if filter.WebhookID != nil && delivery.WebhookID != *filter.WebhookID {
+			continue
+		}
+		if filter.Status != "" && delivery.Status != filter.Status {
+			continue
+		}
+		deliveries = append(deliveries, cloneDelivery(delivery))
+	}
+	return deliveries, nil
+}
+
+func (r *InMemoryWebhookDeliveryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []int
+	for i, delivery := range r.deliveries {
+		if delivery.Status != Domain.WebhookDeliveryPending || delivery.NextAttemptAt == nil || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		if delivery.LockedUntil != nil && delivery.LockedUntil.After(now) {
+			continue
+		}
+		due = append(due, i)
+	}
+	// Longest waiting first, as in the MongoDB repository
+	slices.SortStableFunc(due, func(a, b int) int {
+		return r.deliveries[a].NextAttemptAt.Compare(*r.deliveries[b].NextAttemptAt)
+	})
+
+	lockedUntil := now.Add(lease)
+	claimed := []Domain.WebhookDelivery{}
+	for _, i := range due[:min(len(due), limit)] {
+		r.deliveries[i].LockedUntil = &lockedUntil
+		r.deliveries[i].LeaseID = primitive.NewObjectID()
+		claimed = append(claimed, cloneDelivery(r.deliveries[i]))
+	}
+	return claimed, nil
+}
+
+func (r *InMemoryWebhookDeliveryRepository) Update(ctx context.Context, delivery *Domain.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.indexOf(delivery.ID)
+	if i < 0 || r.deliveries[i].LeaseID != delivery.LeaseID {
+		return errLeaseLost
+	}
+	r.deliveries[i] = cloneDelivery(*delivery)
+	r.deliveries[i].LockedUntil = nil
+	r.deliveries[i].LeaseID = primitive.NilObjectID
+	return nil
+}
+
+func (r *InMemoryWebhookDeliveryRepository) DeleteByWebhook(ctx context.Context, webhookID primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deliveries = slices.DeleteFunc(r.deliveries, func(delivery Domain.WebhookDelivery) bool {
+		return delivery.WebhookID == webhookID
+	})
+	return nil
+}
+
+func (r *InMemoryWebhookDeliveryRepository) indexOf(id primitive.ObjectID) int {
+	return slices.IndexFunc(r.deliveries, func(delivery Domain.WebhookDelivery) bool {
+		return delivery.ID == id
+	})
+}
+
+func cloneDelivery(delivery Domain.WebhookDelivery) Domain.WebhookDelivery {
+	delivery.Attempts = slices.Clone(delivery.Attempts)
+	return delivery
+}
@@ -0,0 +1,39 @@
+package Repositories
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testMongoURIEnv names the MongoDB server tests of the Mongo repositories
+// run against. They are skipped without one.
+const testMongoURIEnv = "TEST_MONGODB_URI"
+
+// testMongoDatabase returns a database of its own for the test, which is
+// dropped afterwards, or nil if testMongoURIEnv is not set
+func testMongoDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+
+	uri := os.Getenv(testMongoURIEnv)
+	if uri == "" {
+		t.Logf("%s is not set, skipping MongoDB", testMongoURIEnv)
+		return nil
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	database := client.Database("taskmanager_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		_ = database.Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+	return database
+}
@@ -0,0 +1,141 @@
+package Repositories
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"taskmanager/auth/Domain"
+)
+
+// taskEventRetention is how long relayed events are stored
+const taskEventRetention = 24 * time.Hour
+
+// TaskEventRelay is a Domain.TaskEventBus that shares events between
+// instances. Events are published by storing them in a collection, and
+// every instance watches its change stream and passes what it sees on to
+// its local bus, which serves the subscribers. Change streams need a
+// replica set.
+type TaskEventRelay struct {
+	collection *mongo.Collection
+	local      Domain.TaskEventBus
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+	timeout    time.Duration
+}
+
+func NewTaskEventRelay(collection *mongo.Collection, local Domain.TaskEventBus, ctx context.Context, timeout time.Duration) *TaskEventRelay {
+	ctx, cancel := context.WithCancel(ctx)
+	return &TaskEventRelay{
+		collection: collection,
+		local:      local,
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+		timeout:    timeout,
+	}
+}
+
+func (r *TaskEventRelay) Initialize(ctx context.Context) error {
+	// Expire events well after any subscriber could resume from them
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "occurred_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(taskEventRetention.Seconds())),
+	})
+	return err
+}
+
+func (r *TaskEventRelay) Publish(event Domain.TaskEvent) {
+	// IDs are set here so that every instance sees the same ones
+	if event.ID == "" {
+		event.ID = primitive.NewObjectID().Hex()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	ctx, cancel := withTimeout(r.ctx, r.timeout)
+	defer cancel()
+
+	if _, err := r.collection.InsertOne(ctx, event); err != nil {
+		// Other instances miss it, but local subscribers still hear of it
+		log.Printf("Failed to relay task event %s: %v", event.ID, err)
+		r.local.Publish(event)
+	}
+}
+
+func (r *TaskEventRelay) Subscribe(lastEventID string, filter func(event Domain.TaskEvent) bool) *Domain.TaskEventSubscription {
+	return r.local.Subscribe(lastEventID, filter)
+}
+
+// Start watches the collection for events in the background
+func (r *TaskEventRelay) Start() {
+	go r.run()
+}
+
+// Stop stops watching and waits for the watcher to finish
+func (r *TaskEventRelay) Stop() {
+	r.cancel()
+	<-r.done
+}
+
+func (r *TaskEventRelay) run() {
+	defer close(r.done)
+
+	var resumeToken bson.Raw
+	backoff := time.Second
+	for {
+		token, err := r.watch(resumeToken)
+		if token != nil {
+			resumeToken = token
+			backoff = time.Second
+		}
+		if r.ctx.Err() != nil {
+			return
+		}
+		log.Printf("Task event change stream: %v; retrying in %s", err, backoff)
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, time.Minute)
+	}
+}
+
+// watch passes inserted events to the local bus until the stream fails,
+// and returns the resume token of the last one
+func (r *TaskEventRelay) watch(resumeToken bson.Raw) (bson.Raw, error) {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": "insert"}}}}
+	opts := options.ChangeStream()
+	if resumeToken != nil {
+		opts.SetStartAfter(resumeToken)
+	}
+
+	stream, err := r.collection.Watch(r.ctx, pipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close(context.Background())
+
+	var lastToken bson.Raw
+	for stream.Next(r.ctx) {
+		var change struct {
+			FullDocument Domain.TaskEvent `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			log.Printf("Failed to decode relayed task event: %v", err)
+		} else {
+			r.local.Publish(change.FullDocument)
+		}
+		lastToken = stream.ResumeToken()
+	}
+	return lastToken, stream.Err()
+}
@@ -0,0 +1,350 @@
+package Repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// taskCursor is the decoded form of TaskPage.NextCursor. It records the sort
+// key and the position of the last task on the page (keyset pagination), so
+// results stay stable while tasks are inserted or removed.
+type taskCursor struct {
+	SortBy Domain.TaskSortField `json:"s"`
+	Desc   bool                 `json:"d,omitempty"`
+	Value  string               `json:"v"`
+	ID     primitive.ObjectID   `json:"id"`
+}
+
+func encodeTaskCursor(task Domain.Task, opts Domain.TaskListOptions) string {
+	cursor := taskCursor{
+		SortBy: opts.SortBy,
+		Desc:   opts.SortDesc,
+		ID:     task.ID,
+	}
+
+	switch opts.SortBy {
+	case Domain.SortByUpdatedAt:
+		cursor.Value = task.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case Domain.SortByTitle:
+		cursor.Value = task.Title
+	default:
+		cursor.Value = task.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeTaskCursor(token string, opts Domain.TaskListOptions) (*taskCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, Domain.ErrInvalidCursor
+	}
+
+	var cursor taskCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, Domain.ErrInvalidCursor
+	}
+
+	// A cursor is only meaningful for the ordering it was created with
+	if cursor.SortBy != opts.SortBy || cursor.Desc != opts.SortDesc || cursor.ID.IsZero() {
+		return nil, Domain.ErrInvalidCursor
+	}
+
+	if _, err := cursor.sortValue(); err != nil {
+		return nil, Domain.ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// sortValue returns the cursor value typed the way it is stored
+func (c *taskCursor) sortValue() (interface{}, error) {
+	if c.SortBy == Domain.SortByTitle {
+		return c.Value, nil
+	}
+	return time.Parse(time.RFC3339Nano, c.Value)
+}
+
+// newTaskPage trims the extra look-ahead task off a page and derives the
+// cursor for the next one
+func newTaskPage(tasks []Domain.Task, total int64, opts Domain.TaskListOptions) *Domain.TaskPage {
+	page := &Domain.TaskPage{Tasks: tasks, Total: total}
+
+	if len(tasks) > opts.Limit {
+		page.Tasks = tasks[:opts.Limit]
+		page.NextCursor = encodeTaskCursor(page.Tasks[opts.Limit-1], opts)
+	}
+
+	return page
+}
+
+// scopeFilter restricts a Mongo query to the tasks inside the scope. It is
+// the Mongo equivalent of Domain.TaskScope.Allows and only uses the user_id,
+// deleted_at and $or keys, so callers can add their own conditions to it.
+func scopeFilter(scope Domain.TaskScope) bson.M {
+	filter := bson.M{"deleted_at": nil}
+	if scope.Deleted {
+		filter["deleted_at"] = bson.M{"$ne": nil}
+	}
+	if scope.AnyDeletedState {
+		delete(filter, "deleted_at")
+	}
+
+	if scope.AllTasks {
+		return filter
+	}
+
+	owned := bson.M{"user_id": scope.UserID}
+	if scope.ExcludeShared || scope.Access == Domain.TaskAccessManage {
+		filter["user_id"] = scope.UserID
+		return filter
+	}
+
+	shared := bson.M{"collaborators.user_id": scope.UserID}
+	if scope.Access == Domain.TaskAccessWrite {
+		shared = bson.M{"collaborators": bson.M{"$elemMatch": bson.M{
+			"user_id": scope.UserID,
+			"level":   Domain.ShareLevelEditor,
+		}}}
+	}
+
+	filter["$or"] = bson.A{owned, shared}
+	return filter
+}
+
+// taskFilterQuery translates a TaskFilter into a Mongo query
+func taskFilterQuery(filter Domain.TaskFilter) bson.M {
+	query := bson.M{}
+
+	if filter.Completed != nil {
+		query["completed"] = *filter.Completed
+	}
+
+	if createdAt := timeRange(filter.CreatedAfter, filter.CreatedBefore); createdAt != nil {
+		query["created_at"] = createdAt
+	}
+
+	if updatedAt := timeRange(filter.UpdatedAfter, filter.UpdatedBefore); updatedAt != nil {
+		query["updated_at"] = updatedAt
+	}
+
+	if filter.TitleContains != "" {
+		query["title"] = primitive.Regex{
+			Pattern: regexp.QuoteMeta(filter.TitleContains),
+			Options: "i",
+		}
+	}
+
+	if dueBefore := dueBefore(filter, time.Now()); dueBefore != nil {
+		query["due_at"] = bson.M{"$lt": *dueBefore}
+	}
+
+	if filter.Overdue {
+		query["completed"] = false
+	}
+
+	if filter.Priority == Domain.PriorityMedium {
+		// Tasks created before priorities existed count as medium
+		query["priority"] = bson.M{"$in": bson.A{Domain.PriorityMedium, nil}}
+	} else if filter.Priority != "" {
+		query["priority"] = filter.Priority
+	}
+
+	if filter.Tag != "" {
+		query["tags"] = filter.Tag
+	}
+
+	return query
+}
+
+// textSearchQuery translates a SearchQuery into a Mongo query matching the
+// tasks the in-memory search index matches (see Domain.SearchQuery).
+// Phrases and prefixes are required and matched by regex on whole words, in
+// the same field. $text ranks the words; it only narrows the results when
+// there is nothing else to match, since it requires one of its terms. With
+// prefixes but no phrases, no $text term is sure to match, so the results
+// are not ranked.
+func textSearchQuery(query Domain.SearchQuery) bson.M {
+	filter := bson.M{}
+
+	var required bson.A
+	for _, phrase := range query.Phrases {
+		words := make([]string, len(phrase))
+		for i, word := range phrase {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		required = append(required, wordsInField(strings.Join(words, `[^\p{L}\p{N}]+`)+`($|[^\p{L}\p{N}])`))
+	}
+	for _, prefix := range query.Prefixes {
+		required = append(required, wordsInField(regexp.QuoteMeta(prefix)))
+	}
+	if len(required) > 0 {
+		filter["$and"] = required
+	}
+
+	// Every task with a phrase has its words, so they can go in $text
+	// without narrowing the results
+	terms := slices.Clone(query.Words)
+	for _, phrase := range query.Phrases {
+		terms = append(terms, phrase...)
+	}
+	if len(terms) > 0 && (len(query.Phrases) > 0 || len(query.Prefixes) == 0) {
+		filter["$text"] = bson.M{"$search": strings.Join(terms, " "), "$diacriticSensitive": true}
+	}
+
+	return filter
+}
+
+// wordsInField matches the title or the description having the pattern at
+// the start of a word
+func wordsInField(pattern string) bson.M {
+	word := primitive.Regex{Pattern: `(^|[^\p{L}\p{N}])` + pattern, Options: "i"}
+	return bson.M{"$or": bson.A{
+		bson.M{"title": word},
+		bson.M{"description": word},
+	}}
+}
+
+// dueBefore combines the due_before and overdue filters into one bound
+func dueBefore(filter Domain.TaskFilter, now time.Time) *time.Time {
+	bound := filter.DueBefore
+	if filter.Overdue && (bound == nil || now.Before(*bound)) {
+		bound = &now
+	}
+	return bound
+}
+
+func timeRange(after, before *time.Time) bson.M {
+	if after == nil && before == nil {
+		return nil
+	}
+
+	bounds := bson.M{}
+	if after != nil {
+		bounds["$gte"] = *after
+	}
+	if before != nil {
+		bounds["$lt"] = *before
+	}
+	return bounds
+}
+
+// cursorQuery selects the tasks that come after the cursor in sort order
+func cursorQuery(cursor *taskCursor) bson.M {
+	value, _ := cursor.sortValue()
+	field := string(cursor.SortBy)
+
+	op := "$gt"
+	if cursor.Desc {
+		op = "$lt"
+	}
+
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{op: value}},
+		bson.M{field: value, "_id": bson.M{op: cursor.ID}},
+	}}
+}
+
+// matchesTaskFilter is the in-memory equivalent of taskFilterQuery
+func matchesTaskFilter(task Domain.Task, filter Domain.TaskFilter) bool {
+	if filter.Completed != nil && task.Completed != *filter.Completed {
+		return false
+	}
+
+	if !inTimeRange(task.CreatedAt, filter.CreatedAfter, filter.CreatedBefore) {
+		return false
+	}
+
+	if !inTimeRange(task.UpdatedAt, filter.UpdatedAfter, filter.UpdatedBefore) {
+		return false
+	}
+
+	if filter.TitleContains != "" &&
+		!strings.Contains(strings.ToLower(task.Title), strings.ToLower(filter.TitleContains)) {
+		return false
+	}
+
+	if bound := dueBefore(filter, time.Now()); bound != nil &&
+		(task.DueAt == nil || !task.DueAt.Before(*bound)) {
+		return false
+	}
+
+	if filter.Overdue && task.Completed {
+		return false
+	}
+
+	if filter.Priority != "" {
+		priority := task.Priority
+		if priority == "" {
+			priority = Domain.PriorityMedium
+		}
+		if priority != filter.Priority {
+			return false
+		}
+	}
+
+	if filter.Tag != "" && !slices.Contains(task.Tags, filter.Tag) {
+		return false
+	}
+
+	return true
+}
+
+func inTimeRange(t time.Time, after, before *time.Time) bool {
+	if after != nil && t.Before(*after) {
+		return false
+	}
+	if before != nil && !t.Before(*before) {
+		return false
+	}
+	return true
+}
+
+// compareTasks orders tasks by the sort field, breaking ties by ID
+func compareTasks(a, b Domain.Task, sortBy Domain.TaskSortField) int {
+	var cmp int
+	switch sortBy {
+	case Domain.SortByUpdatedAt:
+		cmp = a.UpdatedAt.Compare(b.UpdatedAt)
+	case Domain.SortByTitle:
+		cmp = strings.Compare(a.Title, b.Title)
+	default:
+		cmp = a.CreatedAt.Compare(b.CreatedAt)
+	}
+
+	if cmp == 0 {
+		cmp = strings.Compare(a.ID.Hex(), b.ID.Hex())
+	}
+	return cmp
+}
+
+// isAfterCursor is the in-memory equivalent of cursorQuery
+func isAfterCursor(task Domain.Task, cursor *taskCursor) bool {
+	value, _ := cursor.sortValue()
+
+	var boundary Domain.Task
+	boundary.ID = cursor.ID
+	switch v := value.(type) {
+	case string:
+		boundary.Title = v
+	case time.Time:
+		boundary.CreatedAt = v
+		boundary.UpdatedAt = v
+	}
+
+	cmp := compareTasks(task, boundary, cursor.SortBy)
+	if cursor.Desc {
+		return cmp < 0
+	}
+	return cmp > 0
+}
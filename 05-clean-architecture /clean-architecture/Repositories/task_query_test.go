@@ -0,0 +1,189 @@
+package Repositories
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// testID returns an ObjectID that sorts by n
+func testID(n byte) primitive.ObjectID {
+	var id primitive.ObjectID
+	id[len(id)-1] = n
+	return id
+}
+
+func TestIsAfterCursor(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	task := func(id byte, title string, createdAt time.Time) Domain.Task {
+		return Domain.Task{ID: testID(id), Title: title, CreatedAt: createdAt, UpdatedAt: createdAt}
+	}
+	cursor := func(sortBy Domain.TaskSortField, desc bool, last Domain.Task) *taskCursor {
+		opts := Domain.TaskListOptions{SortBy: sortBy, SortDesc: desc}
+		decoded, err := decodeTaskCursor(encodeTaskCursor(last, opts), opts)
+		if err != nil {
+			t.Fatalf("decodeTaskCursor: %v", err)
+		}
+		return decoded
+	}
+
+	last := task(5, "beta", base)
+	tests := []struct {
+		name   string
+		sortBy Domain.TaskSortField
+		desc   bool
+		task   Domain.Task
+		want   bool
+	}{
+		{"later title", Domain.SortByTitle, false, task(1, "gamma", base), true},
+		{"earlier title", Domain.SortByTitle, false, task(9, "alpha", base), false},
+		{"title tie, higher ID", Domain.SortByTitle, false, task(6, "beta", base), true},
+		{"title tie, lower ID", Domain.SortByTitle, false, task(4, "beta", base), false},
+		{"title tie, same task", Domain.SortByTitle, false, task(5, "beta", base), false},
+		{"descending, earlier title", Domain.SortByTitle, true, task(9, "alpha", base), true},
+		{"descending, later title", Domain.SortByTitle, true, task(1, "gamma", base), false},
+		{"descending title tie, lower ID", Domain.SortByTitle, true, task(4, "beta", base), true},
+		{"descending title tie, higher ID", Domain.SortByTitle, true, task(6, "beta", base), false},
+		{"created later", Domain.SortByCreatedAt, false, task(1, "beta", base.Add(time.Millisecond)), true},
+		{"created earlier", Domain.SortByCreatedAt, false, task(9, "beta", base.Add(-time.Millisecond)), false},
+		{"created at the same time, higher ID", Domain.SortByCreatedAt, false, task(6, "beta", base), true},
+		{"descending, created earlier", Domain.SortByCreatedAt, true, task(9, "beta", base.Add(-time.Millisecond)), true},
+		{"descending, created at the same time, higher ID", Domain.SortByCreatedAt, true, task(6, "beta", base), false},
+		{"updated later", Domain.SortByUpdatedAt, false, task(1, "beta", base.Add(time.Second)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAfterCursor(tt.task, cursor(tt.sortBy, tt.desc, last)); got != tt.want {
+				t.Errorf("isAfterCursor = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeTaskCursorRejectsOtherOrderings(t *testing.T) {
+	last := Domain.Task{ID: testID(1), Title: "beta"}
+	token := encodeTaskCursor(last, Domain.TaskListOptions{SortBy: Domain.SortByTitle})
+
+	tests := []struct {
+		name  string
+		token string
+		opts  Domain.TaskListOptions
+	}{
+		{"other field", token, Domain.TaskListOptions{SortBy: Domain.SortByCreatedAt}},
+		{"other direction", token, Domain.TaskListOptions{SortBy: Domain.SortByTitle, SortDesc: true}},
+		{"not base64", "!!!", Domain.TaskListOptions{SortBy: Domain.SortByTitle}},
+		{"not JSON", "bm90IGpzb24", Domain.TaskListOptions{SortBy: Domain.SortByTitle}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeTaskCursor(tt.token, tt.opts); err != Domain.ErrInvalidCursor {
+				t.Errorf("decodeTaskCursor = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestNewTaskPage(t *testing.T) {
+	tasks := []Domain.Task{{ID: testID(1)}, {ID: testID(2)}, {ID: testID(3)}}
+	opts := Domain.TaskListOptions{SortBy: Domain.SortByCreatedAt}
+
+	tests := []struct {
+		name       string
+		limit      int
+		wantTasks  int
+		wantCursor bool
+	}{
+		{"look-ahead task is trimmed", 2, 2, true},
+		{"last page", 3, 3, false},
+		{"fewer than a page", 5, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts.Limit = tt.limit
+			page := newTaskPage(tasks, 10, opts)
+			if len(page.Tasks) != tt.wantTasks || (page.NextCursor != "") != tt.wantCursor || page.Total != 10 {
+				t.Fatalf("page has %d tasks, cursor %q and total %d; want %d tasks, cursor %v and total 10",
+					len(page.Tasks), page.NextCursor, page.Total, tt.wantTasks, tt.wantCursor)
+			}
+			if !tt.wantCursor {
+				return
+			}
+			cursor, err := decodeTaskCursor(page.NextCursor, opts)
+			if err != nil {
+				t.Fatalf("decodeTaskCursor: %v", err)
+			}
+			if cursor.ID != page.Tasks[len(page.Tasks)-1].ID {
+				t.Errorf("cursor is at %s, want the last task on the page", cursor.ID.Hex())
+			}
+		})
+	}
+}
+
+// TestGetAllPagesThroughTies lists tasks a page at a time and checks that
+// every task shows up exactly once and in order, even where many tasks
+// share a title or creation time
+func TestGetAllPagesThroughTies(t *testing.T) {
+	repo := NewInMemoryTaskRepository()
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	titles := []string{"b", "a", "b", "c", "b", "a", "b", "b", "c", "a"}
+	for i, title := range titles {
+		task := &Domain.Task{
+			ID:        testID(byte(len(titles) - i)),
+			Title:     title,
+			CreatedAt: base.Add(time.Duration(i/3) * time.Minute),
+			UpdatedAt: base,
+		}
+		if err := repo.Create(context.Background(), task); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	for _, sortBy := range []Domain.TaskSortField{Domain.SortByTitle, Domain.SortByCreatedAt, Domain.SortByUpdatedAt} {
+		for _, desc := range []bool{false, true} {
+			for _, limit := range []int{1, 2, 3, 4} {
+				opts := Domain.TaskListOptions{SortBy: sortBy, SortDesc: desc, Limit: limit}
+
+				var got []Domain.Task
+				for pages := 0; ; pages++ {
+					if pages > len(titles) {
+						t.Fatalf("%s desc=%v limit=%d: paging does not end", sortBy, desc, limit)
+					}
+					page, err := repo.GetAll(context.Background(), Domain.TaskScope{AllTasks: true}, opts)
+					if err != nil {
+						t.Fatalf("%s desc=%v limit=%d: GetAll: %v", sortBy, desc, limit, err)
+					}
+					got = append(got, page.Tasks...)
+					if page.NextCursor == "" {
+						break
+					}
+					opts.Cursor = page.NextCursor
+				}
+
+				if len(got) != len(titles) {
+					t.Fatalf("%s desc=%v limit=%d: got %d tasks, want %d", sortBy, desc, limit, len(got), len(titles))
+				}
+				sorted := slices.IsSortedFunc(got, func(a, b Domain.Task) int {
+					if desc {
+						return compareTasks(b, a, sortBy)
+					}
+					return compareTasks(a, b, sortBy)
+				})
+				if !sorted {
+					t.Errorf("%s desc=%v limit=%d: tasks are out of order", sortBy, desc, limit)
+				}
+				ids := make(map[primitive.ObjectID]bool)
+				for _, task := range got {
+					ids[task.ID] = true
+				}
+				if len(ids) != len(titles) {
+					t.Errorf("%s desc=%v limit=%d: %d distinct tasks, want %d", sortBy, desc, limit, len(ids), len(titles))
+				}
+			}
+		}
+	}
+}
@@ -7,34 +7,81 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"taskmanager/auth/Domain"
 )
 
 type TaskRepository struct {
 	collection *mongo.Collection
-	ctx        context.Context
+	timeout    time.Duration
 }
 
-func NewTaskRepository(collection *mongo.Collection, ctx context.Context) *TaskRepository {
+func NewTaskRepository(collection *mongo.Collection, timeout time.Duration) *TaskRepository {
 	return &TaskRepository{
 		collection: collection,
-		ctx:        ctx,
+		timeout:    timeout,
 	}
 }
 
-func (r *TaskRepository) GetByID(id primitive.ObjectID, userID primitive.ObjectID, isAdmin bool) (*Domain.Task, error) {
-	var task Domain.Task
-	var filter bson.M
+func (r *TaskRepository) Initialize(ctx context.Context) error {
+	// Create indexes backing the per-user task listings
+	indexModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "updated_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "title", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "collaborators.user_id", Value: 1}}},
+		// Due date, priority and tag filters
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "due_at", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "priority", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "tags", Value: 1}}},
+		// Subtask and dependency lookups
+		{Keys: bson.D{{Key: "parent_id", Value: 1}}},
+		{Keys: bson.D{{Key: "blocked_by", Value: 1}}},
+		// One task per position in a recurring series, so occurrences are
+		// created once even when the scheduler runs more than once
+		{
+			Keys: bson.D{{Key: "series_id", Value: 1}, {Key: "occurrence", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"series_id": bson.M{"$exists": true}}),
+		},
+		{
+			Keys:    bson.D{{Key: "recurred", Value: 1}, {Key: "due_at", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"recurrence": bson.M{"$exists": true}}),
+		},
+		// Full-text search, without stemming or stop words so that it
+		// matches words like the in-memory index does
+		{
+			Keys: bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().SetName("task_text").
+				SetWeights(bson.D{{Key: "title", Value: 3}, {Key: "description", Value: 1}}).
+				SetDefaultLanguage("none"),
+		},
+	}
 
-	// If user is not admin, only return tasks belonging to the user
-	if isAdmin {
-		filter = bson.M{"_id": id}
-	} else {
-		filter = bson.M{"_id": id, "user_id": userID}
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexModels); err != nil {
+		return err
 	}
 
-	err := r.collection.FindOne(r.ctx, filter).Decode(&task)
+	// Tasks stored before revisions were tracked start at revision 1, since
+	// revision 0 means a write has no If-Match condition
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"revision": bson.M{"$in": bson.A{nil, 0}}},
+		bson.M{"$set": bson.M{"revision": 1}},
+	)
+	return err
+}
+
+func (r *TaskRepository) GetByID(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope) (*Domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var task Domain.Task
+
+	filter := scopeFilter(scope)
+	filter["_id"] = id
+
+	err := r.collection.FindOne(ctx, filter).Decode(&task)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, Domain.ErrNotFound
@@ -45,44 +92,105 @@ func (r *TaskRepository) GetByID(id primitive.ObjectID, userID primitive.ObjectI
 	return &task, nil
 }
 
-func (r *TaskRepository) GetAll(userID primitive.ObjectID, isAdmin bool) ([]Domain.Task, error) {
-	var tasks []Domain.Task
+func (r *TaskRepository) GetAll(ctx context.Context, scope Domain.TaskScope, opts Domain.TaskListOptions) (*Domain.TaskPage, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
 
-	var filter bson.M
-	// If user is not admin, only return tasks belonging to the user
-	if isAdmin {
-		filter = bson.M{}
-	} else {
-		filter = bson.M{"user_id": userID}
+	opts = opts.WithDefaults()
+
+	filter := taskFilterQuery(opts.Filter)
+	for key, value := range scopeFilter(scope) {
+		filter[key] = value
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	cursor, err := r.collection.Find(r.ctx, filter)
+	if opts.Cursor != "" {
+		cursor, err := decodeTaskCursor(opts.Cursor, opts)
+		if err != nil {
+			return nil, err
+		}
+		filter = bson.M{"$and": bson.A{filter, cursorQuery(cursor)}}
+	}
+
+	direction := 1
+	if opts.SortDesc {
+		direction = -1
+	}
+
+	// Fetch one extra task to find out whether there is a next page
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: string(opts.SortBy), Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(opts.Limit + 1))
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(r.ctx)
+	defer cursor.Close(ctx)
 
-	if err = cursor.All(r.ctx, &tasks); err != nil {
+	tasks := []Domain.Task{}
+	if err = cursor.All(ctx, &tasks); err != nil {
 		return nil, err
 	}
 
-	return tasks, nil
+	return newTaskPage(tasks, total, opts), nil
 }
 
-func (r *TaskRepository) Create(task *Domain.Task) error {
-	_, err := r.collection.InsertOne(r.ctx, task)
-	return err
-}
+func (r *TaskRepository) Search(ctx context.Context, scope Domain.TaskScope, query Domain.SearchQuery, limit int) ([]Domain.TaskSearchHit, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
 
-func (r *TaskRepository) Update(id primitive.ObjectID, userID primitive.ObjectID, isAdmin bool, updates map[string]interface{}) (*Domain.Task, error) {
-	var filter bson.M
+	filter := textSearchQuery(query)
+	for key, value := range scopeFilter(scope) {
+		filter[key] = value
+	}
 
-	// If user is not admin, only update tasks belonging to the user
-	if isAdmin {
-		filter = bson.M{"_id": id}
+	findOptions := options.Find().SetLimit(int64(limit))
+	if _, ok := filter["$text"]; ok {
+		findOptions.
+			SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+			SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "updated_at", Value: -1}})
 	} else {
-		filter = bson.M{"_id": id, "user_id": userID}
+		// Prefix-only searches have no text score to rank by
+		findOptions.SetSort(bson.D{{Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}})
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
 	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Domain.Task `bson:",inline"`
+		Score       float64 `bson:"score"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	hits := make([]Domain.TaskSearchHit, len(results))
+	for i, result := range results {
+		hits[i] = Domain.TaskSearchHit{Task: result.Task, Score: result.Score}
+	}
+	return hits, nil
+}
+
+func (r *TaskRepository) Create(ctx context.Context, task *Domain.Task) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, task)
+	return err
+}
+
+func (r *TaskRepository) Update(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope, updates map[string]interface{}) (*Domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
 
 	// Set updatedAt time
 	updates["updated_at"] = time.Now()
@@ -91,43 +199,238 @@ func (r *TaskRepository) Update(id primitive.ObjectID, userID primitive.ObjectID
 		"$set": updates,
 	}
 
-	result, err := r.collection.UpdateOne(r.ctx, filter, update)
+	return r.modify(ctx, id, scope, update)
+}
+
+func (r *TaskRepository) Delete(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope) (*Domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{"deleted_at": now, "updated_at": now},
+	}
+
+	return r.modify(ctx, id, scope, update)
+}
+
+func (r *TaskRepository) Undelete(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope) (*Domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	scope.Deleted = true
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	}
+
+	return r.modify(ctx, id, scope, update)
+}
+
+func (r *TaskRepository) SetCollaborator(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope, collaborator Domain.Collaborator) (*Domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	filter := scopeFilter(scope)
+	filter["_id"] = id
+
+	// Update the level if the user is already a collaborator...
+	existing := bson.M{"collaborators.user_id": collaborator.UserID}
+	for key, value := range filter {
+		existing[key] = value
+	}
+	result, err := r.collection.UpdateOne(ctx, existing, bson.M{
+		"$set": bson.M{"collaborators.$.level": collaborator.Level, "updated_at": time.Now()},
+		"$inc": bson.M{"revision": 1},
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	// ...otherwise add them
+	if result.MatchedCount == 0 {
+		missing := bson.M{"collaborators.user_id": bson.M{"$ne": collaborator.UserID}}
+		for key, value := range filter {
+			missing[key] = value
+		}
+		result, err = r.collection.UpdateOne(ctx, missing, bson.M{
+			"$push": bson.M{"collaborators": collaborator},
+			"$set":  bson.M{"updated_at": time.Now()},
+			"$inc":  bson.M{"revision": 1},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if result.MatchedCount == 0 {
 		return nil, Domain.ErrNotFound
 	}
 
-	// Get the updated task
-	var updatedTask Domain.Task
-	err = r.collection.FindOne(r.ctx, bson.M{"_id": id}).Decode(&updatedTask)
+	return r.findByID(ctx, id)
+}
+
+func (r *TaskRepository) RemoveCollaborator(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope, userID primitive.ObjectID) (*Domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	update := bson.M{
+		"$pull": bson.M{"collaborators": bson.M{"user_id": userID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	return r.modify(ctx, id, scope, update)
+}
+
+// modify applies an update to a task in scope, bumps its revision and
+// returns the updated task. If scope.Revision is set, the task must still be
+// at that revision.
+func (r *TaskRepository) modify(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope, update bson.M) (*Domain.Task, error) {
+	filter := scopeFilter(scope)
+	filter["_id"] = id
+	if scope.Revision != 0 {
+		// Compare and set: only a task nobody changed in between matches
+		filter["revision"] = scope.Revision
+	}
+
+	update["$inc"] = bson.M{"revision": 1}
+
+	var task Domain.Task
+	err := r.collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&task)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, r.missingTaskError(ctx, id, scope)
+		}
 		return nil, err
 	}
 
-	return &updatedTask, nil
+	return &task, nil
 }
 
-func (r *TaskRepository) Delete(id primitive.ObjectID, userID primitive.ObjectID, isAdmin bool) error {
-	var filter bson.M
+// missingTaskError tells why a conditional write matched no task: either
+// the task is gone or out of scope, or it is at another revision
+func (r *TaskRepository) missingTaskError(ctx context.Context, id primitive.ObjectID, scope Domain.TaskScope) error {
+	if scope.Revision == 0 {
+		return Domain.ErrNotFound
+	}
 
-	// If user is not admin, only delete tasks belonging to the user
-	if isAdmin {
-		filter = bson.M{"_id": id}
-	} else {
-		filter = bson.M{"_id": id, "user_id": userID}
+	filter := scopeFilter(scope)
+	filter["_id"] = id
+	count, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return Domain.ErrRevisionMismatch
 	}
+	return Domain.ErrNotFound
+}
+
+func (r *TaskRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID, scope Domain.TaskScope) ([]Domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.findInScope(ctx, "_id", ids, scope)
+}
+
+func (r *TaskRepository) GetSubtasks(ctx context.Context, parentIDs []primitive.ObjectID, scope Domain.TaskScope) ([]Domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.findInScope(ctx, "parent_id", parentIDs, scope)
+}
+
+func (r *TaskRepository) GetDependents(ctx context.Context, ids []primitive.ObjectID, scope Domain.TaskScope) ([]Domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.findInScope(ctx, "blocked_by", ids, scope)
+}
+
+func (r *TaskRepository) CreateOccurrence(ctx context.Context, task *Domain.Task) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, task)
+	if mongo.IsDuplicateKeyError(err) {
+		return Domain.ErrOccurrenceExists
+	}
+	return err
+}
 
-	result, err := r.collection.DeleteOne(r.ctx, filter)
+func (r *TaskRepository) GetRecurrenceDue(ctx context.Context, now time.Time, limit int) ([]Domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	filter := bson.M{
+		"deleted_at": nil,
+		"recurrence": bson.M{"$exists": true, "$ne": ""},
+		"recurred":   bson.M{"$ne": true},
+		"$or": bson.A{
+			bson.M{"completed": true},
+			bson.M{"due_at": bson.M{"$lte": now}},
+		},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "due_at", Value: 1}}).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tasks := []Domain.Task{}
+	if err = cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (r *TaskRepository) MarkRecurred(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"recurred": true}})
 	if err != nil {
 		return err
 	}
 
-	if result.DeletedCount == 0 {
+	if result.MatchedCount == 0 {
 		return Domain.ErrNotFound
 	}
 
 	return nil
 }
+
+// findInScope returns the tasks in scope whose field matches one of ids
+func (r *TaskRepository) findInScope(ctx context.Context, field string, ids []primitive.ObjectID, scope Domain.TaskScope) ([]Domain.Task, error) {
+	tasks := []Domain.Task{}
+	if len(ids) == 0 {
+		return tasks, nil
+	}
+
+	filter := scopeFilter(scope)
+	filter[field] = bson.M{"$in": ids}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (r *TaskRepository) findByID(ctx context.Context, id primitive.ObjectID) (*Domain.Task, error) {
+	var task Domain.Task
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&task)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
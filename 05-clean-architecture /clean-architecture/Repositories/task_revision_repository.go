@@ -0,0 +1,78 @@
+package Repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"taskmanager/auth/Domain"
+)
+
+type TaskRevisionRepository struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+func NewTaskRevisionRepository(collection *mongo.Collection, timeout time.Duration) *TaskRevisionRepository {
+	return &TaskRevisionRepository{
+		collection: collection,
+		timeout:    timeout,
+	}
+}
+
+func (r *TaskRevisionRepository) Initialize(ctx context.Context) error {
+	// One snapshot per task revision
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "task_id", Value: 1}, {Key: "revision", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := r.collection.Indexes().CreateOne(ctx, indexModel)
+	return err
+}
+
+func (r *TaskRevisionRepository) Append(ctx context.Context, revision *Domain.TaskRevision) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, revision)
+	return err
+}
+
+func (r *TaskRevisionRepository) List(ctx context.Context, taskID primitive.ObjectID) ([]Domain.TaskRevision, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "revision", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"task_id": taskID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []Domain.TaskRevision{}
+	if err = cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (r *TaskRevisionRepository) Get(ctx context.Context, taskID primitive.ObjectID, revision int) (*Domain.TaskRevision, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var rev Domain.TaskRevision
+	err := r.collection.FindOne(ctx, bson.M{"task_id": taskID, "revision": revision}).Decode(&rev)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, Domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &rev, nil
+}
@@ -0,0 +1,103 @@
+package Repositories
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// testTaskRepositories returns an empty task repository of each backend
+// the tests can reach
+func testTaskRepositories(t *testing.T) map[string]Domain.TaskRepository {
+	t.Helper()
+
+	repos := map[string]Domain.TaskRepository{"memory": NewInMemoryTaskRepository()}
+	if database := testMongoDatabase(t); database != nil {
+		mongoRepo := NewTaskRepository(database.Collection("tasks"), 10*time.Second)
+		if err := mongoRepo.Initialize(context.Background()); err != nil {
+			t.Fatalf("Initialize: %v", err)
+		}
+		repos["mongo"] = mongoRepo
+	}
+	return repos
+}
+
+// TestSearchMatchesAcrossBackends checks that both backends find the same
+// tasks for a query. Only the matches are compared; scores differ.
+func TestSearchMatchesAcrossBackends(t *testing.T) {
+	tasks := []Domain.Task{
+		{Title: "Write release notes", Description: "For the next deploy"},
+		{Title: "Release the app", Description: "Notes are done"},
+		{Title: "Plan the migration", Description: "Migrate the users table"},
+		{Title: "Prerelease notes", Description: ""},
+		{Title: "Café menu", Description: "release, notes and e-mail"},
+		{Title: "Remigrate data", Description: "deploy later"},
+	}
+
+	tests := []struct {
+		query string
+		// want are the indexes in tasks of the matching tasks
+		want []int
+	}{
+		{"release", []int{0, 1, 4}},
+		{"release deploy", []int{0, 1, 4, 5}},
+		{"RELEASE", []int{0, 1, 4}},
+		{`"release notes"`, []int{0, 4}},
+		{`"notes release"`, nil},
+		{`"release notes" deploy`, []int{0, 4}},
+		{"migr*", []int{2}},
+		{"migr* deploy", []int{2}},
+		{"migr* users", []int{2}},
+		{`migr* "release notes"`, nil},
+		{"e-mail", []int{4}},
+		{"cafe", nil},
+		{"café", []int{4}},
+		{"pre*", []int{3}},
+		{"missing", nil},
+	}
+
+	for name, repo := range testTaskRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			userID := primitive.NewObjectID()
+			ids := make([]primitive.ObjectID, len(tasks))
+			for i, task := range tasks {
+				task.ID = primitive.NewObjectID()
+				task.UserID = userID
+				task.Revision = 1
+				if err := repo.Create(ctx, &task); err != nil {
+					t.Fatalf("Create: %v", err)
+				}
+				ids[i] = task.ID
+			}
+			scope := Domain.TaskScope{UserID: userID, Access: Domain.TaskAccessRead}
+
+			for _, tt := range tests {
+				t.Run(tt.query, func(t *testing.T) {
+					query, err := Domain.ParseSearchQuery(tt.query)
+					if err != nil {
+						t.Fatalf("ParseSearchQuery: %v", err)
+					}
+					hits, err := repo.Search(ctx, scope, query, Domain.MaxSearchLimit)
+					if err != nil {
+						t.Fatalf("Search: %v", err)
+					}
+
+					var got []int
+					for _, hit := range hits {
+						got = append(got, slices.Index(ids, hit.Task.ID))
+					}
+					slices.Sort(got)
+					if !slices.Equal(got, tt.want) {
+						t.Errorf("Search matched tasks %v, want %v", got, tt.want)
+					}
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package Repositories
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"taskmanager/auth/Domain"
+)
+
+// illegalOperationCode is the MongoDB error code for commands the server
+// cannot run in its configuration
+const illegalOperationCode = 20
+
+// TaskTransactor runs task changes in a MongoDB transaction. Transactions
+// need a replica set or sharded cluster; on a standalone server they fail
+// with Domain.ErrNoTransactions.
+type TaskTransactor struct {
+	client    *mongo.Client
+	tasks     *TaskRepository
+	revisions *TaskRevisionRepository
+	audit     *AuditRepository
+}
+
+func NewTaskTransactor(client *mongo.Client, tasks *TaskRepository, revisions *TaskRevisionRepository, audit *AuditRepository) *TaskTransactor {
+	return &TaskTransactor{
+		client:    client,
+		tasks:     tasks,
+		revisions: revisions,
+		audit:     audit,
+	}
+}
+
+// WithinTransaction runs fn in a transaction. The context passed to fn
+// carries the session, so the repositories write inside the transaction
+// when called with it. The driver retries fn on transient errors, so fn
+// may run more than once.
+func (t *TaskTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repos Domain.TaskRepositories) error) error {
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	// Ending the session must not depend on the caller still waiting
+	defer session.EndSession(context.WithoutCancel(ctx))
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc, Domain.TaskRepositories{
+			Tasks:     t.tasks,
+			Revisions: t.revisions,
+			Audit:     t.audit,
+		})
+	})
+	if isNoTransactions(err) {
+		return Domain.ErrNoTransactions.Wrap(err)
+	}
+	return err
+}
+
+// isNoTransactions reports whether err is a standalone server refusing to
+// start a transaction
+func isNoTransactions(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == illegalOperationCode &&
+		strings.Contains(cmdErr.Message, "Transaction numbers are only allowed")
+}
@@ -0,0 +1,13 @@
+package Repositories
+
+import (
+	"context"
+	"time"
+)
+
+// withTimeout bounds a MongoDB operation by the repository's timeout, on
+// top of any deadline the caller's context already has, so a stuck query
+// cannot hold a request or a background job forever
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, timeout)
+}
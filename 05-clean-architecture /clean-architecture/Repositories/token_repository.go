@@ -0,0 +1,124 @@
+package Repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"taskmanager/auth/Domain"
+)
+
+type TokenRepository struct {
+	refreshCollection *mongo.Collection
+	revokedCollection *mongo.Collection
+	timeout           time.Duration
+}
+
+func NewTokenRepository(refreshCollection, revokedCollection *mongo.Collection, timeout time.Duration) *TokenRepository {
+	return &TokenRepository{
+		refreshCollection: refreshCollection,
+		revokedCollection: revokedCollection,
+		timeout:           timeout,
+	}
+}
+
+func (r *TokenRepository) Initialize(ctx context.Context) error {
+	// Unique lookup by hash, family lookup for revocation, and TTL indexes so
+	// expired tokens are removed by Mongo
+	refreshIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}
+	if _, err := r.refreshCollection.Indexes().CreateMany(ctx, refreshIndexes); err != nil {
+		return err
+	}
+
+	revokedIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	_, err := r.revokedCollection.Indexes().CreateOne(ctx, revokedIndex)
+	return err
+}
+
+func (r *TokenRepository) CreateRefreshToken(ctx context.Context, token *Domain.RefreshToken) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	_, err := r.refreshCollection.InsertOne(ctx, token)
+	return err
+}
+
+func (r *TokenRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*Domain.RefreshToken, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var token Domain.RefreshToken
+	err := r.refreshCollection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, Domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *TokenRepository) MarkRefreshTokenUsed(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	// Only an unused, unrevoked token can be consumed
+	filter := bson.M{"_id": id, "used_at": nil, "revoked_at": nil}
+	update := bson.M{"$set": bson.M{"used_at": time.Now()}}
+
+	result, err := r.refreshCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return Domain.ErrTokenReused
+	}
+
+	return nil
+}
+
+func (r *TokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	filter := bson.M{"family_id": familyID, "revoked_at": nil}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+
+	_, err := r.refreshCollection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+func (r *TokenRepository) RevokeTokenID(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	_, err := r.revokedCollection.InsertOne(ctx, bson.M{"_id": tokenID, "expires_at": expiresAt})
+	if mongo.IsDuplicateKeyError(err) {
+		// Already revoked
+		return nil
+	}
+	return err
+}
+
+func (r *TokenRepository) IsTokenIDRevoked(ctx context.Context, tokenID string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	count, err := r.revokedCollection.CountDocuments(ctx, bson.M{"_id": tokenID}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
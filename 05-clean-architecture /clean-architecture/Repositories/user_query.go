@@ -0,0 +1,79 @@
+package Repositories
+
+import (
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// User lists and the audit log are ordered by ID, so their cursor is simply
+// the ID of the last item on the page
+func decodeIDCursor(token string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(token)
+	if err != nil {
+		return primitive.NilObjectID, Domain.ErrInvalidCursor
+	}
+	return id, nil
+}
+
+// newUserPage trims the extra look-ahead user off a page and derives the
+// cursor for the next one
+func newUserPage(users []Domain.User, total int64, opts Domain.UserListOptions) *Domain.UserPage {
+	page := &Domain.UserPage{Users: users, Total: total}
+
+	if len(users) > opts.Limit {
+		page.Users = users[:opts.Limit]
+		page.NextCursor = page.Users[opts.Limit-1].ID.Hex()
+	}
+
+	return page
+}
+
+// userFilterQuery translates a UserFilter into a Mongo query
+func userFilterQuery(filter Domain.UserFilter) bson.M {
+	query := bson.M{}
+
+	if filter.UsernameContains != "" {
+		query["username"] = primitive.Regex{
+			Pattern: regexp.QuoteMeta(filter.UsernameContains),
+			Options: "i",
+		}
+	}
+
+	if filter.Role != "" {
+		query["role"] = filter.Role
+	}
+
+	if filter.Disabled != nil {
+		if *filter.Disabled {
+			query["disabled"] = true
+		} else {
+			// Users created before accounts could be disabled have no field
+			query["disabled"] = bson.M{"$ne": true}
+		}
+	}
+
+	return query
+}
+
+// matchesUserFilter is the in-memory equivalent of userFilterQuery
+func matchesUserFilter(user Domain.User, filter Domain.UserFilter) bool {
+	if filter.UsernameContains != "" &&
+		!strings.Contains(strings.ToLower(user.Username), strings.ToLower(filter.UsernameContains)) {
+		return false
+	}
+
+	if filter.Role != "" && user.Role != filter.Role {
+		return false
+	}
+
+	if filter.Disabled != nil && user.Disabled != *filter.Disabled {
+		return false
+	}
+
+	return true
+}
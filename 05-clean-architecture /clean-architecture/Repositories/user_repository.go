@@ -14,38 +14,44 @@ import (
 
 type UserRepository struct {
 	collection *mongo.Collection
-	ctx        context.Context
+	timeout    time.Duration
 }
 
-func NewUserRepository(collection *mongo.Collection, ctx context.Context) *UserRepository {
+func NewUserRepository(collection *mongo.Collection, timeout time.Duration) *UserRepository {
 	return &UserRepository{
 		collection: collection,
-		ctx:        ctx,
+		timeout:    timeout,
 	}
 }
 
-func (r *UserRepository) Initialize() error {
+func (r *UserRepository) Initialize(ctx context.Context) error {
 	// Create unique index for username
 	indexModel := mongo.IndexModel{
 		Keys:    bson.D{{Key: "username", Value: 1}},
 		Options: options.Index().SetUnique(true),
 	}
 
-	_, err := r.collection.Indexes().CreateOne(r.ctx, indexModel)
+	_, err := r.collection.Indexes().CreateOne(ctx, indexModel)
 	return err
 }
 
-func (r *UserRepository) Create(user *Domain.User) error {
-	_, err := r.collection.InsertOne(r.ctx, user)
+func (r *UserRepository) Create(ctx context.Context, user *Domain.User) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, user)
 	if mongo.IsDuplicateKeyError(err) {
 		return Domain.ErrUsernameTaken
 	}
 	return err
 }
 
-func (r *UserRepository) GetByID(id primitive.ObjectID) (*Domain.User, error) {
+func (r *UserRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*Domain.User, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
 	var user Domain.User
-	err := r.collection.FindOne(r.ctx, bson.M{"_id": id}).Decode(&user)
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, Domain.ErrNotFound
@@ -55,9 +61,12 @@ func (r *UserRepository) GetByID(id primitive.ObjectID) (*Domain.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) GetByUsername(username string) (*Domain.User, error) {
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*Domain.User, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
 	var user Domain.User
-	err := r.collection.FindOne(r.ctx, bson.M{"username": username}).Decode(&user)
+	err := r.collection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, Domain.ErrNotFound
@@ -67,12 +76,15 @@ func (r *UserRepository) GetByUsername(username string) (*Domain.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) UpdateLastLogin(id primitive.ObjectID) error {
+func (r *UserRepository) UpdateLastLogin(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
 	update := bson.M{
 		"$set": bson.M{"last_login_at": time.Now()},
 	}
 
-	result, err := r.collection.UpdateOne(r.ctx, bson.M{"_id": id}, update)
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
 	if err != nil {
 		return err
 	}
@@ -82,4 +94,112 @@ func (r *UserRepository) UpdateLastLogin(id primitive.ObjectID) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+func (r *UserRepository) List(ctx context.Context, opts Domain.UserListOptions) (*Domain.UserPage, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	opts = opts.WithDefaults()
+
+	filter := userFilterQuery(opts.Filter)
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Cursor != "" {
+		after, err := decodeIDCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$gt": after}}}}
+	}
+
+	// Fetch one extra user to find out whether there is a next page
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(opts.Limit + 1))
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []Domain.User{}
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return newUserPage(users, total, opts), nil
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, id primitive.ObjectID, grant Domain.RoleGrant) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	update := bson.M{
+		"$set":  bson.M{"role": grant.Role},
+		"$push": bson.M{"role_grants": grant},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return Domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) CountByRole(ctx context.Context, role Domain.Role) (int64, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, bson.M{"role": role})
+}
+
+func (r *UserRepository) SetDisabled(ctx context.Context, id primitive.ObjectID, disabled bool) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.setField(ctx, id, "disabled", disabled)
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return Domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) setField(ctx context.Context, id primitive.ObjectID, field string, value interface{}) error {
+	update := bson.M{
+		"$set": bson.M{field: value},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return Domain.ErrNotFound
+	}
+
+	return nil
+}
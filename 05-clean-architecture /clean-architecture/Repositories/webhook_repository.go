@@ -0,0 +1,250 @@
+package Repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"taskmanager/auth/Domain"
+)
+
+// webhookDeliveryRetention is how long the delivery log is kept
+const webhookDeliveryRetention = 30 * 24 * time.Hour
+
+type WebhookRepository struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+func NewWebhookRepository(collection *mongo.Collection, timeout time.Duration) *WebhookRepository {
+	return &WebhookRepository{
+		collection: collection,
+		timeout:    timeout,
+	}
+}
+
+func (r *WebhookRepository) Initialize(ctx context.Context) error {
+	// Webhooks are looked up by the users who see an event
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: 1}},
+	})
+	return err
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, webhook *Domain.Webhook) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if webhook.ID.IsZero() {
+		webhook.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, webhook)
+	return err
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*Domain.Webhook, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var webhook Domain.Webhook
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&webhook)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, Domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) ListByUsers(ctx context.Context, userIDs []primitive.ObjectID) ([]Domain.Webhook, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": bson.M{"$in": userIDs}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []Domain.Webhook{}
+	if err = cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return Domain.ErrNotFound
+	}
+	return nil
+}
+
+type WebhookDeliveryRepository struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+func NewWebhookDeliveryRepository(collection *mongo.Collection, timeout time.Duration) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{
+		collection: collection,
+		timeout:    timeout,
+	}
+}
+
+func (r *WebhookDeliveryRepository) Initialize(ctx context.Context) error {
+	indexModels := []mongo.IndexModel{
+		// Due deliveries for the dispatcher
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		// Delivery logs, newest first
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}, {Key: "_id", Value: -1}}},
+		// Expire old deliveries, long after their last retry
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(webhookDeliveryRetention.Seconds())),
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexModels)
+	return err
+}
+
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, delivery *Domain.WebhookDelivery) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if delivery.ID.IsZero() {
+		delivery.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, delivery)
+	return err
+}
+
+func (r *WebhookDeliveryRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*Domain.WebhookDelivery, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var delivery Domain.WebhookDelivery
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, Domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *WebhookDeliveryRepository) List(ctx context.Context, filter Domain.WebhookDeliveryFilter, limit int) ([]Domain.WebhookDelivery, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := bson.M{}
+	if !filter.UserID.IsZero() {
+		query["user_id"] = filter.UserID
+	}
+	if filter.WebhookID != nil {
+		query["webhook_id"] = *filter.WebhookID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, query, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []Domain.WebhookDelivery{}
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookDeliveryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Domain.WebhookDelivery, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	filter := bson.M{
+		"status":          Domain.WebhookDeliveryPending,
+		"next_attempt_at": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"locked_until": bson.M{"$exists": false}},
+			bson.M{"locked_until": bson.M{"$lte": now}},
+		},
+	}
+	updateOptions := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	// Claimed one at a time, so that instances never claim the same one
+	claimed := []Domain.WebhookDelivery{}
+	for len(claimed) < limit {
+		update := bson.M{"$set": bson.M{"locked_until": now.Add(lease), "lease_id": primitive.NewObjectID()}}
+
+		var delivery Domain.WebhookDelivery
+		err := r.collection.FindOneAndUpdate(ctx, filter, update, updateOptions).Decode(&delivery)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			return claimed, err
+		}
+		claimed = append(claimed, delivery)
+	}
+	return claimed, nil
+}
+
+// errLeaseLost is returned when saving a delivery that is gone or was
+// claimed again after its lease ran out
+var errLeaseLost = Domain.ErrNotFound.WithMessage("Webhook delivery not found or its lease has run out")
+
+func (r *WebhookDeliveryRepository) Update(ctx context.Context, delivery *Domain.WebhookDelivery) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	released := *delivery
+	released.LockedUntil = nil
+	released.LeaseID = primitive.NilObjectID
+
+	// A send that outlived its lease must not overwrite the outcome of the
+	// claim that took the delivery over
+	filter := bson.M{"_id": delivery.ID, "lease_id": delivery.LeaseID}
+	result, err := r.collection.ReplaceOne(ctx, filter, released)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errLeaseLost
+	}
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) DeleteByWebhook(ctx context.Context, webhookID primitive.ObjectID) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"webhook_id": webhookID})
+	return err
+}
@@ -0,0 +1,76 @@
+package Repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+func TestWebhookDeliveryLease(t *testing.T) {
+	repos := map[string]Domain.WebhookDeliveryRepository{"memory": NewInMemoryWebhookDeliveryRepository()}
+	if database := testMongoDatabase(t); database != nil {
+		mongoRepo := NewWebhookDeliveryRepository(database.Collection("webhook_deliveries"), 10*time.Second)
+		if err := mongoRepo.Initialize(context.Background()); err != nil {
+			t.Fatalf("Initialize: %v", err)
+		}
+		repos["mongo"] = mongoRepo
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now().UTC().Truncate(time.Millisecond)
+			delivery := &Domain.WebhookDelivery{
+				ID:            primitive.NewObjectID(),
+				WebhookID:     primitive.NewObjectID(),
+				Status:        Domain.WebhookDeliveryPending,
+				NextAttemptAt: &now,
+				CreatedAt:     now,
+			}
+			if err := repo.Create(ctx, delivery); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			const lease = time.Minute
+			slow, err := repo.ClaimDue(ctx, now, lease, 10)
+			if err != nil || len(slow) != 1 {
+				t.Fatalf("ClaimDue = %d deliveries, %v; want 1", len(slow), err)
+			}
+			if held, _ := repo.ClaimDue(ctx, now.Add(lease/2), lease, 10); len(held) != 0 {
+				t.Fatalf("ClaimDue during the lease = %d deliveries, want none", len(held))
+			}
+
+			// The first send outlives its lease and the delivery is claimed again
+			later := now.Add(2 * lease)
+			fast, err := repo.ClaimDue(ctx, later, lease, 10)
+			if err != nil || len(fast) != 1 {
+				t.Fatalf("ClaimDue after the lease = %d deliveries, %v; want 1", len(fast), err)
+			}
+
+			fast[0].Status = Domain.WebhookDeliverySucceeded
+			fast[0].NextAttemptAt = nil
+			if err := repo.Update(ctx, &fast[0]); err != nil {
+				t.Fatalf("Update by the current claim: %v", err)
+			}
+
+			slow[0].Status = Domain.WebhookDeliveryFailed
+			slow[0].NextAttemptAt = nil
+			if err := repo.Update(ctx, &slow[0]); !errors.Is(err, Domain.ErrNotFound) {
+				t.Fatalf("Update by the expired claim = %v, want ErrNotFound", err)
+			}
+
+			saved, err := repo.GetByID(ctx, delivery.ID)
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if saved.Status != Domain.WebhookDeliverySucceeded || saved.LockedUntil != nil {
+				t.Errorf("saved delivery is %s, locked until %v; want succeeded and unlocked", saved.Status, saved.LockedUntil)
+			}
+		})
+	}
+}
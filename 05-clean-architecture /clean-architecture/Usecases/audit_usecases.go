@@ -0,0 +1,89 @@
+package Usecases
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"time"
+
+	"taskmanager/auth/Domain"
+)
+
+// Fields left out of audit diffs because they change on every write
+var unauditedFields = map[string]bool{
+	"updated_at":    true,
+	"revision":      true,
+	"last_login_at": true,
+}
+
+type AuditUseCase struct {
+	auditRepo Domain.AuditRepository
+}
+
+func NewAuditUseCase(auditRepo Domain.AuditRepository) *AuditUseCase {
+	return &AuditUseCase{
+		auditRepo: auditRepo,
+	}
+}
+
+func (uc *AuditUseCase) ListEntries(ctx context.Context, opts Domain.AuditListOptions) (*Domain.AuditPage, error) {
+	if opts.Limit < 0 {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	return uc.auditRepo.List(ctx, opts.WithDefaults())
+}
+
+// recordAudit appends an entry to the audit log. The change it describes
+// has already happened, so the entry is written even if the request has
+// been canceled, and a failure to record it is logged rather than reported
+// to the caller.
+func recordAudit(ctx context.Context, auditRepo Domain.AuditRepository, entry Domain.AuditEntry) {
+	entry.CreatedAt = time.Now()
+	if err := auditRepo.Append(context.WithoutCancel(ctx), &entry); err != nil {
+		log.Printf("Failed to record audit entry %s: %v", entry.Action, err)
+	}
+}
+
+// auditChanges returns the fields that differ between two versions of an
+// entity, keyed by their JSON name. Either version may be nil, for created
+// and deleted entities. Fields hidden from JSON, such as password hashes,
+// never show up.
+func auditChanges(before, after interface{}) map[string]Domain.AuditChange {
+	beforeFields, afterFields := jsonFields(before), jsonFields(after)
+
+	changes := make(map[string]Domain.AuditChange)
+	for field, value := range afterFields {
+		if !unauditedFields[field] && !reflect.DeepEqual(beforeFields[field], value) {
+			changes[field] = Domain.AuditChange{Before: beforeFields[field], After: value}
+		}
+	}
+	for field, value := range beforeFields {
+		if _, ok := afterFields[field]; !ok && !unauditedFields[field] {
+			changes[field] = Domain.AuditChange{Before: value}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+func jsonFields(entity interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if entity == nil {
+		return fields
+	}
+	if v := reflect.ValueOf(entity); v.Kind() == reflect.Pointer && v.IsNil() {
+		return fields
+	}
+
+	raw, err := json.Marshal(entity)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(raw, &fields)
+	return fields
+}
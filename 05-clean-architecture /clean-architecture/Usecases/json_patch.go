@@ -0,0 +1,303 @@
+package Usecases
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"taskmanager/auth/Domain"
+)
+
+// jsonPatchOperation is one step of a JSON Patch (RFC 6902)
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyMergePatch applies a JSON Merge Patch (RFC 7396) to doc. Members set
+// to null are removed, objects are merged recursively and everything else,
+// arrays included, is replaced.
+func applyMergePatch(doc map[string]interface{}, patch []byte) (map[string]interface{}, error) {
+	var merge map[string]interface{}
+	if err := json.Unmarshal(patch, &merge); err != nil || merge == nil {
+		return nil, Domain.ErrInvalidPatch
+	}
+
+	return mergeObjects(doc, merge), nil
+}
+
+func mergeObjects(target, patch map[string]interface{}) map[string]interface{} {
+	if target == nil {
+		target = make(map[string]interface{})
+	}
+
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		if object, ok := value.(map[string]interface{}); ok {
+			existing, _ := target[key].(map[string]interface{})
+			target[key] = mergeObjects(existing, object)
+			continue
+		}
+		target[key] = value
+	}
+	return target
+}
+
+// applyJSONPatch applies a JSON Patch (RFC 6902) to doc. The operations run
+// in order and either all of them apply or the patch fails as a whole.
+// Malformed patches return ErrInvalidPatch; operations that do not fit the
+// document, such as a failed test, return ErrPatchConflict.
+func applyJSONPatch(doc map[string]interface{}, patch []byte) (map[string]interface{}, error) {
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, Domain.ErrInvalidPatch
+	}
+
+	var root interface{} = doc
+	for i, operation := range operations {
+		next, err := applyOperation(root, operation)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		root = next
+	}
+
+	result, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: the document must stay an object", Domain.ErrPatchConflict)
+	}
+	return result, nil
+}
+
+func applyOperation(root interface{}, operation jsonPatchOperation) (interface{}, error) {
+	if operation.Path == nil {
+		return nil, fmt.Errorf("%w: missing path", Domain.ErrInvalidPatch)
+	}
+	path, err := parsePointer(*operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, fmt.Errorf("%w: %s needs a value", Domain.ErrInvalidPatch, operation.Op)
+		}
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return nil, Domain.ErrInvalidPatch
+		}
+	case "move", "copy":
+		if operation.From == nil {
+			return nil, fmt.Errorf("%w: %s needs a from path", Domain.ErrInvalidPatch, operation.Op)
+		}
+		from, err := parsePointer(*operation.From)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = pointerGet(root, from); err != nil {
+			return nil, err
+		}
+		if operation.Op == "copy" {
+			value = cloneJSON(value)
+			break
+		}
+		// A value cannot be moved into itself
+		if len(from) < len(path) && reflect.DeepEqual(from, path[:len(from)]) {
+			return nil, fmt.Errorf("%w: cannot move %s into itself", Domain.ErrPatchConflict, *operation.From)
+		}
+		if root, err = pointerRemove(root, from); err != nil {
+			return nil, err
+		}
+	case "remove":
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", Domain.ErrInvalidPatch, operation.Op)
+	}
+
+	switch operation.Op {
+	case "remove":
+		return pointerRemove(root, path)
+	case "replace":
+		if root, err = pointerRemove(root, path); err != nil {
+			return nil, err
+		}
+		return pointerAdd(root, path, value)
+	case "test":
+		current, err := pointerGet(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, fmt.Errorf("%w: test failed at %s", Domain.ErrPatchConflict, *operation.Path)
+		}
+		return root, nil
+	default:
+		return pointerAdd(root, path, value)
+	}
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: invalid path %q", Domain.ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func pointerGet(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, missingPath(path)
+			}
+			node = value
+		case []interface{}:
+			i, err := arrayIndex(token, len(container)-1, path)
+			if err != nil {
+				return nil, err
+			}
+			node = container[i]
+		default:
+			return nil, missingPath(path)
+		}
+	}
+	return node, nil
+}
+
+func pointerAdd(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return pointerUpdate(root, path, func(container interface{}, token string) (interface{}, error) {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			container[token] = value
+			return container, nil
+		case []interface{}:
+			if token == "-" {
+				return append(container, value), nil
+			}
+			i, err := arrayIndex(token, len(container), path)
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[i+1:], container[i:])
+			container[i] = value
+			return container, nil
+		default:
+			return nil, missingPath(path)
+		}
+	})
+}
+
+func pointerRemove(root interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", Domain.ErrPatchConflict)
+	}
+
+	return pointerUpdate(root, path, func(container interface{}, token string) (interface{}, error) {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			if _, ok := container[token]; !ok {
+				return nil, missingPath(path)
+			}
+			delete(container, token)
+			return container, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(container)-1, path)
+			if err != nil {
+				return nil, err
+			}
+			return append(container[:i], container[i+1:]...), nil
+		default:
+			return nil, missingPath(path)
+		}
+	})
+}
+
+// pointerUpdate calls change on the container holding the last token of
+// path and stores the container it returns, since changing an array's
+// length replaces it
+func pointerUpdate(root interface{}, path []string, change func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	var update func(node interface{}, depth int) (interface{}, error)
+	update = func(node interface{}, depth int) (interface{}, error) {
+		if depth == len(path)-1 {
+			return change(node, path[depth])
+		}
+
+		switch container := node.(type) {
+		case map[string]interface{}:
+			child, ok := container[path[depth]]
+			if !ok {
+				return nil, missingPath(path)
+			}
+			updated, err := update(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			container[path[depth]] = updated
+			return container, nil
+		case []interface{}:
+			i, err := arrayIndex(path[depth], len(container)-1, path)
+			if err != nil {
+				return nil, err
+			}
+			updated, err := update(container[i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			container[i] = updated
+			return container, nil
+		default:
+			return nil, missingPath(path)
+		}
+	}
+
+	return update(root, 0)
+}
+
+// arrayIndex parses an array index token no greater than last
+func arrayIndex(token string, last int, path []string) (int, error) {
+	if token == "" || len(token) > 1 && token[0] == '0' {
+		return 0, missingPath(path)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > last {
+		return 0, missingPath(path)
+	}
+	return i, nil
+}
+
+func missingPath(path []string) error {
+	escaped := make([]string, len(path))
+	for i, token := range path {
+		escaped[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+	}
+	return fmt.Errorf("%w: no value at /%s", Domain.ErrPatchConflict, strings.Join(escaped, "/"))
+}
+
+func cloneJSON(value interface{}) interface{} {
+	data, _ := json.Marshal(value)
+	var clone interface{}
+	_ = json.Unmarshal(data, &clone)
+	return clone
+}
@@ -0,0 +1,120 @@
+package Usecases
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"taskmanager/auth/Domain"
+)
+
+// testDocument parses a JSON object for a patch test
+func testDocument(t *testing.T, doc string) map[string]interface{} {
+	t.Helper()
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(doc), &parsed); err != nil {
+		t.Fatalf("invalid test document %s: %v", doc, err)
+	}
+	return parsed
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	const doc = `{"title":"Report","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`
+
+	tests := []struct {
+		name    string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{"add a member", `[{"op":"add","path":"/priority","value":"high"}]`,
+			`{"title":"Report","priority":"high","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"add to the end of an array", `[{"op":"add","path":"/tags/-","value":"c"}]`,
+			`{"title":"Report","tags":["a","b","c"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"insert into an array", `[{"op":"add","path":"/tags/0","value":"z"}]`,
+			`{"title":"Report","tags":["z","a","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"remove an array item", `[{"op":"remove","path":"/tags/0"}]`,
+			`{"title":"Report","tags":["b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"replace a member", `[{"op":"replace","path":"/title","value":"Summary"}]`,
+			`{"title":"Summary","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"escaped pointers", `[{"op":"remove","path":"/meta/a~1b"},{"op":"replace","path":"/meta/m~0n","value":3}]`,
+			`{"title":"Report","tags":["a","b"],"meta":{"m~n":3}}`, nil},
+		{"move", `[{"op":"move","from":"/title","path":"/description"}]`,
+			`{"description":"Report","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"copy", `[{"op":"copy","from":"/tags/1","path":"/tags/-"}]`,
+			`{"title":"Report","tags":["a","b","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"test passes", `[{"op":"test","path":"/tags","value":["a","b"]},{"op":"replace","path":"/title","value":"Summary"}]`,
+			`{"title":"Summary","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"test of a nested value", `[{"op":"test","path":"/meta/a~1b","value":1}]`, doc, nil},
+		{"no operations", `[]`, doc, nil},
+
+		{"test fails", `[{"op":"test","path":"/title","value":"Other"},{"op":"replace","path":"/title","value":"Summary"}]`, "", Domain.ErrPatchConflict},
+		{"test compares types", `[{"op":"test","path":"/meta/a~1b","value":"1"}]`, "", Domain.ErrPatchConflict},
+		{"test of a missing value", `[{"op":"test","path":"/missing","value":null}]`, "", Domain.ErrPatchConflict},
+		{"remove a missing member", `[{"op":"remove","path":"/missing"}]`, "", Domain.ErrPatchConflict},
+		{"index past the end", `[{"op":"replace","path":"/tags/2","value":"c"}]`, "", Domain.ErrPatchConflict},
+		{"index with a leading zero", `[{"op":"remove","path":"/tags/01"}]`, "", Domain.ErrPatchConflict},
+		{"move into itself", `[{"op":"move","from":"/meta","path":"/meta/inner"}]`, "", Domain.ErrPatchConflict},
+		{"replace the document with a list", `[{"op":"replace","path":"","value":[]}]`, "", Domain.ErrPatchConflict},
+		{"unknown operation", `[{"op":"merge","path":"/title","value":"x"}]`, "", Domain.ErrInvalidPatch},
+		{"missing path", `[{"op":"remove"}]`, "", Domain.ErrInvalidPatch},
+		{"missing value", `[{"op":"add","path":"/title"}]`, "", Domain.ErrInvalidPatch},
+		{"path without a slash", `[{"op":"remove","path":"title"}]`, "", Domain.ErrInvalidPatch},
+		{"not a list of operations", `{"op":"remove","path":"/title"}`, "", Domain.ErrInvalidPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyJSONPatch(testDocument(t, doc), []byte(tt.patch))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("applyJSONPatch error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyJSONPatch: %v", err)
+			}
+			if want := testDocument(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("applyJSONPatch = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	const doc = `{"title":"Report","tags":["a","b"],"meta":{"a":1,"b":2}}`
+
+	tests := []struct {
+		name    string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{"replace a member", `{"title":"Summary"}`, `{"title":"Summary","tags":["a","b"],"meta":{"a":1,"b":2}}`, nil},
+		{"null removes", `{"title":null}`, `{"tags":["a","b"],"meta":{"a":1,"b":2}}`, nil},
+		{"arrays are replaced", `{"tags":["c"]}`, `{"title":"Report","tags":["c"],"meta":{"a":1,"b":2}}`, nil},
+		{"objects are merged", `{"meta":{"a":null,"c":3}}`, `{"title":"Report","tags":["a","b"],"meta":{"b":2,"c":3}}`, nil},
+		{"empty patch", `{}`, doc, nil},
+		{"not an object", `["title"]`, "", Domain.ErrInvalidPatch},
+		{"null", `null`, "", Domain.ErrInvalidPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyMergePatch(testDocument(t, doc), []byte(tt.patch))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("applyMergePatch error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyMergePatch: %v", err)
+			}
+			if want := testDocument(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("applyMergePatch = %v, want %v", got, want)
+			}
+		})
+	}
+}
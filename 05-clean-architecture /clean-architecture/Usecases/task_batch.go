@@ -0,0 +1,96 @@
+package Usecases
+
+import (
+	"context"
+	"errors"
+
+	"taskmanager/auth/Domain"
+)
+
+// errBatchFailed rolls back an atomic batch
+var errBatchFailed = errors.New("batch operation failed")
+
+// RunBatch applies a list of task operations for the policy's user. Each
+// operation goes through CreateTask, UpdateTask or DeleteTask, so the same
+// permission and validation rules apply as for single requests.
+//
+// Batches that are not atomic run every operation and report each result.
+// Atomic batches run in a transaction and stop at the first failure; the
+// failed operation reports its error and all others ErrBatchAborted.
+func (uc *TaskUseCase) RunBatch(ctx context.Context, policy *Domain.Policy, req Domain.BatchRequest) ([]Domain.BatchOperationResult, error) {
+	if len(req.Operations) == 0 || len(req.Operations) > Domain.MaxBatchOperations {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	if !req.Atomic {
+		return uc.runOperations(ctx, policy, req.Operations, false), nil
+	}
+
+	var results []Domain.BatchOperationResult
+	var events *pendingEvents
+	err := uc.withinTransaction(ctx, func(ctx context.Context, repos Domain.TaskRepositories) error {
+		// The transaction may run this more than once, so start over each time
+		events = &pendingEvents{TaskEventBus: uc.events}
+		results = uc.withRepositories(repos, events).runOperations(ctx, policy, req.Operations, true)
+		for _, result := range results {
+			if result.Err != nil {
+				return errBatchFailed
+			}
+		}
+		return nil
+	})
+	if err == errBatchFailed {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = Domain.BatchOperationResult{Op: results[i].Op, Err: Domain.ErrBatchAborted}
+			}
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	events.flush()
+	return results, nil
+}
+
+// runOperations runs the operations in order. With stopOnError, operations
+// after a failed one are not run and report ErrBatchAborted.
+func (uc *TaskUseCase) runOperations(ctx context.Context, policy *Domain.Policy, operations []Domain.BatchOperation, stopOnError bool) []Domain.BatchOperationResult {
+	results := make([]Domain.BatchOperationResult, len(operations))
+	failed := false
+	for i, operation := range operations {
+		results[i].Op = operation.Op
+		if failed && stopOnError {
+			results[i].Err = Domain.ErrBatchAborted
+			continue
+		}
+
+		switch {
+		case operation.Op == Domain.BatchCreate && operation.Create != nil:
+			results[i].Task, results[i].Err = uc.CreateTask(ctx, *operation.Create, policy)
+		case operation.Op == Domain.BatchUpdate && operation.Update != nil:
+			results[i].Task, results[i].Err = uc.UpdateTask(ctx, operation.ID, policy, *operation.Update, operation.Revision)
+		case operation.Op == Domain.BatchDelete:
+			results[i].Err = uc.DeleteTask(ctx, operation.ID, policy, operation.Revision)
+		default:
+			results[i].Err = Domain.ErrInvalidInput
+		}
+		failed = failed || results[i].Err != nil
+	}
+	return results
+}
+
+// withRepositories returns a copy of the use case that works on repos and
+// publishes to events, such as the repositories of a transaction and the
+// events to publish once it commits
+func (uc *TaskUseCase) withRepositories(repos Domain.TaskRepositories, events Domain.TaskEventBus) *TaskUseCase {
+	return &TaskUseCase{
+		taskRepo:     repos.Tasks,
+		revisionRepo: repos.Revisions,
+		userRepo:     uc.userRepo,
+		auditRepo:    repos.Audit,
+		events:       events,
+	}
+}
@@ -0,0 +1,69 @@
+package Usecases
+
+import (
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// SubscribeTaskEvents streams the changes to tasks GetAllTasks would list,
+// after the event with lastEventID if it is set
+func (uc *TaskUseCase) SubscribeTaskEvents(policy *Domain.Policy, lastEventID string, includeShared bool) (*Domain.TaskEventSubscription, error) {
+	scope, err := policy.TaskScope(Domain.TaskAccessRead)
+	if err != nil {
+		return nil, err
+	}
+	scope.ExcludeShared = !includeShared
+
+	return uc.events.Subscribe(lastEventID, func(event Domain.TaskEvent) bool {
+		return event.VisibleTo(scope)
+	}), nil
+}
+
+// publishTaskEvent tells subscribers about a change to a task. before is
+// nil for created tasks.
+func (uc *TaskUseCase) publishTaskEvent(before, after *Domain.Task) {
+	event := Domain.TaskEvent{Type: Domain.TaskEventUpdated, Task: *after}
+	switch {
+	case before == nil:
+		event.Type = Domain.TaskEventCreated
+	case after.DeletedAt != nil && before.DeletedAt == nil:
+		event.Type = Domain.TaskEventDeleted
+	case after.DeletedAt == nil && before.DeletedAt != nil:
+		event.Type = Domain.TaskEventCreated
+	}
+
+	for _, task := range []*Domain.Task{before, after} {
+		if task == nil {
+			continue
+		}
+		event.Viewers = append(event.Viewers, task.UserID)
+		for _, collaborator := range task.Collaborators {
+			event.Viewers = append(event.Viewers, collaborator.UserID)
+		}
+	}
+	slices.SortFunc(event.Viewers, func(a, b primitive.ObjectID) int {
+		return slices.Compare(a[:], b[:])
+	})
+	event.Viewers = slices.Compact(event.Viewers)
+
+	uc.events.Publish(event)
+}
+
+// pendingEvents holds back the events of a transaction until it commits
+type pendingEvents struct {
+	Domain.TaskEventBus
+	events []Domain.TaskEvent
+}
+
+func (p *pendingEvents) Publish(event Domain.TaskEvent) {
+	p.events = append(p.events, event)
+}
+
+func (p *pendingEvents) flush() {
+	for _, event := range p.events {
+		p.TaskEventBus.Publish(event)
+	}
+}
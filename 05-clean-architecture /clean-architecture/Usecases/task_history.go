@@ -0,0 +1,130 @@
+package Usecases
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// GetTaskHistory returns every revision of a task, newest first
+func (uc *TaskUseCase) GetTaskHistory(ctx context.Context, id string, policy *Domain.Policy) ([]Domain.TaskRevision, error) {
+	task, err := uc.GetTask(ctx, id, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.revisionRepo.List(ctx, task.ID)
+}
+
+func (uc *TaskUseCase) GetTaskRevision(ctx context.Context, id string, revision int, policy *Domain.Policy) (*Domain.TaskRevision, error) {
+	task, err := uc.GetTask(ctx, id, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.revisionRepo.Get(ctx, task.ID, revision)
+}
+
+// RestoreTaskRevision puts a task's content back the way it was at an
+// earlier revision. Only the title, description, completion, due date,
+// priority, tags and recurrence are restored; sharing and relations to
+// other tasks stay as they are. Like UpdateTask, a restore that completes
+// the task fails while a blocker is open unless force is set. The restore
+// is itself a new revision.
+func (uc *TaskUseCase) RestoreTaskRevision(ctx context.Context, id string, revision int, policy *Domain.Policy, force bool) (*Domain.Task, error) {
+	taskID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, Domain.ErrInvalidID
+	}
+
+	scope, err := policy.TaskScope(Domain.TaskAccessWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := uc.taskRepo.GetByID(ctx, taskID, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, err := uc.revisionRepo.Get(ctx, taskID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := rev.Task
+	if snapshot.Completed && !before.Completed && !force {
+		if err := uc.checkBlockers(ctx, before.BlockedBy); err != nil {
+			return nil, err
+		}
+	}
+
+	updates := map[string]interface{}{
+		"title":       snapshot.Title,
+		"description": snapshot.Description,
+		"completed":   snapshot.Completed,
+		"due_at":      snapshot.DueAt,
+		"priority":    snapshot.Priority,
+		"tags":        snapshot.Tags,
+		"recurrence":  snapshot.Recurrence,
+	}
+	if snapshot.Recurrence != "" && before.SeriesID == nil {
+		updates["series_id"] = taskID
+		updates["occurrence"] = 1
+	}
+
+	// Only restore over the version the blockers were checked against
+	scope.Revision = before.Revision
+	return uc.updateTask(ctx, taskID, scope, policy, updates, Domain.AuditTaskRestored, fmt.Sprintf("revision %d", revision))
+}
+
+// ListTrash lists the deleted tasks the caller could undelete
+func (uc *TaskUseCase) ListTrash(ctx context.Context, policy *Domain.Policy, opts Domain.TaskListOptions) (*Domain.TaskPage, error) {
+	if opts.SortBy != "" && !opts.SortBy.IsValid() {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	if opts.Limit < 0 {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	scope, err := policy.TaskScope(Domain.TaskAccessManage)
+	if err != nil {
+		return nil, err
+	}
+	scope.Deleted = true
+
+	return uc.taskRepo.GetAll(ctx, scope, opts.WithDefaults())
+}
+
+// UndeleteTask moves a task out of the trash. Like deleting, it is up to
+// the owner.
+func (uc *TaskUseCase) UndeleteTask(ctx context.Context, id string, policy *Domain.Policy) (*Domain.Task, error) {
+	taskID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, Domain.ErrInvalidID
+	}
+
+	scope, err := policy.TaskScope(Domain.TaskAccessManage)
+	if err != nil {
+		return nil, err
+	}
+	scope.Deleted = true
+
+	before, err := uc.taskRepo.GetByID(ctx, taskID, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := uc.taskRepo.Undelete(ctx, taskID, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.recordTaskChange(ctx, Domain.AuditTaskUndeleted, policy, before, task, "")
+
+	return task, nil
+}
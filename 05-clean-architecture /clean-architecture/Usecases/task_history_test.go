@@ -0,0 +1,123 @@
+package Usecases
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"taskmanager/auth/Domain"
+)
+
+func TestRestoreTaskRevision(t *testing.T) {
+	completed, reopened := true, false
+
+	tests := []struct {
+		name string
+		// restoreCompleted restores the revision the task was completed
+		// at, rather than the one it was reopened at
+		restoreCompleted bool
+		blockerDone      bool
+		force            bool
+		wantErr          error
+	}{
+		{"completes with an open blocker", true, false, false, Domain.ErrTaskBlocked},
+		{"completes with an open blocker, forced", true, false, true, nil},
+		{"completes with the blocker done", true, true, false, nil},
+		{"stays open with an open blocker", false, false, false, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tt := newTaskTest(t)
+			ctx := context.Background()
+			blocker := tt.add(t, nil)
+			id := tt.add(t, nil, blocker).Hex()
+
+			done, err := tt.uc.UpdateTask(ctx, id, tt.policy, Domain.UpdateTaskRequest{Completed: &completed, Force: true}, 0)
+			if err != nil {
+				t.Fatalf("UpdateTask: %v", err)
+			}
+			open, err := tt.uc.UpdateTask(ctx, id, tt.policy, Domain.UpdateTaskRequest{Completed: &reopened}, 0)
+			if err != nil {
+				t.Fatalf("UpdateTask: %v", err)
+			}
+			if test.blockerDone {
+				if _, err := tt.uc.UpdateTask(ctx, blocker.Hex(), tt.policy, Domain.UpdateTaskRequest{Completed: &completed}, 0); err != nil {
+					t.Fatalf("UpdateTask of the blocker: %v", err)
+				}
+			}
+
+			revision := open.Revision
+			if test.restoreCompleted {
+				revision = done.Revision
+			}
+			restored, err := tt.uc.RestoreTaskRevision(ctx, id, revision, tt.policy, test.force)
+			if err != test.wantErr {
+				t.Fatalf("RestoreTaskRevision = %v, want %v", err, test.wantErr)
+			}
+
+			history, err := tt.uc.GetTaskHistory(ctx, id, tt.policy)
+			if err != nil {
+				t.Fatalf("GetTaskHistory: %v", err)
+			}
+			if test.wantErr != nil {
+				if len(history) != 2 {
+					t.Errorf("history has %d revisions, want the 2 updates only", len(history))
+				}
+				return
+			}
+
+			if restored.Completed != test.restoreCompleted || restored.Revision != open.Revision+1 {
+				t.Errorf("restored task completed = %v at revision %d, want %v at %d",
+					restored.Completed, restored.Revision, test.restoreCompleted, open.Revision+1)
+			}
+			// Restores share the write path of updates, so they are
+			// recorded in the history like any other change
+			if len(history) != 3 || history[0].Revision != restored.Revision || history[0].Action != Domain.AuditTaskRestored {
+				t.Errorf("history = %+v, want a restore at revision %d first", history, restored.Revision)
+			}
+		})
+	}
+}
+
+// TestRestoreTaskRevisionEvent checks that a restore is announced to event
+// streams and audited with the revision it restored
+func TestRestoreTaskRevisionEvent(t *testing.T) {
+	tt := newTaskTest(t)
+	ctx := context.Background()
+	id := tt.add(t, nil).Hex()
+
+	first, err := tt.uc.UpdateTask(ctx, id, tt.policy, Domain.UpdateTaskRequest{Title: "First"}, 0)
+	if err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if _, err := tt.uc.UpdateTask(ctx, id, tt.policy, Domain.UpdateTaskRequest{Title: "Second"}, 0); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+
+	sub := tt.events.Subscribe("", func(Domain.TaskEvent) bool { return true })
+	defer sub.Cancel()
+
+	restored, err := tt.uc.RestoreTaskRevision(ctx, id, first.Revision, tt.policy, false)
+	if err != nil {
+		t.Fatalf("RestoreTaskRevision: %v", err)
+	}
+
+	select {
+	case event := <-sub.Events:
+		if event.Type != Domain.TaskEventUpdated || event.Task.Title != "First" || event.Task.Revision != restored.Revision {
+			t.Errorf("event = %s of %q at revision %d, want %s of %q at %d",
+				event.Type, event.Task.Title, event.Task.Revision, Domain.TaskEventUpdated, "First", restored.Revision)
+		}
+	default:
+		t.Fatal("no event was published for the restore")
+	}
+
+	page, err := tt.audit.List(ctx, Domain.AuditListOptions{Filter: Domain.AuditFilter{Action: Domain.AuditTaskRestored}})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	want := fmt.Sprintf("revision %d", first.Revision)
+	if len(page.Entries) != 1 || page.Entries[0].Detail != want {
+		t.Errorf("restore audit entries = %+v, want one with detail %q", page.Entries, want)
+	}
+}
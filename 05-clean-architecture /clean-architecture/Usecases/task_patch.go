@@ -0,0 +1,290 @@
+package Usecases
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// maxPatchAttempts is how often an unconditional patch is recomputed when
+// the task changes between reading and writing it
+const maxPatchAttempts = 3
+
+// patchableTask is the document a TaskPatch applies to
+type patchableTask struct {
+	Title       string               `json:"title"`
+	Description string               `json:"description"`
+	Completed   bool                 `json:"completed"`
+	DueAt       *time.Time           `json:"due_at"`
+	Priority    Domain.Priority      `json:"priority"`
+	Tags        []string             `json:"tags"`
+	ParentID    *primitive.ObjectID  `json:"parent_id"`
+	BlockedBy   []primitive.ObjectID `json:"blocked_by"`
+	Recurrence  string               `json:"recurrence"`
+}
+
+var patchableTaskFields = map[string]bool{
+	"title":       true,
+	"description": true,
+	"completed":   true,
+	"due_at":      true,
+	"priority":    true,
+	"tags":        true,
+	"parent_id":   true,
+	"blocked_by":  true,
+	"recurrence":  true,
+}
+
+// PatchTask applies a JSON Merge Patch or JSON Patch to a task's editable
+// fields. The patched task is validated as a whole and written in one
+// conditional update, so it never overwrites a concurrent change: with a
+// non-zero revision (from If-Match) such a change fails the patch with
+// ErrRevisionMismatch, otherwise the patch is applied again to the new
+// version of the task.
+func (uc *TaskUseCase) PatchTask(ctx context.Context, id string, policy *Domain.Policy, patch Domain.TaskPatch, revision int) (*Domain.Task, error) {
+	taskID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, Domain.ErrInvalidID
+	}
+
+	var apply func(doc map[string]interface{}, patch []byte) (map[string]interface{}, error)
+	switch patch.Format {
+	case Domain.PatchFormatMerge:
+		apply = applyMergePatch
+	case Domain.PatchFormatJSON:
+		apply = applyJSONPatch
+	default:
+		return nil, Domain.ErrInvalidPatch
+	}
+
+	scope, err := policy.TaskScope(Domain.TaskAccessWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		before, err := uc.taskRepo.GetByID(ctx, taskID, scope)
+		if err != nil {
+			return nil, err
+		}
+		if revision != 0 && before.Revision != revision {
+			return nil, Domain.ErrRevisionMismatch
+		}
+
+		doc, err := apply(patchDocument(*before), patch.Document)
+		if err != nil {
+			return nil, err
+		}
+
+		updates, err := uc.patchUpdates(ctx, *before, doc, patch.Force, policy)
+		if err != nil {
+			return nil, err
+		}
+
+		// The updates were made from this revision, so they are only
+		// written if the task is still at it
+		scope.Revision = before.Revision
+		task, err := uc.updateTask(ctx, taskID, scope, policy, updates, Domain.AuditTaskUpdated, "")
+		if err == Domain.ErrRevisionMismatch && revision == 0 && attempt < maxPatchAttempts {
+			continue
+		}
+		return task, err
+	}
+}
+
+// patchDocument returns the editable fields of a task as a JSON object.
+// Empty lists are included so JSON Patch can append to them.
+func patchDocument(task Domain.Task) map[string]interface{} {
+	fields := patchableTask{
+		Title:       task.Title,
+		Description: task.Description,
+		Completed:   task.Completed,
+		DueAt:       task.DueAt,
+		Priority:    task.Priority,
+		Tags:        task.Tags,
+		ParentID:    task.ParentID,
+		BlockedBy:   task.BlockedBy,
+		Recurrence:  task.Recurrence,
+	}
+	if fields.Priority == "" {
+		fields.Priority = Domain.PriorityMedium
+	}
+	if fields.Tags == nil {
+		fields.Tags = []string{}
+	}
+	if fields.BlockedBy == nil {
+		fields.BlockedBy = []primitive.ObjectID{}
+	}
+
+	var doc map[string]interface{}
+	data, _ := json.Marshal(fields)
+	_ = json.Unmarshal(data, &doc)
+	return doc
+}
+
+// patchUpdates validates a patched document and returns the fields that
+// differ from the task. Missing and null fields are cleared, or reset to
+// their default for completed and priority.
+func (uc *TaskUseCase) patchUpdates(ctx context.Context, task Domain.Task, doc map[string]interface{}, force bool, policy *Domain.Policy) (map[string]interface{}, error) {
+	for _, field := range slices.Sorted(maps.Keys(doc)) {
+		if !patchableTaskFields[field] {
+			return nil, &Domain.FieldError{Field: field, Message: "cannot be changed"}
+		}
+	}
+
+	var patched struct {
+		Title       string
+		Description string
+		Completed   bool
+		DueAt       *time.Time
+		Priority    Domain.Priority
+		Tags        []string
+		ParentID    string
+		BlockedBy   []string
+		Recurrence  string
+	}
+	fields := []struct {
+		name    string
+		target  interface{}
+		message string
+	}{
+		{"title", &patched.Title, "must be a string"},
+		{"description", &patched.Description, "must be a string"},
+		{"completed", &patched.Completed, "must be true or false"},
+		{"due_at", &patched.DueAt, "must be an RFC 3339 timestamp"},
+		{"priority", &patched.Priority, "must be a string"},
+		{"tags", &patched.Tags, "must be a list of strings"},
+		{"parent_id", &patched.ParentID, "must be a task ID"},
+		{"blocked_by", &patched.BlockedBy, "must be a list of task IDs"},
+		{"recurrence", &patched.Recurrence, "must be a string"},
+	}
+	for _, field := range fields {
+		value := doc[field.name]
+		if value == nil {
+			continue
+		}
+		data, _ := json.Marshal(value)
+		if err := json.Unmarshal(data, field.target); err != nil {
+			return nil, &Domain.FieldError{Field: field.name, Message: field.message}
+		}
+	}
+
+	updates := make(map[string]interface{})
+
+	if strings.TrimSpace(patched.Title) == "" {
+		return nil, &Domain.FieldError{Field: "title", Message: "is required"}
+	}
+	if err := checkTaskText(patched.Title, patched.Description, patched.Recurrence); err != nil {
+		return nil, err
+	}
+	if patched.Title != task.Title {
+		updates["title"] = patched.Title
+	}
+
+	if patched.Description != task.Description {
+		updates["description"] = patched.Description
+	}
+
+	if patched.Completed != task.Completed {
+		updates["completed"] = patched.Completed
+	}
+
+	if !sameTime(patched.DueAt, task.DueAt) {
+		updates["due_at"] = patched.DueAt
+	}
+
+	if patched.Priority == "" {
+		patched.Priority = Domain.PriorityMedium
+	}
+	if !patched.Priority.IsValid() {
+		return nil, &Domain.FieldError{Field: "priority", Message: "must be low, medium, high or urgent"}
+	}
+	if current := task.Priority; patched.Priority != current && (current != "" || patched.Priority != Domain.PriorityMedium) {
+		updates["priority"] = patched.Priority
+	}
+
+	tags, err := normalizeTags(patched.Tags)
+	if err != nil {
+		return nil, &Domain.FieldError{Field: "tags", Message: "must be at most 20 tags of 1 to 32 characters"}
+	}
+	if !slices.Equal(tags, task.Tags) {
+		updates["tags"] = tags
+	}
+
+	var parentID *primitive.ObjectID
+	if patched.ParentID != "" {
+		id, err := primitive.ObjectIDFromHex(patched.ParentID)
+		if err != nil {
+			return nil, &Domain.FieldError{Field: "parent_id", Message: "must be a task ID"}
+		}
+		parentID = &id
+	}
+	if !sameID(parentID, task.ParentID) {
+		if parentID != nil {
+			if parentID, err = uc.resolveParent(ctx, task.ID, patched.ParentID, policy); err != nil {
+				return nil, err
+			}
+		}
+		updates["parent_id"] = parentID
+	}
+
+	blockers := task.BlockedBy
+	blockerIDs := make([]primitive.ObjectID, 0, len(patched.BlockedBy))
+	for _, hex := range patched.BlockedBy {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			return nil, &Domain.FieldError{Field: "blocked_by", Message: "must be a list of task IDs"}
+		}
+		blockerIDs = append(blockerIDs, id)
+	}
+	if !slices.Equal(blockerIDs, task.BlockedBy) {
+		if blockers, err = uc.resolveBlockers(ctx, task.ID, patched.BlockedBy, policy); err != nil {
+			return nil, err
+		}
+		updates["blocked_by"] = blockers
+	}
+
+	if patched.Completed && !task.Completed && !force {
+		if err := uc.checkBlockers(ctx, blockers); err != nil {
+			return nil, err
+		}
+	}
+
+	recurrence := ""
+	if patched.Recurrence != "" {
+		if recurrence, err = normalizeRecurrence(patched.Recurrence, patched.DueAt); err != nil {
+			return nil, &Domain.FieldError{Field: "recurrence", Message: "must be a supported RRULE on a task with a due date"}
+		}
+	}
+	if recurrence != task.Recurrence {
+		updates["recurrence"] = recurrence
+		// A task that starts repeating starts a new series
+		if recurrence != "" && task.SeriesID == nil {
+			updates["series_id"] = task.ID
+			updates["occurrence"] = 1
+		}
+	}
+
+	return updates, nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func sameID(a, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
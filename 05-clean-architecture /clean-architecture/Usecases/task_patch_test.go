@@ -0,0 +1,71 @@
+package Usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+func TestPatchTask(t *testing.T) {
+	tt := newTaskTest(t)
+	ctx := context.Background()
+
+	dueAt := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	task := &Domain.Task{
+		ID:         primitive.NewObjectID(),
+		Title:      "Stand-up",
+		UserID:     tt.policy.UserID,
+		DueAt:      &dueAt,
+		Recurrence: "FREQ=DAILY",
+		Revision:   1,
+	}
+	if err := tt.tasks.Create(ctx, task); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	merge := func(doc string) Domain.TaskPatch {
+		return Domain.TaskPatch{Format: Domain.PatchFormatMerge, Document: []byte(doc)}
+	}
+
+	if _, err := tt.uc.PatchTask(ctx, task.ID.Hex(), tt.policy, merge(`{"title":"Daily stand-up"}`), 2); err != Domain.ErrRevisionMismatch {
+		t.Fatalf("PatchTask at a stale revision = %v, want ErrRevisionMismatch", err)
+	}
+
+	patched, err := tt.uc.PatchTask(ctx, task.ID.Hex(), tt.policy, merge(`{"completed":true}`), 1)
+	if err != nil {
+		t.Fatalf("PatchTask: %v", err)
+	}
+	if !patched.Completed || patched.Revision != 2 {
+		t.Errorf("patched task completed = %v at revision %d, want true at 2", patched.Completed, patched.Revision)
+	}
+
+	// Patches share the write path of updates, so completing a recurring
+	// task creates its next occurrence and records the change
+	history, err := tt.uc.GetTaskHistory(ctx, task.ID.Hex(), tt.policy)
+	if err != nil {
+		t.Fatalf("GetTaskHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].Revision != 2 {
+		t.Errorf("history = %+v, want revision 2", history)
+	}
+
+	scope, _ := tt.policy.TaskScope(Domain.TaskAccessRead)
+	page, err := tt.tasks.GetAll(ctx, scope, Domain.TaskListOptions{})
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(page.Tasks) != 2 {
+		t.Errorf("stored %d tasks, want the patched task and its next occurrence", len(page.Tasks))
+	}
+
+	unchanged, err := tt.uc.PatchTask(ctx, task.ID.Hex(), tt.policy, merge(`{}`), 0)
+	if err != nil {
+		t.Fatalf("PatchTask without changes: %v", err)
+	}
+	if unchanged.Revision != 2 {
+		t.Errorf("empty patch moved the task to revision %d, want 2", unchanged.Revision)
+	}
+}
@@ -0,0 +1,113 @@
+package Usecases
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// recurrenceBatchSize is how many recurring tasks one pass loads at a time
+const recurrenceBatchSize = 100
+
+// ProcessRecurrences creates the next occurrence of every recurring task
+// that was completed or whose due date passed, and returns how many tasks
+// it handled. Running it again, or concurrently, creates no duplicates.
+func (uc *TaskUseCase) ProcessRecurrences(ctx context.Context, now time.Time) (int, error) {
+	handled := 0
+	for {
+		tasks, err := uc.taskRepo.GetRecurrenceDue(ctx, now, recurrenceBatchSize)
+		if err != nil {
+			return handled, err
+		}
+
+		for _, task := range tasks {
+			if err := uc.createNextOccurrence(ctx, task, now); err != nil {
+				return handled, err
+			}
+			handled++
+		}
+
+		if len(tasks) < recurrenceBatchSize {
+			return handled, nil
+		}
+	}
+}
+
+// createNextOccurrence creates the task that follows task in its series and
+// marks task as recurred. The next occurrence only depends on task, so a
+// retry after a crash finds the one created before instead of adding another.
+func (uc *TaskUseCase) createNextOccurrence(ctx context.Context, task Domain.Task, now time.Time) error {
+	rule, err := Domain.ParseRecurrenceRule(task.Recurrence)
+	if err != nil || task.DueAt == nil {
+		// Nothing to schedule from; don't pick the task up again
+		return uc.taskRepo.MarkRecurred(ctx, task.ID)
+	}
+
+	seriesID := task.ID
+	if task.SeriesID != nil {
+		seriesID = *task.SeriesID
+	}
+	occurrence := max(task.Occurrence, 1) + 1
+	dueAt := rule.Next(*task.DueAt)
+
+	if rule.Count > 0 && occurrence > rule.Count || rule.Until != nil && dueAt.After(*rule.Until) {
+		// The series is over
+		return uc.taskRepo.MarkRecurred(ctx, task.ID)
+	}
+
+	next := &Domain.Task{
+		ID:            primitive.NewObjectID(),
+		Title:         task.Title,
+		Description:   task.Description,
+		DueAt:         &dueAt,
+		Priority:      task.Priority,
+		Tags:          task.Tags,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		UserID:        task.UserID,
+		Collaborators: task.Collaborators,
+		ParentID:      task.ParentID,
+		Recurrence:    task.Recurrence,
+		SeriesID:      &seriesID,
+		Occurrence:    occurrence,
+		Revision:      1,
+	}
+
+	// ErrOccurrenceExists means an earlier attempt got as far as creating
+	// the task but not marking this one
+	err = uc.taskRepo.CreateOccurrence(ctx, next)
+	if err == nil {
+		uc.recordRevision(ctx, Domain.AuditTaskCreated, nil, next)
+		recordAudit(ctx, uc.auditRepo, Domain.AuditEntry{
+			Action:     Domain.AuditTaskCreated,
+			EntityType: Domain.AuditEntityTask,
+			EntityID:   &next.ID,
+			Changes:    auditChanges(nil, next),
+			Detail:     "recurrence",
+		})
+		uc.publishTaskEvent(nil, next)
+	} else if err != Domain.ErrOccurrenceExists {
+		return err
+	}
+
+	return uc.taskRepo.MarkRecurred(ctx, task.ID)
+}
+
+// normalizeRecurrence validates an RRULE for a task due at dueAt and
+// returns it in canonical form. Occurrences are scheduled from the due
+// date, so recurring tasks need one.
+func normalizeRecurrence(value string, dueAt *time.Time) (string, error) {
+	if dueAt == nil {
+		return "", Domain.ErrInvalidInput
+	}
+
+	rule, err := Domain.ParseRecurrenceRule(value)
+	if err != nil {
+		return "", err
+	}
+
+	return rule.String(), nil
+}
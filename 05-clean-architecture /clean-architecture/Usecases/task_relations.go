@@ -0,0 +1,256 @@
+package Usecases
+
+import (
+	"context"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// integrityScope sees every task, trashed ones included. Cycles are checked
+// against the whole task graph, not only the part the caller can read, so
+// that taking a task out of the trash cannot close one.
+var integrityScope = Domain.TaskScope{AllTasks: true, AnyDeletedState: true}
+
+// blockerScope sees every live task. Blockers in the trash do not block.
+var blockerScope = Domain.TaskScope{AllTasks: true}
+
+// GetSubtree returns the task with its subtasks, nested to any depth.
+// Subtasks the caller cannot read are left out along with their own subtasks.
+func (uc *TaskUseCase) GetSubtree(ctx context.Context, id string, policy *Domain.Policy) (*Domain.TaskNode, error) {
+	taskID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, Domain.ErrInvalidID
+	}
+
+	scope, err := policy.TaskScope(Domain.TaskAccessRead)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := uc.taskRepo.GetByID(ctx, taskID, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the tree one level at a time
+	children := make(map[primitive.ObjectID][]Domain.Task)
+	seen := map[primitive.ObjectID]bool{root.ID: true}
+	level := []primitive.ObjectID{root.ID}
+	for len(level) > 0 {
+		subtasks, err := uc.taskRepo.GetSubtasks(ctx, level, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		level = nil
+		for _, subtask := range subtasks {
+			if seen[subtask.ID] {
+				continue
+			}
+			seen[subtask.ID] = true
+			children[*subtask.ParentID] = append(children[*subtask.ParentID], subtask)
+			level = append(level, subtask.ID)
+		}
+	}
+
+	node := buildTaskNode(*root, children)
+	return &node, nil
+}
+
+func buildTaskNode(task Domain.Task, children map[primitive.ObjectID][]Domain.Task) Domain.TaskNode {
+	node := Domain.TaskNode{Task: task, Subtasks: []Domain.TaskNode{}}
+	for _, child := range children[task.ID] {
+		node.Subtasks = append(node.Subtasks, buildTaskNode(child, children))
+	}
+	return node
+}
+
+// GetDependencyGraph returns the tasks the task transitively depends on and
+// the tasks that transitively depend on it, limited to what the caller can
+// read, with the edges between them
+func (uc *TaskUseCase) GetDependencyGraph(ctx context.Context, id string, policy *Domain.Policy) (*Domain.DependencyGraph, error) {
+	taskID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, Domain.ErrInvalidID
+	}
+
+	scope, err := policy.TaskScope(Domain.TaskAccessRead)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := uc.taskRepo.GetByID(ctx, taskID, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []Domain.Task{*root}
+	seen := map[primitive.ObjectID]bool{root.ID: true}
+	collect := func(found []Domain.Task) []Domain.Task {
+		var added []Domain.Task
+		for _, task := range found {
+			if !seen[task.ID] {
+				seen[task.ID] = true
+				tasks = append(tasks, task)
+				added = append(added, task)
+			}
+		}
+		return added
+	}
+
+	// Blockers, following blocked_by upstream
+	for frontier := []Domain.Task{*root}; len(frontier) > 0; {
+		var ids []primitive.ObjectID
+		for _, task := range frontier {
+			ids = append(ids, task.BlockedBy...)
+		}
+		found, err := uc.taskRepo.GetByIDs(ctx, ids, scope)
+		if err != nil {
+			return nil, err
+		}
+		frontier = collect(found)
+	}
+
+	// Dependents, following blocked_by downstream
+	for frontier := []primitive.ObjectID{root.ID}; len(frontier) > 0; {
+		found, err := uc.taskRepo.GetDependents(ctx, frontier, scope)
+		if err != nil {
+			return nil, err
+		}
+		frontier = nil
+		for _, task := range collect(found) {
+			frontier = append(frontier, task.ID)
+		}
+	}
+
+	graph := &Domain.DependencyGraph{Tasks: tasks, Edges: []Domain.DependencyEdge{}}
+	for _, task := range tasks {
+		for _, blocker := range task.BlockedBy {
+			if seen[blocker] {
+				graph.Edges = append(graph.Edges, Domain.DependencyEdge{TaskID: task.ID, BlockedBy: blocker})
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+// resolveParent checks that the task can become a subtask of parentHex.
+// Adding a subtask changes the parent's tree, so it takes write access to
+// the parent. taskID is zero for tasks that do not exist yet.
+func (uc *TaskUseCase) resolveParent(ctx context.Context, taskID primitive.ObjectID, parentHex string, policy *Domain.Policy) (*primitive.ObjectID, error) {
+	parentID, err := primitive.ObjectIDFromHex(parentHex)
+	if err != nil {
+		return nil, Domain.ErrInvalidID
+	}
+
+	scope, err := policy.TaskScope(Domain.TaskAccessWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := uc.taskRepo.GetByID(ctx, parentID, scope); err != nil {
+		if err == Domain.ErrNotFound {
+			return nil, Domain.ErrRelatedNotFound
+		}
+		return nil, err
+	}
+
+	// The task must not be among the parent's ancestors
+	seen := make(map[primitive.ObjectID]bool)
+	for id := &parentID; id != nil && !seen[*id]; {
+		if *id == taskID {
+			return nil, Domain.ErrDependencyCycle
+		}
+		seen[*id] = true
+
+		ancestor, err := uc.taskRepo.GetByID(ctx, *id, integrityScope)
+		if err == Domain.ErrNotFound {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		id = ancestor.ParentID
+	}
+
+	return &parentID, nil
+}
+
+// resolveBlockers checks that the task can be blocked by the tasks in
+// blockerHexes, which the caller must be able to read. taskID is zero for
+// tasks that do not exist yet.
+func (uc *TaskUseCase) resolveBlockers(ctx context.Context, taskID primitive.ObjectID, blockerHexes []string, policy *Domain.Policy) ([]primitive.ObjectID, error) {
+	blockers := []primitive.ObjectID{}
+	for _, hex := range blockerHexes {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			return nil, Domain.ErrInvalidID
+		}
+		if id == taskID {
+			return nil, Domain.ErrDependencyCycle
+		}
+		if !slices.Contains(blockers, id) {
+			blockers = append(blockers, id)
+		}
+	}
+
+	if len(blockers) > Domain.MaxBlockersPerTask {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	scope, err := policy.TaskScope(Domain.TaskAccessRead)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := uc.taskRepo.GetByIDs(ctx, blockers, scope)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) != len(blockers) {
+		return nil, Domain.ErrRelatedNotFound
+	}
+
+	// The task must not be reachable from its new blockers
+	seen := make(map[primitive.ObjectID]bool)
+	for frontier := found; len(frontier) > 0; {
+		var next []primitive.ObjectID
+		for _, task := range frontier {
+			for _, id := range task.BlockedBy {
+				if id == taskID {
+					return nil, Domain.ErrDependencyCycle
+				}
+				if !seen[id] {
+					seen[id] = true
+					next = append(next, id)
+				}
+			}
+		}
+
+		if frontier, err = uc.taskRepo.GetByIDs(ctx, next, integrityScope); err != nil {
+			return nil, err
+		}
+	}
+
+	return blockers, nil
+}
+
+// checkBlockers returns ErrTaskBlocked while any of the blockers is open.
+// Blockers that were deleted no longer block.
+func (uc *TaskUseCase) checkBlockers(ctx context.Context, blockers []primitive.ObjectID) error {
+	tasks, err := uc.taskRepo.GetByIDs(ctx, blockers, blockerScope)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		if !task.Completed {
+			return Domain.ErrTaskBlocked
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package Usecases
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+	"taskmanager/auth/Infrastructure"
+	"taskmanager/auth/Repositories"
+)
+
+// taskTest is a task use case on the in-memory repositories
+type taskTest struct {
+	uc     *TaskUseCase
+	tasks  *Repositories.InMemoryTaskRepository
+	audit  *Repositories.InMemoryAuditRepository
+	events *Infrastructure.EventBus
+	policy *Domain.Policy
+}
+
+func newTaskTest(t *testing.T) *taskTest {
+	t.Helper()
+
+	tasks := Repositories.NewInMemoryTaskRepository()
+	revisions := Repositories.NewInMemoryTaskRevisionRepository()
+	audit := Repositories.NewInMemoryAuditRepository()
+	events := Infrastructure.NewEventBus(10)
+	uc := NewTaskUseCase(tasks, revisions, Repositories.NewInMemoryUserRepository(), audit,
+		Repositories.NewInMemoryTaskTransactor(tasks, revisions, audit), events)
+
+	return &taskTest{
+		uc:     uc,
+		tasks:  tasks,
+		audit:  audit,
+		events: events,
+		policy: Domain.NewPolicy(primitive.NewObjectID(), Domain.RoleUser),
+	}
+}
+
+// add stores a task of the test's user with the given relations
+func (tt *taskTest) add(t *testing.T, parentID *primitive.ObjectID, blockedBy ...primitive.ObjectID) primitive.ObjectID {
+	t.Helper()
+
+	task := &Domain.Task{
+		ID:        primitive.NewObjectID(),
+		Title:     "Task",
+		UserID:    tt.policy.UserID,
+		ParentID:  parentID,
+		BlockedBy: blockedBy,
+	}
+	if err := tt.tasks.Create(context.Background(), task); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return task.ID
+}
+
+func TestResolveParent(t *testing.T) {
+	tt := newTaskTest(t)
+
+	// root <- child <- grandchild
+	root := tt.add(t, nil)
+	child := tt.add(t, &root)
+	grandchild := tt.add(t, &child)
+
+	// A loop that is already stored must not hang the check
+	loopA := primitive.NewObjectID()
+	loopB := tt.add(t, &loopA)
+	if err := tt.tasks.Create(context.Background(), &Domain.Task{ID: loopA, Title: "Task", UserID: tt.policy.UserID, ParentID: &loopB}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	otherUser := &taskTest{tasks: tt.tasks, policy: Domain.NewPolicy(primitive.NewObjectID(), Domain.RoleUser)}
+	notMine := otherUser.add(t, nil)
+
+	tests := []struct {
+		name    string
+		task    primitive.ObjectID
+		parent  string
+		wantErr error
+	}{
+		{"new task", primitive.NilObjectID, grandchild.Hex(), nil},
+		{"move down the tree", grandchild, root.Hex(), nil},
+		{"own parent", child, child.Hex(), Domain.ErrDependencyCycle},
+		{"under its child", child, grandchild.Hex(), Domain.ErrDependencyCycle},
+		{"under its grandchild", root, grandchild.Hex(), Domain.ErrDependencyCycle},
+		{"under a stored loop", root, loopA.Hex(), nil},
+		{"parent of another user", root, notMine.Hex(), Domain.ErrRelatedNotFound},
+		{"missing parent", root, primitive.NewObjectID().Hex(), Domain.ErrRelatedNotFound},
+		{"invalid ID", root, "not-an-id", Domain.ErrInvalidID},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parentID, err := tt.uc.resolveParent(context.Background(), test.task, test.parent, tt.policy)
+			if err != test.wantErr {
+				t.Fatalf("resolveParent = %v, want %v", err, test.wantErr)
+			}
+			if err == nil && parentID.Hex() != test.parent {
+				t.Errorf("resolveParent = %s, want %s", parentID.Hex(), test.parent)
+			}
+		})
+	}
+}
+
+func TestResolveBlockers(t *testing.T) {
+	tt := newTaskTest(t)
+
+	// first blocks second, which blocks third
+	first := tt.add(t, nil)
+	second := tt.add(t, nil, first)
+	third := tt.add(t, nil, second)
+	unrelated := tt.add(t, nil)
+
+	tooMany := make([]string, Domain.MaxBlockersPerTask+1)
+	for i := range tooMany {
+		tooMany[i] = primitive.NewObjectID().Hex()
+	}
+
+	tests := []struct {
+		name     string
+		task     primitive.ObjectID
+		blockers []string
+		want     []primitive.ObjectID
+		wantErr  error
+	}{
+		{"new task", primitive.NilObjectID, []string{third.Hex()}, []primitive.ObjectID{third}, nil},
+		{"no blockers", third, []string{}, []primitive.ObjectID{}, nil},
+		{"duplicates are dropped", third, []string{unrelated.Hex(), first.Hex(), unrelated.Hex()}, []primitive.ObjectID{unrelated, first}, nil},
+		{"blocked by itself", second, []string{second.Hex()}, nil, Domain.ErrDependencyCycle},
+		{"blocked by its dependent", first, []string{second.Hex()}, nil, Domain.ErrDependencyCycle},
+		{"blocked by an indirect dependent", first, []string{unrelated.Hex(), third.Hex()}, nil, Domain.ErrDependencyCycle},
+		{"missing blocker", third, []string{primitive.NewObjectID().Hex()}, nil, Domain.ErrRelatedNotFound},
+		{"invalid ID", third, []string{"not-an-id"}, nil, Domain.ErrInvalidID},
+		{"too many blockers", third, tooMany, nil, Domain.ErrInvalidInput},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blockers, err := tt.uc.resolveBlockers(context.Background(), test.task, test.blockers, tt.policy)
+			if err != test.wantErr {
+				t.Fatalf("resolveBlockers = %v, want %v", err, test.wantErr)
+			}
+			if err == nil && !slices.Equal(blockers, test.want) {
+				t.Errorf("resolveBlockers = %v, want %v", blockers, test.want)
+			}
+		})
+	}
+}
+
+func TestCycleThroughTrash(t *testing.T) {
+	tests := []struct {
+		name string
+		// build stores a chain through trashed and returns the update that
+		// would close a loop through it
+		build func(tt *taskTest) (trashed, task primitive.ObjectID, req Domain.UpdateTaskRequest)
+	}{
+		{"parents", func(tt *taskTest) (primitive.ObjectID, primitive.ObjectID, Domain.UpdateTaskRequest) {
+			// root <- trashed <- leaf, then root under leaf
+			root := tt.add(t, nil)
+			trashed := tt.add(t, &root)
+			leaf := tt.add(t, &trashed).Hex()
+			return trashed, root, Domain.UpdateTaskRequest{ParentID: &leaf}
+		}},
+		{"blockers", func(tt *taskTest) (primitive.ObjectID, primitive.ObjectID, Domain.UpdateTaskRequest) {
+			// first blocks trashed, which blocks last, then last blocks first
+			first := tt.add(t, nil)
+			trashed := tt.add(t, nil, first)
+			last := tt.add(t, nil, trashed)
+			return trashed, first, Domain.UpdateTaskRequest{BlockedBy: &[]string{last.Hex()}}
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tt := newTaskTest(t)
+			ctx := context.Background()
+			trashed, task, req := test.build(tt)
+
+			if err := tt.uc.DeleteTask(ctx, trashed.Hex(), tt.policy, 0); err != nil {
+				t.Fatalf("DeleteTask: %v", err)
+			}
+			if _, err := tt.uc.UpdateTask(ctx, task.Hex(), tt.policy, req, 0); err != Domain.ErrDependencyCycle {
+				t.Fatalf("UpdateTask = %v, want ErrDependencyCycle", err)
+			}
+
+			// Taking the task out of the trash leaves the graph acyclic
+			if _, err := tt.uc.UndeleteTask(ctx, trashed.Hex(), tt.policy); err != nil {
+				t.Fatalf("UndeleteTask: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package Usecases
+
+import (
+	"context"
+	"html"
+	"slices"
+	"strings"
+
+	"taskmanager/auth/Domain"
+)
+
+// Snippets show about snippetLength bytes of a field, starting up to
+// snippetLead bytes before the first match
+const (
+	snippetLength = 160
+	snippetLead   = 40
+)
+
+// SearchTasks runs a full-text search over the tasks GetAllTasks would list
+// and highlights the matches
+func (uc *TaskUseCase) SearchTasks(ctx context.Context, policy *Domain.Policy, opts Domain.TaskSearchOptions) ([]Domain.TaskSearchHit, error) {
+	query, err := Domain.ParseSearchQuery(opts.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Limit < 0 || opts.Limit > Domain.MaxSearchLimit {
+		return nil, Domain.ErrInvalidInput
+	}
+	if opts.Limit == 0 {
+		opts.Limit = Domain.DefaultSearchLimit
+	}
+
+	scope, err := policy.TaskScope(Domain.TaskAccessRead)
+	if err != nil {
+		return nil, err
+	}
+	scope.ExcludeShared = !opts.IncludeShared
+
+	hits, err := uc.taskRepo.Search(ctx, scope, query, opts.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, hit := range hits {
+		hits[i].Highlights = make(map[string]string)
+		if snippet, ok := highlight(hit.Task.Title, query); ok {
+			hits[i].Highlights["title"] = snippet
+		}
+		if snippet, ok := highlight(hit.Task.Description, query); ok {
+			hits[i].Highlights["description"] = snippet
+		}
+	}
+	return hits, nil
+}
+
+// highlight returns an HTML snippet of text around its first match with
+// every matching word wrapped in <mark>, or false if nothing matches
+func highlight(text string, query Domain.SearchQuery) (string, bool) {
+	tokens := Domain.SearchTokens(text)
+	matches := make([]bool, len(tokens))
+	first := -1
+	for i, token := range tokens {
+		matches[i] = highlights(token.Word, query)
+		if matches[i] && first < 0 {
+			first = i
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	// Cut the snippet at word boundaries
+	start, end := 0, len(text)
+	if len(text) > snippetLength {
+		from := first
+		for from > 0 && tokens[first].Start-tokens[from-1].Start <= snippetLead {
+			from--
+		}
+		start = tokens[from].Start
+		end = start
+		for _, token := range tokens[from:] {
+			if token.End-start > snippetLength {
+				break
+			}
+			end = token.End
+		}
+		end = max(end, tokens[first].End)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for i, token := range tokens {
+		if !matches[i] || token.Start < start || token.End > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:token.Start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[token.Start:token.End]))
+		b.WriteString("</mark>")
+		pos = token.End
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+// highlights tells whether a word is one the query searches for
+func highlights(word string, query Domain.SearchQuery) bool {
+	if slices.Contains(query.Words, word) {
+		return true
+	}
+	for _, phrase := range query.Phrases {
+		if slices.Contains(phrase, word) {
+			return true
+		}
+	}
+	for _, prefix := range query.Prefixes {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package Usecases
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+func TestHighlight(t *testing.T) {
+	long := strings.Repeat("lorem ", 50) + "target " + strings.Repeat("ipsum ", 50)
+
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  string
+	}{
+		{"word", "Deploy the app", "deploy", "<mark>Deploy</mark> the app"},
+		{"every match", "app, App and APP", "app", "<mark>app</mark>, <mark>App</mark> and <mark>APP</mark>"},
+		{"phrase words", "Write release notes", `"release notes"`, "Write <mark>release</mark> <mark>notes</mark>"},
+		{"prefix", "Migrate the migrations", "migr*", "<mark>Migrate</mark> the <mark>migrations</mark>"},
+		{"HTML is escaped", `Fix <b> & "deploy"`, "deploy", "Fix &lt;b&gt; &amp; &#34;<mark>deploy</mark>&#34;"},
+		{"prefix inside a word", "remigrate", "migr*", ""},
+		{"no match", "Deploy the app", "release", ""},
+		{"long text is cut around the match", long, "target",
+			"…" + strings.Repeat("lorem ", 6) + "<mark>target</mark> " + strings.TrimSuffix(strings.Repeat("ipsum ", 19), " ") + "…"},
+		{"long text starting with the match", "target " + strings.Repeat("ipsum ", 50), "target",
+			"<mark>target</mark> " + strings.TrimSuffix(strings.Repeat("ipsum ", 25), " ") + "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := Domain.ParseSearchQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseSearchQuery: %v", err)
+			}
+			got, ok := highlight(tt.text, query)
+			if ok != (tt.want != "") || got != tt.want {
+				t.Errorf("highlight = %q, %v; want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchTasks(t *testing.T) {
+	tt := newTaskTest(t)
+
+	add := func(title, description string) primitive.ObjectID {
+		task := &Domain.Task{ID: primitive.NewObjectID(), Title: title, Description: description, UserID: tt.policy.UserID}
+		if err := tt.tasks.Create(context.Background(), task); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return task.ID
+	}
+	notes := add("Write release notes", "For the <next> deploy")
+	add("Release the app", "Notes are done")
+	add("Plan the sprint", "")
+
+	hits, err := tt.uc.SearchTasks(context.Background(), tt.policy, Domain.TaskSearchOptions{Query: `"release notes"`})
+	if err != nil {
+		t.Fatalf("SearchTasks: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Task.ID != notes {
+		t.Fatalf("SearchTasks found %d tasks, want only %s", len(hits), notes.Hex())
+	}
+	if got, want := hits[0].Highlights["title"], "Write <mark>release</mark> <mark>notes</mark>"; got != want {
+		t.Errorf("title highlight = %q, want %q", got, want)
+	}
+	if _, ok := hits[0].Highlights["description"]; ok {
+		t.Errorf("description is highlighted without a match: %q", hits[0].Highlights["description"])
+	}
+
+	hits, err = tt.uc.SearchTasks(context.Background(), tt.policy, Domain.TaskSearchOptions{Query: "deploy"})
+	if err != nil {
+		t.Fatalf("SearchTasks: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Highlights["description"] != "For the &lt;next&gt; <mark>deploy</mark>" {
+		t.Errorf("SearchTasks(deploy) = %+v, want the description highlighted", hits)
+	}
+
+	for _, opts := range []Domain.TaskSearchOptions{
+		{Query: ""},
+		{Query: "deploy", Limit: -1},
+		{Query: "deploy", Limit: Domain.MaxSearchLimit + 1},
+	} {
+		if _, err := tt.uc.SearchTasks(context.Background(), tt.policy, opts); err != Domain.ErrInvalidInput {
+			t.Errorf("SearchTasks(%+v) = %v, want ErrInvalidInput", opts, err)
+		}
+	}
+}
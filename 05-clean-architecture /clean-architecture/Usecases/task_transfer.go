@@ -0,0 +1,161 @@
+package Usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"taskmanager/auth/Domain"
+)
+
+// errImportRolledBack rolls back an import with a row that failed
+var errImportRolledBack = errors.New("import rolled back")
+
+// ExportTasks calls emit with every task GetAllTasks would list for opts, in
+// their list order. Tasks are read a page at a time, so exports of any size
+// stay out of memory; opts.Limit and opts.Cursor are ignored.
+func (uc *TaskUseCase) ExportTasks(ctx context.Context, policy *Domain.Policy, opts Domain.TaskListOptions, emit func(task Domain.Task) error) error {
+	opts.Limit = Domain.MaxTaskPageSize
+	opts.Cursor = ""
+
+	for {
+		page, err := uc.GetAllTasks(ctx, policy, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, task := range page.Tasks {
+			if err := emit(task); err != nil {
+				return err
+			}
+		}
+
+		if page.NextCursor == "" {
+			return nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// ImportTasks creates a task for every row through CreateTask. Imports are
+// all or nothing: every row is checked before anything is written, and if
+// any row fails, nothing is imported and the results report each row's
+// error. A dry run stops after the check.
+//
+// The rows are then created in a transaction. Without transactions (a
+// standalone MongoDB) they are created one at a time instead, so a storage
+// error part way keeps the rows created before it; the results tell which.
+//
+// Rows create standalone tasks of the caller, so parent and blocker IDs
+// are ignored.
+func (uc *TaskUseCase) ImportTasks(ctx context.Context, policy *Domain.Policy, rows []Domain.TaskImportRow, dryRun bool) ([]Domain.TaskImportResult, error) {
+	if len(rows) == 0 || len(rows) > Domain.MaxImportRows {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	if !policy.Can(Domain.PermTasksWriteOwn) {
+		return nil, Domain.ErrForbidden
+	}
+
+	results := make([]Domain.TaskImportResult, len(rows))
+	failed := false
+	for i, row := range rows {
+		results[i] = Domain.TaskImportResult{Line: row.Line, Err: checkImportRow(row)}
+		failed = failed || results[i].Err != nil
+	}
+	if dryRun || failed {
+		return results, nil
+	}
+
+	var events *pendingEvents
+	err := uc.withinTransaction(ctx, func(ctx context.Context, repos Domain.TaskRepositories) error {
+		// The transaction may run this more than once, so start over each time
+		events = &pendingEvents{TaskEventBus: uc.events}
+		results = uc.withRepositories(repos, events).importRows(ctx, policy, rows)
+		for _, result := range results {
+			if result.Err != nil {
+				// Keep the cause, so the transactor can tell a server
+				// without transactions
+				return fmt.Errorf("%w: %w", errImportRolledBack, result.Err)
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, Domain.ErrNoTransactions) {
+		return uc.importRows(ctx, policy, rows), nil
+	}
+	if errors.Is(err, errImportRolledBack) {
+		for i := range results {
+			results[i].Task = nil
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	events.flush()
+	return results, nil
+}
+
+func (uc *TaskUseCase) importRows(ctx context.Context, policy *Domain.Policy, rows []Domain.TaskImportRow) []Domain.TaskImportResult {
+	results := make([]Domain.TaskImportResult, len(rows))
+	for i, row := range rows {
+		results[i].Line = row.Line
+		if err := checkImportRow(row); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		results[i].Task, results[i].Err = uc.CreateTask(ctx, importedTask(row), policy)
+	}
+	return results
+}
+
+// importedTask is the task a row creates: a standalone task, since parent
+// and blocker IDs from another account or server mean nothing here
+func importedTask(row Domain.TaskImportRow) Domain.CreateTaskRequest {
+	req := row.Task
+	req.ParentID = ""
+	req.BlockedBy = nil
+	req.Force = false
+	return req
+}
+
+// checkImportRow returns why a row cannot be imported, as far as its
+// fields tell
+func checkImportRow(row Domain.TaskImportRow) error {
+	if row.Err != nil {
+		return row.Err
+	}
+	return validateImportedTask(importedTask(row))
+}
+
+// validateImportedTask checks the fields CreateTask would reject, to tell
+// which one is wrong
+func validateImportedTask(req Domain.CreateTaskRequest) error {
+	if strings.TrimSpace(req.Title) == "" {
+		return &Domain.FieldError{Field: "title", Message: "is required"}
+	}
+
+	if err := checkTaskText(req.Title, req.Description, req.Recurrence); err != nil {
+		return err
+	}
+
+	if req.Priority != "" && !req.Priority.IsValid() {
+		return &Domain.FieldError{Field: "priority", Message: "must be low, medium, high or urgent"}
+	}
+
+	if _, err := normalizeTags(req.Tags); err != nil {
+		return &Domain.FieldError{Field: "tags", Message: "must be at most 20 tags of 1 to 32 characters"}
+	}
+
+	if req.Recurrence != "" {
+		if _, err := normalizeRecurrence(req.Recurrence, req.DueAt); err != nil {
+			return &Domain.FieldError{Field: "recurrence", Message: "must be a supported RRULE on a task with a due date"}
+		}
+	}
+
+	return nil
+}
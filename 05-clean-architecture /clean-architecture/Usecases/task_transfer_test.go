@@ -0,0 +1,71 @@
+package Usecases
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"taskmanager/auth/Domain"
+)
+
+func TestImportTasks(t *testing.T) {
+	valid := []Domain.TaskImportRow{
+		{Line: 2, Task: Domain.CreateTaskRequest{Title: "Write report"}},
+		{Line: 3, Task: Domain.CreateTaskRequest{Title: "Send report", Priority: Domain.PriorityHigh}},
+	}
+	invalid := append(valid[:1:1], Domain.TaskImportRow{Line: 3, Task: Domain.CreateTaskRequest{Title: "Send report", Priority: "someday"}})
+
+	tests := []struct {
+		name string
+		rows []Domain.TaskImportRow
+		// withoutTransactions runs the import with no transactor, as on a
+		// standalone MongoDB
+		withoutTransactions bool
+		dryRun              bool
+		wantFailed          []int
+		wantTasks           int
+	}{
+		{"import", valid, false, false, nil, 2},
+		{"import without transactions", valid, true, false, nil, 2},
+		{"dry run", valid, false, true, nil, 0},
+		{"dry run without transactions", valid, true, true, nil, 0},
+		{"invalid row", invalid, false, false, []int{3}, 0},
+		{"invalid row without transactions", invalid, true, false, []int{3}, 0},
+		{"invalid row in a dry run", invalid, false, true, []int{3}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tt := newTaskTest(t)
+			if test.withoutTransactions {
+				tt.uc.transactor = nil
+			}
+
+			results, err := tt.uc.ImportTasks(context.Background(), tt.policy, test.rows, test.dryRun)
+			if err != nil {
+				t.Fatalf("ImportTasks: %v", err)
+			}
+
+			var failed []int
+			for _, result := range results {
+				if result.Err != nil {
+					failed = append(failed, result.Line)
+				}
+				if imported := result.Task != nil; imported != (test.wantTasks > 0) {
+					t.Errorf("line %d imported = %v, want %v", result.Line, imported, test.wantTasks > 0)
+				}
+			}
+			if !slices.Equal(failed, test.wantFailed) {
+				t.Errorf("failed lines = %v, want %v", failed, test.wantFailed)
+			}
+
+			scope, _ := tt.policy.TaskScope(Domain.TaskAccessRead)
+			page, err := tt.tasks.GetAll(context.Background(), scope, Domain.TaskListOptions{})
+			if err != nil {
+				t.Fatalf("GetAll: %v", err)
+			}
+			if len(page.Tasks) != test.wantTasks {
+				t.Errorf("stored %d tasks, want %d", len(page.Tasks), test.wantTasks)
+			}
+		})
+	}
+}
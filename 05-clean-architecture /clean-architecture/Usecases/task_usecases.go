@@ -1,7 +1,12 @@
 package Usecases
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
@@ -9,22 +14,37 @@ import (
 )
 
 type TaskUseCase struct {
-	taskRepo Domain.TaskRepository
+	taskRepo     Domain.TaskRepository
+	revisionRepo Domain.TaskRevisionRepository
+	userRepo     Domain.UserRepository
+	auditRepo    Domain.AuditRepository
+	transactor   Domain.TaskTransactor
+	events       Domain.TaskEventBus
 }
 
-func NewTaskUseCase(taskRepo Domain.TaskRepository) *TaskUseCase {
+func NewTaskUseCase(taskRepo Domain.TaskRepository, revisionRepo Domain.TaskRevisionRepository, userRepo Domain.UserRepository, auditRepo Domain.AuditRepository, transactor Domain.TaskTransactor, events Domain.TaskEventBus) *TaskUseCase {
 	return &TaskUseCase{
-		taskRepo: taskRepo,
+		taskRepo:     taskRepo,
+		revisionRepo: revisionRepo,
+		userRepo:     userRepo,
+		auditRepo:    auditRepo,
+		transactor:   transactor,
+		events:       events,
 	}
 }
 
-func (uc *TaskUseCase) GetTask(id string, userID primitive.ObjectID, isAdmin bool) (*Domain.Task, error) {
+func (uc *TaskUseCase) GetTask(ctx context.Context, id string, policy *Domain.Policy) (*Domain.Task, error) {
 	taskID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, Domain.ErrInvalidID
 	}
 
-	task, err := uc.taskRepo.GetByID(taskID, userID, isAdmin)
+	scope, err := policy.TaskScope(Domain.TaskAccessRead)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := uc.taskRepo.GetByID(ctx, taskID, scope)
 	if err != nil {
 		return nil, err
 	}
@@ -32,36 +52,134 @@ func (uc *TaskUseCase) GetTask(id string, userID primitive.ObjectID, isAdmin boo
 	return task, nil
 }
 
-func (uc *TaskUseCase) GetAllTasks(userID primitive.ObjectID, isAdmin bool) ([]Domain.Task, error) {
-	return uc.taskRepo.GetAll(userID, isAdmin)
+func (uc *TaskUseCase) GetAllTasks(ctx context.Context, policy *Domain.Policy, opts Domain.TaskListOptions) (*Domain.TaskPage, error) {
+	if opts.SortBy != "" && !opts.SortBy.IsValid() {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	if opts.Limit < 0 {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	if opts.Filter.Priority != "" && !opts.Filter.Priority.IsValid() {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	// Overdue tasks are incomplete by definition
+	if opts.Filter.Overdue && opts.Filter.Completed != nil && *opts.Filter.Completed {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	if opts.Filter.Tag != "" {
+		opts.Filter.Tag = normalizeTag(opts.Filter.Tag)
+	}
+
+	scope, err := policy.TaskScope(Domain.TaskAccessRead)
+	if err != nil {
+		return nil, err
+	}
+	scope.ExcludeShared = !opts.IncludeShared
+
+	return uc.taskRepo.GetAll(ctx, scope, opts.WithDefaults())
 }
 
-func (uc *TaskUseCase) CreateTask(req Domain.CreateTaskRequest, userID primitive.ObjectID) (*Domain.Task, error) {
+func (uc *TaskUseCase) CreateTask(ctx context.Context, req Domain.CreateTaskRequest, policy *Domain.Policy) (*Domain.Task, error) {
+	// Tasks are always created for the caller
+	if !policy.Can(Domain.PermTasksWriteOwn) {
+		return nil, Domain.ErrForbidden
+	}
+
+	priority := req.Priority
+	if priority == "" {
+		priority = Domain.PriorityMedium
+	}
+	if !priority.IsValid() {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	taskID := primitive.NewObjectID()
+
+	var recurrence string
+	var seriesID *primitive.ObjectID
+	if req.Recurrence != "" {
+		if recurrence, err = normalizeRecurrence(req.Recurrence, req.DueAt); err != nil {
+			return nil, err
+		}
+		seriesID = &taskID
+	}
+
+	var parentID *primitive.ObjectID
+	if req.ParentID != "" {
+		if parentID, err = uc.resolveParent(ctx, taskID, req.ParentID, policy); err != nil {
+			return nil, err
+		}
+	}
+
+	var blockers []primitive.ObjectID
+	if len(req.BlockedBy) > 0 {
+		if blockers, err = uc.resolveBlockers(ctx, taskID, req.BlockedBy, policy); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Completed && !req.Force {
+		if err := uc.checkBlockers(ctx, blockers); err != nil {
+			return nil, err
+		}
+	}
+
 	now := time.Now()
 	task := &Domain.Task{
-		ID:          primitive.NewObjectID(),
+		ID:          taskID,
 		Title:       req.Title,
 		Description: req.Description,
 		Completed:   req.Completed,
+		DueAt:       req.DueAt,
+		Priority:    priority,
+		Tags:        tags,
 		CreatedAt:   now,
 		UpdatedAt:   now,
-		UserID:      userID,
+		UserID:      policy.UserID,
+		ParentID:    parentID,
+		BlockedBy:   blockers,
+		Recurrence:  recurrence,
+		SeriesID:    seriesID,
+		Revision:    1,
+	}
+	if seriesID != nil {
+		task.Occurrence = 1
 	}
 
-	err := uc.taskRepo.Create(task)
+	err = uc.taskRepo.Create(ctx, task)
 	if err != nil {
 		return nil, err
 	}
 
+	uc.recordTaskChange(ctx, Domain.AuditTaskCreated, policy, nil, task, "")
+
 	return task, nil
 }
 
-func (uc *TaskUseCase) UpdateTask(id string, userID primitive.ObjectID, isAdmin bool, req Domain.UpdateTaskRequest) (*Domain.Task, error) {
+// UpdateTask applies the changes in req. A non-zero revision is the one the
+// caller last read; if the task has changed since, nothing is updated and
+// ErrRevisionMismatch is returned.
+func (uc *TaskUseCase) UpdateTask(ctx context.Context, id string, policy *Domain.Policy, req Domain.UpdateTaskRequest, revision int) (*Domain.Task, error) {
 	taskID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, Domain.ErrInvalidID
 	}
 
+	scope, err := policy.TaskScope(Domain.TaskAccessWrite)
+	if err != nil {
+		return nil, err
+	}
+	scope.Revision = revision
+
 	updates := make(map[string]interface{})
 
 	if req.Title != "" {
@@ -76,20 +194,328 @@ func (uc *TaskUseCase) UpdateTask(id string, userID primitive.ObjectID, isAdmin
 		updates["completed"] = *req.Completed
 	}
 
+	if req.DueAt != nil {
+		updates["due_at"] = *req.DueAt
+	}
+
+	if req.Priority != "" {
+		if !req.Priority.IsValid() {
+			return nil, Domain.ErrInvalidInput
+		}
+		updates["priority"] = req.Priority
+	}
+
+	if req.Tags != nil {
+		tags, err := normalizeTags(*req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		updates["tags"] = tags
+	}
+
+	if req.ParentID != nil {
+		var parentID *primitive.ObjectID
+		if *req.ParentID != "" {
+			if parentID, err = uc.resolveParent(ctx, taskID, *req.ParentID, policy); err != nil {
+				return nil, err
+			}
+		}
+		updates["parent_id"] = parentID
+	}
+
+	var blockers []primitive.ObjectID
+	if req.BlockedBy != nil {
+		if blockers, err = uc.resolveBlockers(ctx, taskID, *req.BlockedBy, policy); err != nil {
+			return nil, err
+		}
+		updates["blocked_by"] = blockers
+	}
+
+	if req.Completed != nil && *req.Completed && !req.Force {
+		if req.BlockedBy == nil {
+			task, err := uc.taskRepo.GetByID(ctx, taskID, scope)
+			if err != nil {
+				return nil, err
+			}
+			blockers = task.BlockedBy
+		}
+		if err := uc.checkBlockers(ctx, blockers); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Recurrence != nil {
+		if *req.Recurrence == "" {
+			updates["recurrence"] = ""
+		} else {
+			task, err := uc.taskRepo.GetByID(ctx, taskID, scope)
+			if err != nil {
+				return nil, err
+			}
+			dueAt := task.DueAt
+			if req.DueAt != nil {
+				dueAt = req.DueAt
+			}
+			if updates["recurrence"], err = normalizeRecurrence(*req.Recurrence, dueAt); err != nil {
+				return nil, err
+			}
+			// A task that starts repeating starts a new series
+			if task.SeriesID == nil {
+				updates["series_id"] = taskID
+				updates["occurrence"] = 1
+			}
+		}
+	}
+
+	return uc.updateTask(ctx, taskID, scope, policy, updates, Domain.AuditTaskUpdated, "")
+}
+
+// updateTask writes validated updates to a task in scope and records the
+// change as action. A non-zero scope.Revision must still be the task's;
+// without updates the task is returned unchanged.
+func (uc *TaskUseCase) updateTask(ctx context.Context, taskID primitive.ObjectID, scope Domain.TaskScope, policy *Domain.Policy, updates map[string]interface{}, action Domain.AuditAction, detail string) (*Domain.Task, error) {
+	before, err := uc.taskRepo.GetByID(ctx, taskID, scope)
+	if err != nil {
+		return nil, err
+	}
+	if scope.Revision != 0 && before.Revision != scope.Revision {
+		return nil, Domain.ErrRevisionMismatch
+	}
+
 	if len(updates) == 0 {
 		// No updates provided
-		task, err := uc.taskRepo.GetByID(taskID, userID, isAdmin)
-		return task, err
+		return before, nil
+	}
+
+	task, err := uc.taskRepo.Update(ctx, taskID, scope, updates)
+	if err != nil {
+		return nil, err
 	}
 
-	return uc.taskRepo.Update(taskID, userID, isAdmin, updates)
+	uc.recordTaskChange(ctx, action, policy, before, task, detail)
+
+	// Create the next occurrence right away instead of on the scheduler's
+	// next pass. The update is already written, so a failure does not fail
+	// the request; the scheduler retries.
+	if task.Completed && task.Recurrence != "" && !task.Recurred {
+		if err := uc.createNextOccurrence(ctx, *task, time.Now()); err != nil {
+			log.Printf("Failed to create the next occurrence of task %s: %v", task.ID.Hex(), err)
+		}
+	}
+
+	return task, nil
 }
 
-func (uc *TaskUseCase) DeleteTask(id string, userID primitive.ObjectID, isAdmin bool) error {
+// DeleteTask moves a task to the trash. As with UpdateTask, a non-zero
+// revision makes the delete conditional on the task not having changed.
+func (uc *TaskUseCase) DeleteTask(ctx context.Context, id string, policy *Domain.Policy, revision int) error {
 	taskID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return Domain.ErrInvalidID
 	}
 
-	return uc.taskRepo.Delete(taskID, userID, isAdmin)
+	// Only the owner can delete a task, editors cannot
+	scope, err := policy.TaskScope(Domain.TaskAccessManage)
+	if err != nil {
+		return err
+	}
+	scope.Revision = revision
+
+	before, err := uc.taskRepo.GetByID(ctx, taskID, scope)
+	if err != nil {
+		return err
+	}
+	if revision != 0 && before.Revision != revision {
+		return Domain.ErrRevisionMismatch
+	}
+
+	// Deleted tasks go to the trash, see UndeleteTask
+	task, err := uc.taskRepo.Delete(ctx, taskID, scope)
+	if err != nil {
+		return err
+	}
+
+	uc.recordTaskChange(ctx, Domain.AuditTaskDeleted, policy, before, task, "")
+
+	return nil
+}
+
+// ShareTask gives another user viewer or editor access to a task, or changes
+// their level if it is already shared with them. Only the owner can share.
+func (uc *TaskUseCase) ShareTask(ctx context.Context, id string, policy *Domain.Policy, req Domain.ShareTaskRequest) (*Domain.Task, error) {
+	taskID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, Domain.ErrInvalidID
+	}
+
+	if !req.Level.IsValid() {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	scope, err := policy.TaskScope(Domain.TaskAccessManage)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := uc.taskRepo.GetByID(ctx, taskID, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.GetByUsername(ctx, req.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	// The owner already has full access
+	if user.ID == task.UserID {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	shared, err := uc.taskRepo.SetCollaborator(ctx, taskID, scope, Domain.Collaborator{
+		UserID:   user.ID,
+		Level:    req.Level,
+		SharedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.recordTaskChange(ctx, Domain.AuditTaskShared, policy, task, shared, "")
+
+	return shared, nil
+}
+
+// UnshareTask removes a collaborator. The owner can remove anyone, and
+// collaborators can remove themselves.
+func (uc *TaskUseCase) UnshareTask(ctx context.Context, id string, policy *Domain.Policy, collaboratorID string) (*Domain.Task, error) {
+	taskID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, Domain.ErrInvalidID
+	}
+
+	userID, err := primitive.ObjectIDFromHex(collaboratorID)
+	if err != nil {
+		return nil, Domain.ErrInvalidID
+	}
+
+	access := Domain.TaskAccessManage
+	if userID == policy.UserID {
+		access = Domain.TaskAccessRead
+	}
+
+	scope, err := policy.TaskScope(access)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := uc.taskRepo.GetByID(ctx, taskID, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := uc.taskRepo.RemoveCollaborator(ctx, taskID, scope, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.recordTaskChange(ctx, Domain.AuditTaskUnshared, policy, before, task, "")
+
+	return task, nil
+}
+
+// recordTaskChange audits a change the policy's user made to a task, adds
+// the new version to its history and publishes it as an event. before is
+// nil for created tasks; detail, if any, goes into the audit entry.
+func (uc *TaskUseCase) recordTaskChange(ctx context.Context, action Domain.AuditAction, policy *Domain.Policy, before, after *Domain.Task, detail string) {
+	actorID := policy.UserID
+	uc.recordRevision(ctx, action, &actorID, after)
+
+	changes := auditChanges(before, after)
+	if action == Domain.AuditTaskUpdated && changes == nil {
+		return
+	}
+
+	recordAudit(ctx, uc.auditRepo, Domain.AuditEntry{
+		Action:     action,
+		ActorID:    &actorID,
+		EntityType: Domain.AuditEntityTask,
+		EntityID:   &after.ID,
+		Changes:    changes,
+		Detail:     detail,
+	})
+
+	uc.publishTaskEvent(before, after)
+}
+
+// recordRevision adds a snapshot of the task to its history. Like audit
+// entries, snapshots are written even if the request has been canceled,
+// and one that cannot be stored is logged and skipped.
+func (uc *TaskUseCase) recordRevision(ctx context.Context, action Domain.AuditAction, actorID *primitive.ObjectID, task *Domain.Task) {
+	err := uc.revisionRepo.Append(context.WithoutCancel(ctx), &Domain.TaskRevision{
+		TaskID:    task.ID,
+		Revision:  task.Revision,
+		Action:    action,
+		ActorID:   actorID,
+		Task:      *task,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to record revision %d of task %s: %v", task.Revision, task.ID.Hex(), err)
+	}
+}
+
+// withinTransaction runs fn through the transactor, if there is one
+func (uc *TaskUseCase) withinTransaction(ctx context.Context, fn func(ctx context.Context, repos Domain.TaskRepositories) error) error {
+	if uc.transactor == nil {
+		return Domain.ErrNoTransactions
+	}
+	return uc.transactor.WithinTransaction(ctx, fn)
+}
+
+// normalizeTags lowercases and trims tags and drops duplicates, so filtering
+// by tag is case-insensitive
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > Domain.MaxTagsPerTask {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || len(tag) > Domain.MaxTagLength {
+			return nil, Domain.ErrInvalidInput
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	return normalized, nil
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// checkTaskText checks the length of a task's text fields, for tasks that
+// do not come from a request body checked by its binding rules
+func checkTaskText(title, description, recurrence string) error {
+	fields := []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"title", title, Domain.MaxTitleLength},
+		{"description", description, Domain.MaxDescriptionLength},
+		{"recurrence", recurrence, Domain.MaxRecurrenceLength},
+	}
+	for _, field := range fields {
+		if utf8.RuneCountInString(field.value) > field.max {
+			return &Domain.FieldError{Field: field.name, Message: fmt.Sprintf("must be at most %d characters", field.max)}
+		}
+	}
+	return nil
 }
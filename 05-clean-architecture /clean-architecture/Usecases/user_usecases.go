@@ -1,6 +1,9 @@
 package Usecases
 
 import (
+	"context"
+	"crypto/subtle"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,97 +14,421 @@ import (
 
 type UserUseCase struct {
 	userRepo        Domain.UserRepository
+	tokenRepo       Domain.TokenRepository
+	taskRepo        Domain.TaskRepository
+	webhookRepo     Domain.WebhookRepository
 	passwordService *Infrastructure.PasswordService
 	jwtService      *Infrastructure.JWTService
+	auditRepo       Domain.AuditRepository
+	bootstrapToken  string
+	bootstrapMu     sync.Mutex
 }
 
+// NewUserUseCase creates the user use case. bootstrapToken enables
+// BootstrapAdmin; leave it empty to turn the bootstrap flow off.
 func NewUserUseCase(
 	userRepo Domain.UserRepository,
+	tokenRepo Domain.TokenRepository,
+	taskRepo Domain.TaskRepository,
+	webhookRepo Domain.WebhookRepository,
 	passwordService *Infrastructure.PasswordService,
 	jwtService *Infrastructure.JWTService,
+	auditRepo Domain.AuditRepository,
+	bootstrapToken string,
 ) *UserUseCase {
 	return &UserUseCase{
 		userRepo:        userRepo,
+		tokenRepo:       tokenRepo,
+		taskRepo:        taskRepo,
+		webhookRepo:     webhookRepo,
 		passwordService: passwordService,
 		jwtService:      jwtService,
+		auditRepo:       auditRepo,
+		bootstrapToken:  bootstrapToken,
 	}
 }
 
-func (uc *UserUseCase) Register(req Domain.RegisterRequest) (*Domain.User, string, error) {
+func (uc *UserUseCase) Register(ctx context.Context, req Domain.RegisterRequest) (*Domain.User, *Domain.TokenPair, error) {
 	// Hash the password
 	hashedPassword, err := uc.passwordService.HashPassword(req.Password)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	// Set role to user if not specified
-	role := req.Role
-	if role == "" {
-		role = Domain.RoleUser
-	}
-
-	// Create the user
+	// Create the user. Everyone starts as a regular user; admins are made
+	// through BootstrapAdmin or by another admin.
 	now := time.Now()
 	user := &Domain.User{
 		ID:          primitive.NewObjectID(),
 		Username:    req.Username,
 		Password:    hashedPassword,
-		Role:        role,
+		Role:        Domain.RoleUser,
 		CreatedAt:   now,
 		LastLoginAt: now,
 	}
 
 	// Save the user to the repository
-	err = uc.userRepo.Create(user)
+	err = uc.userRepo.Create(ctx, user)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	// Generate JWT token
-	token, err := uc.jwtService.GenerateToken(user.ID.Hex(), user.Username, string(user.Role))
+	uc.recordUserChange(ctx, Domain.AuditUserRegistered, user.ID, nil, user, "")
+
+	// Start a new session
+	tokens, err := uc.issueTokens(ctx, user, primitive.NewObjectID().Hex())
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	return user, token, nil
+	return user, tokens, nil
 }
 
-func (uc *UserUseCase) Login(req Domain.LoginRequest) (*Domain.User, string, error) {
+func (uc *UserUseCase) Login(ctx context.Context, req Domain.LoginRequest) (*Domain.User, *Domain.TokenPair, error) {
 	// Find user by username
-	user, err := uc.userRepo.GetByUsername(req.Username)
+	user, err := uc.userRepo.GetByUsername(ctx, req.Username)
 	if err != nil {
 		if err == Domain.ErrNotFound {
-			return nil, "", Domain.ErrInvalidCredentials
+			uc.recordFailedLogin(ctx, req.Username, nil, "unknown_user")
+			return nil, nil, Domain.ErrInvalidCredentials
 		}
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	// Verify password
 	err = uc.passwordService.ComparePassword(user.Password, req.Password)
 	if err != nil {
-		return nil, "", Domain.ErrInvalidCredentials
+		uc.recordFailedLogin(ctx, req.Username, user, "invalid_password")
+		return nil, nil, Domain.ErrInvalidCredentials
+	}
+
+	if user.Disabled {
+		uc.recordFailedLogin(ctx, req.Username, user, "account_disabled")
+		return nil, nil, Domain.ErrAccountDisabled
 	}
 
 	// Update last login time
-	err = uc.userRepo.UpdateLastLogin(user.ID)
+	err = uc.userRepo.UpdateLastLogin(ctx, user.ID)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	// Generate JWT token
-	token, err := uc.jwtService.GenerateToken(user.ID.Hex(), user.Username, string(user.Role))
+	uc.recordUserChange(ctx, Domain.AuditUserLogin, user.ID, user, user, "")
+
+	// Start a new session
+	tokens, err := uc.issueTokens(ctx, user, primitive.NewObjectID().Hex())
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	return user, token, nil
+	return user, tokens, nil
 }
 
-func (uc *UserUseCase) GetUserByID(id string) (*Domain.User, error) {
+func (uc *UserUseCase) GetUserByID(ctx context.Context, id string) (*Domain.User, error) {
 	userID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, Domain.ErrInvalidID
 	}
 
-	return uc.userRepo.GetByID(userID)
+	return uc.userRepo.GetByID(ctx, userID)
+}
+
+func (uc *UserUseCase) ListUsers(ctx context.Context, opts Domain.UserListOptions) (*Domain.UserPage, error) {
+	if opts.Limit < 0 {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	if opts.Filter.Role != "" && !opts.Filter.Role.IsValid() {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	return uc.userRepo.List(ctx, opts.WithDefaults())
+}
+
+// SetUserRole promotes or demotes a user. Admins cannot change their own
+// role, so there is always at least one admin left.
+func (uc *UserUseCase) SetUserRole(ctx context.Context, actorID primitive.ObjectID, id string, role Domain.Role) (*Domain.User, error) {
+	if !role.IsValid() {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	return uc.updateOtherUser(ctx, actorID, id, Domain.AuditUserRoleChanged, func(userID primitive.ObjectID) error {
+		return uc.userRepo.UpdateRole(ctx, userID, Domain.RoleGrant{
+			Role:      role,
+			Method:    Domain.GrantMethodAdmin,
+			GrantedBy: &actorID,
+			GrantedAt: time.Now(),
+		})
+	})
+}
+
+// BootstrapAdmin makes the calling user the first admin. It requires the
+// operator-configured bootstrap token and only works while there is no
+// admin yet.
+func (uc *UserUseCase) BootstrapAdmin(ctx context.Context, userID primitive.ObjectID, token string) (*Domain.User, error) {
+	if uc.bootstrapToken == "" {
+		return nil, Domain.ErrBootstrapDisabled
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(uc.bootstrapToken)) != 1 {
+		return nil, Domain.ErrForbidden
+	}
+
+	// Serialize bootstraps so two requests cannot both see "no admin"
+	uc.bootstrapMu.Lock()
+	defer uc.bootstrapMu.Unlock()
+
+	admins, err := uc.userRepo.CountByRole(ctx, Domain.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if admins > 0 {
+		return nil, Domain.ErrAdminExists
+	}
+
+	before, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.userRepo.UpdateRole(ctx, userID, Domain.RoleGrant{
+		Role:      Domain.RoleAdmin,
+		Method:    Domain.GrantMethodBootstrap,
+		GrantedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.recordUserChange(ctx, Domain.AuditUserRoleChanged, userID, before, user, Domain.GrantMethodBootstrap)
+
+	return user, nil
+}
+
+// SetUserDisabled disables or re-enables a user. Disabled users cannot log
+// in, refresh tokens or use tokens they already hold.
+func (uc *UserUseCase) SetUserDisabled(ctx context.Context, actorID primitive.ObjectID, id string, disabled bool) (*Domain.User, error) {
+	action := Domain.AuditUserEnabled
+	if disabled {
+		action = Domain.AuditUserDisabled
+	}
+
+	return uc.updateOtherUser(ctx, actorID, id, action, func(userID primitive.ObjectID) error {
+		return uc.userRepo.SetDisabled(ctx, userID, disabled)
+	})
+}
+
+// DeleteUser deletes a user who has no tasks, trashed or shared ones
+// included, and no webhooks. Nothing is cascaded: a user with data is
+// rejected with ErrUserInUse, so nothing they own is left orphaned.
+func (uc *UserUseCase) DeleteUser(ctx context.Context, actorID primitive.ObjectID, id string) error {
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Domain.ErrInvalidID
+	}
+
+	if userID == actorID {
+		return Domain.ErrForbidden
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	inUse, err := uc.hasData(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return Domain.ErrUserInUse
+	}
+
+	if err := uc.userRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	uc.recordUserChange(ctx, Domain.AuditUserDeleted, actorID, user, nil, "")
+
+	return nil
+}
+
+// hasData reports whether the user owns or shares a task, live or in the
+// trash, or has a webhook
+func (uc *UserUseCase) hasData(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	for _, deleted := range []bool{false, true} {
+		scope := Domain.TaskScope{UserID: userID, Access: Domain.TaskAccessRead, Deleted: deleted}
+		page, err := uc.taskRepo.GetAll(ctx, scope, Domain.TaskListOptions{Limit: 1})
+		if err != nil {
+			return false, err
+		}
+		if len(page.Tasks) > 0 {
+			return true, nil
+		}
+	}
+
+	webhooks, err := uc.webhookRepo.ListByUsers(ctx, []primitive.ObjectID{userID})
+	if err != nil {
+		return false, err
+	}
+	return len(webhooks) > 0, nil
+}
+
+// updateOtherUser applies an admin change to a user other than the actor,
+// audits it and returns the updated user
+func (uc *UserUseCase) updateOtherUser(ctx context.Context, actorID primitive.ObjectID, id string, action Domain.AuditAction, update func(userID primitive.ObjectID) error) (*Domain.User, error) {
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, Domain.ErrInvalidID
+	}
+
+	if userID == actorID {
+		return nil, Domain.ErrForbidden
+	}
+
+	before, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := update(userID); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.recordUserChange(ctx, action, actorID, before, user, "")
+
+	return user, nil
+}
+
+// recordUserChange audits a change made by actorID to a user. before is nil
+// for new users and after for deleted ones.
+func (uc *UserUseCase) recordUserChange(ctx context.Context, action Domain.AuditAction, actorID primitive.ObjectID, before, after *Domain.User, detail string) {
+	user := before
+	if user == nil {
+		user = after
+	}
+
+	recordAudit(ctx, uc.auditRepo, Domain.AuditEntry{
+		Action:     action,
+		ActorID:    &actorID,
+		EntityType: Domain.AuditEntityUser,
+		EntityID:   &user.ID,
+		Changes:    auditChanges(before, after),
+		Detail:     detail,
+	})
+}
+
+// recordFailedLogin audits a rejected login. user is nil when the username
+// does not exist.
+func (uc *UserUseCase) recordFailedLogin(ctx context.Context, username string, user *Domain.User, reason string) {
+	entry := Domain.AuditEntry{
+		Action:        Domain.AuditUserLoginFailed,
+		ActorUsername: username,
+		EntityType:    Domain.AuditEntityUser,
+		Detail:        reason,
+	}
+	if user != nil {
+		entry.EntityID = &user.ID
+	}
+
+	recordAudit(ctx, uc.auditRepo, entry)
+}
+
+// RefreshTokens rotates a refresh token: the presented token is consumed and a
+// new access/refresh pair is issued in the same session. Presenting a token
+// that was already used revokes the whole session, since it means the token
+// was copied.
+func (uc *UserUseCase) RefreshTokens(ctx context.Context, refreshToken string) (*Domain.TokenPair, error) {
+	stored, err := uc.tokenRepo.GetRefreshToken(ctx, uc.jwtService.HashRefreshToken(refreshToken))
+	if err != nil {
+		if err == Domain.ErrNotFound {
+			return nil, Domain.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, Domain.ErrInvalidToken
+	}
+
+	if stored.UsedAt == nil {
+		err = uc.tokenRepo.MarkRefreshTokenUsed(ctx, stored.ID)
+	} else {
+		err = Domain.ErrTokenReused
+	}
+	if err != nil {
+		if err == Domain.ErrTokenReused {
+			if err := uc.tokenRepo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+				return nil, err
+			}
+			return nil, Domain.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	// Reload the user so role changes take effect on refresh
+	user, err := uc.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		if err == Domain.ErrNotFound {
+			return nil, Domain.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if user.Disabled {
+		return nil, Domain.ErrAccountDisabled
+	}
+
+	return uc.issueTokens(ctx, user, stored.FamilyID)
+}
+
+// Logout revokes every refresh token of the session and the access token
+// used to make the request
+func (uc *UserUseCase) Logout(ctx context.Context, sessionID, tokenID string, tokenExpiresAt time.Time) error {
+	if err := uc.tokenRepo.RevokeFamily(ctx, sessionID); err != nil {
+		return err
+	}
+
+	return uc.tokenRepo.RevokeTokenID(ctx, tokenID, tokenExpiresAt)
+}
+
+func (uc *UserUseCase) issueTokens(ctx context.Context, user *Domain.User, sessionID string) (*Domain.TokenPair, error) {
+	accessToken, err := uc.jwtService.GenerateToken(user.ID.Hex(), user.Username, string(user.Role), sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := uc.jwtService.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = uc.tokenRepo.CreateRefreshToken(ctx, &Domain.RefreshToken{
+		ID:        primitive.NewObjectID(),
+		TokenHash: uc.jwtService.HashRefreshToken(refreshToken),
+		FamilyID:  sessionID,
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(uc.jwtService.RefreshExpiration()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Domain.TokenPair{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(uc.jwtService.TokenExpiration().Seconds()),
+	}, nil
 }
@@ -0,0 +1,78 @@
+package Usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+	"taskmanager/auth/Repositories"
+)
+
+func TestDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	admin := primitive.NewObjectID()
+
+	tests := []struct {
+		name string
+		// setup gives the user data, if any
+		setup   func(t *testing.T, userID primitive.ObjectID, tasks *Repositories.InMemoryTaskRepository, webhooks *Repositories.InMemoryWebhookRepository)
+		wantErr error
+	}{
+		{"no data", nil, nil},
+		{"owns a task", func(t *testing.T, userID primitive.ObjectID, tasks *Repositories.InMemoryTaskRepository, _ *Repositories.InMemoryWebhookRepository) {
+			if err := tasks.Create(ctx, &Domain.Task{ID: primitive.NewObjectID(), Title: "Task", UserID: userID}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}, Domain.ErrUserInUse},
+		{"task in the trash", func(t *testing.T, userID primitive.ObjectID, tasks *Repositories.InMemoryTaskRepository, _ *Repositories.InMemoryWebhookRepository) {
+			deletedAt := time.Now()
+			if err := tasks.Create(ctx, &Domain.Task{ID: primitive.NewObjectID(), Title: "Task", UserID: userID, DeletedAt: &deletedAt}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}, Domain.ErrUserInUse},
+		{"task shared with them", func(t *testing.T, userID primitive.ObjectID, tasks *Repositories.InMemoryTaskRepository, _ *Repositories.InMemoryWebhookRepository) {
+			task := &Domain.Task{
+				ID:            primitive.NewObjectID(),
+				Title:         "Task",
+				UserID:        primitive.NewObjectID(),
+				Collaborators: []Domain.Collaborator{{UserID: userID, Level: Domain.ShareLevelViewer}},
+			}
+			if err := tasks.Create(ctx, task); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}, Domain.ErrUserInUse},
+		{"has a webhook", func(t *testing.T, userID primitive.ObjectID, _ *Repositories.InMemoryTaskRepository, webhooks *Repositories.InMemoryWebhookRepository) {
+			if err := webhooks.Create(ctx, &Domain.Webhook{ID: primitive.NewObjectID(), UserID: userID, URL: "https://example.com/hook"}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}, Domain.ErrUserInUse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := Repositories.NewInMemoryUserRepository()
+			tasks := Repositories.NewInMemoryTaskRepository()
+			webhooks := Repositories.NewInMemoryWebhookRepository()
+			uc := NewUserUseCase(users, Repositories.NewInMemoryTokenRepository(), tasks, webhooks, nil, nil,
+				Repositories.NewInMemoryAuditRepository(), "")
+
+			user := &Domain.User{ID: primitive.NewObjectID(), Username: "someone", Role: Domain.RoleUser}
+			if err := users.Create(ctx, user); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if tt.setup != nil {
+				tt.setup(t, user.ID, tasks, webhooks)
+			}
+
+			if err := uc.DeleteUser(ctx, admin, user.ID.Hex()); err != tt.wantErr {
+				t.Fatalf("DeleteUser = %v, want %v", err, tt.wantErr)
+			}
+			_, err := users.GetByID(ctx, user.ID)
+			if deleted := err == Domain.ErrNotFound; deleted != (tt.wantErr == nil) {
+				t.Errorf("user deleted = %v, want %v", deleted, tt.wantErr == nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,492 @@
+package Usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+)
+
+// webhookDispatchBatch is how many deliveries are sent at once
+const webhookDispatchBatch = 10
+
+// maxWebhookErrorLength keeps delivery logs small when sending fails
+const maxWebhookErrorLength = 200
+
+// WebhookUseCase manages the webhooks of users and delivers task events to
+// them. Events are stored as deliveries when they are published and sent
+// by DispatchDue, which retries failed ones with exponential backoff until
+// they run out of attempts and end up in the dead-letter list.
+type WebhookUseCase struct {
+	webhookRepo  Domain.WebhookRepository
+	deliveryRepo Domain.WebhookDeliveryRepository
+	auditRepo    Domain.AuditRepository
+	sender       Domain.WebhookSender
+	wake         chan struct{}
+	// allowPrivateAddresses lets webhook URLs point at hosts that are not
+	// public, such as localhost; it is meant for development only
+	allowPrivateAddresses bool
+}
+
+func NewWebhookUseCase(webhookRepo Domain.WebhookRepository, deliveryRepo Domain.WebhookDeliveryRepository, auditRepo Domain.AuditRepository, sender Domain.WebhookSender, allowPrivateAddresses bool) *WebhookUseCase {
+	return &WebhookUseCase{
+		webhookRepo:           webhookRepo,
+		deliveryRepo:          deliveryRepo,
+		auditRepo:             auditRepo,
+		sender:                sender,
+		wake:                  make(chan struct{}, 1),
+		allowPrivateAddresses: allowPrivateAddresses,
+	}
+}
+
+// CreateWebhook adds a webhook for the caller and returns it with its
+// secret, which is generated if the request has none
+func (uc *WebhookUseCase) CreateWebhook(ctx context.Context, req Domain.CreateWebhookRequest, policy *Domain.Policy) (*Domain.Webhook, string, error) {
+	if err := uc.validateWebhookURL(ctx, req.URL); err != nil {
+		return nil, "", err
+	}
+
+	var events []Domain.TaskEventType
+	for _, eventType := range req.Events {
+		if !eventType.IsValid() {
+			return nil, "", &Domain.FieldError{Field: "events", Message: "must be task.created, task.updated or task.deleted"}
+		}
+		if !slices.Contains(events, eventType) {
+			events = append(events, eventType)
+		}
+	}
+
+	existing, err := uc.webhookRepo.ListByUsers(ctx, []primitive.ObjectID{policy.UserID})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(existing) >= Domain.MaxWebhooksPerUser {
+		return nil, "", Domain.ErrTooManyWebhooks
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = generateWebhookSecret(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	webhook := &Domain.Webhook{
+		ID:        primitive.NewObjectID(),
+		UserID:    policy.UserID,
+		URL:       req.URL,
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+	if webhook.Events == nil {
+		webhook.Events = []Domain.TaskEventType{}
+	}
+
+	if err := uc.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, "", err
+	}
+
+	actorID := policy.UserID
+	recordAudit(ctx, uc.auditRepo, Domain.AuditEntry{
+		Action:     Domain.AuditWebhookCreated,
+		ActorID:    &actorID,
+		EntityType: Domain.AuditEntityWebhook,
+		EntityID:   &webhook.ID,
+		Changes:    auditChanges(nil, webhook),
+	})
+
+	return webhook, secret, nil
+}
+
+// ListWebhooks returns the caller's webhooks, oldest first
+func (uc *WebhookUseCase) ListWebhooks(ctx context.Context, policy *Domain.Policy) ([]Domain.Webhook, error) {
+	return uc.webhookRepo.ListByUsers(ctx, []primitive.ObjectID{policy.UserID})
+}
+
+// GetWebhook returns one of the caller's webhooks. Other users' webhooks
+// are not found.
+func (uc *WebhookUseCase) GetWebhook(ctx context.Context, id string, policy *Domain.Policy) (*Domain.Webhook, error) {
+	webhookID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, Domain.ErrInvalidID
+	}
+
+	webhook, err := uc.webhookRepo.GetByID(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.UserID != policy.UserID {
+		return nil, Domain.ErrNotFound
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes one of the caller's webhooks along with its
+// deliveries, so pending ones are never sent
+func (uc *WebhookUseCase) DeleteWebhook(ctx context.Context, id string, policy *Domain.Policy) error {
+	webhook, err := uc.GetWebhook(ctx, id, policy)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.webhookRepo.Delete(ctx, webhook.ID); err != nil {
+		return err
+	}
+	if err := uc.deliveryRepo.DeleteByWebhook(ctx, webhook.ID); err != nil {
+		log.Printf("Failed to delete deliveries of webhook %s: %v", webhook.ID.Hex(), err)
+	}
+
+	actorID := policy.UserID
+	recordAudit(ctx, uc.auditRepo, Domain.AuditEntry{
+		Action:     Domain.AuditWebhookDeleted,
+		ActorID:    &actorID,
+		EntityType: Domain.AuditEntityWebhook,
+		EntityID:   &webhook.ID,
+		Changes:    auditChanges(webhook, nil),
+	})
+	return nil
+}
+
+// ListDeliveries returns the delivery log of one of the caller's webhooks,
+// newest first
+func (uc *WebhookUseCase) ListDeliveries(ctx context.Context, id string, policy *Domain.Policy, status Domain.WebhookDeliveryStatus, limit int) ([]Domain.WebhookDelivery, error) {
+	if status != "" && !status.IsValid() {
+		return nil, Domain.ErrInvalidInput
+	}
+
+	webhook, err := uc.GetWebhook(ctx, id, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.deliveryRepo.List(ctx, Domain.WebhookDeliveryFilter{
+		UserID:    policy.UserID,
+		WebhookID: &webhook.ID,
+		Status:    status,
+	}, webhookDeliveryLimit(limit))
+}
+
+// ListDeadLetters returns the caller's deliveries that ran out of
+// attempts, newest first
+func (uc *WebhookUseCase) ListDeadLetters(ctx context.Context, policy *Domain.Policy, limit int) ([]Domain.WebhookDelivery, error) {
+	return uc.deliveryRepo.List(ctx, Domain.WebhookDeliveryFilter{
+		UserID: policy.UserID,
+		Status: Domain.WebhookDeliveryFailed,
+	}, webhookDeliveryLimit(limit))
+}
+
+// Redeliver sends the event of one of the caller's deliveries again, as a
+// new delivery with a fresh set of attempts
+func (uc *WebhookUseCase) Redeliver(ctx context.Context, id string, policy *Domain.Policy) (*Domain.WebhookDelivery, error) {
+	deliveryID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, Domain.ErrInvalidID
+	}
+
+	previous, err := uc.deliveryRepo.GetByID(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if previous.UserID != policy.UserID {
+		return nil, Domain.ErrNotFound
+	}
+
+	webhook, err := uc.webhookRepo.GetByID(ctx, previous.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := uc.enqueue(ctx, webhook, previous.Event, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	uc.signal()
+	return delivery, nil
+}
+
+// Wake receives a value when deliveries are waiting to be sent
+func (uc *WebhookUseCase) Wake() <-chan struct{} {
+	return uc.wake
+}
+
+// Publisher returns a Domain.TaskEventBus that stores a delivery for every
+// webhook interested in an event before passing it on to next. Only the
+// instance where a change happens publishes its event, so every event is
+// delivered once however many instances run.
+func (uc *WebhookUseCase) Publisher(next Domain.TaskEventBus) Domain.TaskEventBus {
+	return &webhookPublisher{TaskEventBus: next, webhooks: uc}
+}
+
+type webhookPublisher struct {
+	Domain.TaskEventBus
+	webhooks *WebhookUseCase
+}
+
+func (p *webhookPublisher) Publish(event Domain.TaskEvent) {
+	// Deliveries carry the same ID and time as the event subscribers see
+	if event.ID == "" {
+		event.ID = primitive.NewObjectID().Hex()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	// The change has already happened, so its deliveries are stored
+	// whatever became of the request that made it
+	p.webhooks.enqueueEvent(context.Background(), event)
+	p.TaskEventBus.Publish(event)
+}
+
+// enqueueEvent stores a delivery of the event for every webhook of its
+// viewers that wants it. Like audit entries, deliveries that cannot be
+// stored are logged and skipped.
+func (uc *WebhookUseCase) enqueueEvent(ctx context.Context, event Domain.TaskEvent) {
+	if len(event.Viewers) == 0 {
+		return
+	}
+
+	webhooks, err := uc.webhookRepo.ListByUsers(ctx, event.Viewers)
+	if err != nil {
+		log.Printf("Failed to look up webhooks for task event %s: %v", event.ID, err)
+		return
+	}
+
+	queued := false
+	for i := range webhooks {
+		if !webhooks[i].Wants(event) {
+			continue
+		}
+		if _, err := uc.enqueue(ctx, &webhooks[i], event, event.OccurredAt); err != nil {
+			log.Printf("Failed to queue task event %s for webhook %s: %v", event.ID, webhooks[i].ID.Hex(), err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		uc.signal()
+	}
+}
+
+func (uc *WebhookUseCase) enqueue(ctx context.Context, webhook *Domain.Webhook, event Domain.TaskEvent, now time.Time) (*Domain.WebhookDelivery, error) {
+	delivery := &Domain.WebhookDelivery{
+		ID:            primitive.NewObjectID(),
+		WebhookID:     webhook.ID,
+		UserID:        webhook.UserID,
+		Event:         event,
+		Status:        Domain.WebhookDeliveryPending,
+		Attempts:      []Domain.WebhookAttempt{},
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}
+	if err := uc.deliveryRepo.Create(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// signal wakes the dispatcher without waiting for it
+func (uc *WebhookUseCase) signal() {
+	select {
+	case uc.wake <- struct{}{}:
+	default:
+	}
+}
+
+// DispatchDue sends every delivery due at now, a batch at a time, and
+// returns how many it attempted. Canceling ctx stops it; deliveries cut
+// short are sent again once their lease runs out.
+func (uc *WebhookUseCase) DispatchDue(ctx context.Context, now time.Time) (int, error) {
+	attempted := 0
+	for ctx.Err() == nil {
+		deliveries, err := uc.deliveryRepo.ClaimDue(ctx, now, Domain.WebhookDeliveryLease, webhookDispatchBatch)
+		if err != nil {
+			return attempted, err
+		}
+
+		var wg sync.WaitGroup
+		for i := range deliveries {
+			wg.Add(1)
+			go func(delivery *Domain.WebhookDelivery) {
+				defer wg.Done()
+				uc.deliver(ctx, delivery)
+			}(&deliveries[i])
+		}
+		wg.Wait()
+
+		attempted += len(deliveries)
+		if len(deliveries) < webhookDispatchBatch {
+			return attempted, nil
+		}
+	}
+	return attempted, ctx.Err()
+}
+
+// deliver makes one attempt at sending a claimed delivery and schedules
+// the next one if it failed
+func (uc *WebhookUseCase) deliver(ctx context.Context, delivery *Domain.WebhookDelivery) {
+	attempt := Domain.WebhookAttempt{At: time.Now()}
+
+	webhook, err := uc.webhookRepo.GetByID(ctx, delivery.WebhookID)
+	switch {
+	case err == Domain.ErrNotFound:
+		// Deleted while the delivery was being claimed; there is nothing
+		// left to send it to
+		attempt.Error = "webhook was deleted"
+		delivery.Attempts = append(delivery.Attempts, attempt)
+		delivery.Status = Domain.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+		uc.saveDelivery(ctx, delivery)
+		return
+	case err != nil:
+		// Try again once the lease runs out
+		log.Printf("Failed to look up webhook %s: %v", delivery.WebhookID.Hex(), err)
+		return
+	}
+
+	request, err := webhookRequest(webhook, delivery, attempt.At)
+	if err != nil {
+		log.Printf("Failed to build webhook delivery %s: %v", delivery.ID.Hex(), err)
+		return
+	}
+
+	attempt.StatusCode, err = uc.sender.Send(ctx, request)
+	attempt.DurationMS = time.Since(attempt.At).Milliseconds()
+	if ctx.Err() != nil {
+		// Stopped rather than failed, so it does not count as an attempt
+		return
+	}
+	switch {
+	case err != nil:
+		attempt.Error = truncateWebhookError(err.Error())
+	case attempt.StatusCode < 200 || attempt.StatusCode > 299:
+		attempt.Error = fmt.Sprintf("unexpected response %d %s", attempt.StatusCode, http.StatusText(attempt.StatusCode))
+	}
+	delivery.Attempts = append(delivery.Attempts, attempt)
+
+	switch {
+	case attempt.Error == "":
+		delivery.Status = Domain.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = nil
+	case len(delivery.Attempts) >= Domain.MaxWebhookAttempts:
+		delivery.Status = Domain.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+	default:
+		next := time.Now().Add(webhookRetryDelay(len(delivery.Attempts)))
+		delivery.NextAttemptAt = &next
+	}
+	uc.saveDelivery(ctx, delivery)
+}
+
+func (uc *WebhookUseCase) saveDelivery(ctx context.Context, delivery *Domain.WebhookDelivery) {
+	if err := uc.deliveryRepo.Update(ctx, delivery); err != nil {
+		log.Printf("Failed to save webhook delivery %s: %v", delivery.ID.Hex(), err)
+	}
+}
+
+// webhookRequest builds the signed request for a delivery. Receivers
+// verify it by computing the HMAC-SHA256 of the timestamp header, a dot
+// and the body with their secret.
+func webhookRequest(webhook *Domain.Webhook, delivery *Domain.WebhookDelivery, now time.Time) (Domain.WebhookRequest, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return Domain.WebhookRequest{}, err
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	return Domain.WebhookRequest{
+		URL: webhook.URL,
+		Headers: map[string]string{
+			"Content-Type":        "application/json",
+			"X-Webhook-Id":        webhook.ID.Hex(),
+			"X-Webhook-Delivery":  delivery.ID.Hex(),
+			"X-Webhook-Event":     string(delivery.Event.Type),
+			"X-Webhook-Timestamp": timestamp,
+			"X-Webhook-Signature": "sha256=" + signWebhook(webhook.Secret, timestamp, body),
+		},
+		Body: body,
+	}, nil
+}
+
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay is the wait after the given number of failed attempts
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := Domain.WebhookRetryDelay
+	for i := 1; i < attempts && delay < Domain.MaxWebhookRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, Domain.MaxWebhookRetryDelay)
+}
+
+func truncateWebhookError(message string) string {
+	if len(message) <= maxWebhookErrorLength {
+		return message
+	}
+	return message[:maxWebhookErrorLength] + "..."
+}
+
+func webhookDeliveryLimit(limit int) int {
+	if limit <= 0 {
+		return Domain.DefaultWebhookDeliveryPageSize
+	}
+	return min(limit, Domain.MaxWebhookDeliveryPageSize)
+}
+
+// validateWebhookURL checks that a webhook URL is http or https and, unless
+// private addresses are allowed, that its host only resolves to public
+// addresses. The sender checks the address again when it connects, in case
+// the host resolves differently by then.
+func (uc *WebhookUseCase) validateWebhookURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &Domain.FieldError{Field: "url", Message: "must be an http or https URL"}
+	}
+	if u.User != nil {
+		return &Domain.FieldError{Field: "url", Message: "must not contain credentials"}
+	}
+	if uc.allowPrivateAddresses {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return &Domain.FieldError{Field: "url", Message: "must have a host that can be resolved"}
+	}
+	for _, addr := range addrs {
+		if !Domain.IsPublicAddress(addr) {
+			return &Domain.FieldError{Field: "url", Message: "must not point at a loopback, link-local or private address"}
+		}
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,244 @@
+package Usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"taskmanager/auth/Domain"
+	"taskmanager/auth/Infrastructure"
+	"taskmanager/auth/Repositories"
+)
+
+const testWebhookSecret = "a-secret-of-at-least-16-characters"
+
+// webhookTest is a webhook use case on the in-memory repositories with one
+// user, whose webhook posts to receiver
+type webhookTest struct {
+	uc         *WebhookUseCase
+	deliveries *Repositories.InMemoryWebhookDeliveryRepository
+	policy     *Domain.Policy
+	webhook    *Domain.Webhook
+}
+
+func newWebhookTest(t *testing.T, receiver http.HandlerFunc) *webhookTest {
+	t.Helper()
+
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	deliveries := Repositories.NewInMemoryWebhookDeliveryRepository()
+	uc := NewWebhookUseCase(
+		Repositories.NewInMemoryWebhookRepository(),
+		deliveries,
+		Repositories.NewInMemoryAuditRepository(),
+		Infrastructure.NewHTTPWebhookSender(5*time.Second, true),
+		true,
+	)
+	policy := Domain.NewPolicy(primitive.NewObjectID(), Domain.RoleUser)
+
+	webhook, _, err := uc.CreateWebhook(context.Background(), Domain.CreateWebhookRequest{
+		URL:    server.URL,
+		Secret: testWebhookSecret,
+	}, policy)
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+
+	return &webhookTest{uc: uc, deliveries: deliveries, policy: policy, webhook: webhook}
+}
+
+// publish sends a task event of the user through the webhook publisher
+func (wt *webhookTest) publish(t *testing.T) Domain.TaskEvent {
+	t.Helper()
+
+	event := Domain.TaskEvent{
+		ID:         primitive.NewObjectID().Hex(),
+		Type:       Domain.TaskEventCreated,
+		Task:       Domain.Task{ID: primitive.NewObjectID(), Title: "Write report", UserID: wt.policy.UserID},
+		Viewers:    []primitive.ObjectID{wt.policy.UserID},
+		OccurredAt: time.Now(),
+	}
+	wt.uc.Publisher(Infrastructure.NewEventBus(10)).Publish(event)
+	return event
+}
+
+// delivery returns the only delivery of the webhook
+func (wt *webhookTest) delivery(t *testing.T) Domain.WebhookDelivery {
+	t.Helper()
+
+	deliveries, err := wt.deliveries.List(context.Background(), Domain.WebhookDeliveryFilter{WebhookID: &wt.webhook.ID}, 10)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+func TestWebhookDeliveryIsSigned(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		headers http.Header
+		body    []byte
+	)
+	wt := newWebhookTest(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		headers = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	event := wt.publish(t)
+
+	attempted, err := wt.uc.DispatchDue(context.Background(), time.Now())
+	if err != nil || attempted != 1 {
+		t.Fatalf("DispatchDue = %d, %v; want 1, nil", attempted, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(headers.Get("X-Webhook-Timestamp") + "."))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := headers.Get("X-Webhook-Signature"); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("X-Webhook-Signature = %q, want %q", got, want)
+	}
+
+	var received Domain.TaskEvent
+	if err := json.Unmarshal(body, &received); err != nil {
+		t.Fatalf("body is not a task event: %v", err)
+	}
+	if received.ID != event.ID || received.Task.ID != event.Task.ID {
+		t.Errorf("received event %s of task %s, want %s of task %s", received.ID, received.Task.ID.Hex(), event.ID, event.Task.ID.Hex())
+	}
+	if got := headers.Get("X-Webhook-Event"); got != string(Domain.TaskEventCreated) {
+		t.Errorf("X-Webhook-Event = %q, want %q", got, Domain.TaskEventCreated)
+	}
+	if got := headers.Get("X-Webhook-Id"); got != wt.webhook.ID.Hex() {
+		t.Errorf("X-Webhook-Id = %q, want %q", got, wt.webhook.ID.Hex())
+	}
+
+	delivery := wt.delivery(t)
+	if delivery.Status != Domain.WebhookDeliverySucceeded || delivery.NextAttemptAt != nil {
+		t.Errorf("delivery is %s with next attempt %v, want succeeded with none", delivery.Status, delivery.NextAttemptAt)
+	}
+	if got := headers.Get("X-Webhook-Delivery"); got != delivery.ID.Hex() {
+		t.Errorf("X-Webhook-Delivery = %q, want %q", got, delivery.ID.Hex())
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{5, 8 * time.Minute},
+		{6, 16 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("webhookRetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookDeliveryFailsAfterMaxAttempts(t *testing.T) {
+	var requests atomic.Int32
+	wt := newWebhookTest(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	wt.publish(t)
+
+	// Dispatch far enough ahead that every retry is due
+	now := time.Now()
+	for attempt := 1; attempt <= Domain.MaxWebhookAttempts; attempt++ {
+		now = now.Add(2 * Domain.MaxWebhookRetryDelay)
+		attempted, err := wt.uc.DispatchDue(context.Background(), now)
+		if err != nil || attempted != 1 {
+			t.Fatalf("attempt %d: DispatchDue = %d, %v; want 1, nil", attempt, attempted, err)
+		}
+
+		delivery := wt.delivery(t)
+		if len(delivery.Attempts) != attempt {
+			t.Fatalf("attempt %d: delivery has %d attempts", attempt, len(delivery.Attempts))
+		}
+		if got := delivery.Attempts[attempt-1].StatusCode; got != http.StatusInternalServerError {
+			t.Errorf("attempt %d: status code %d, want 500", attempt, got)
+		}
+		if attempt < Domain.MaxWebhookAttempts {
+			if delivery.Status != Domain.WebhookDeliveryPending || delivery.NextAttemptAt == nil {
+				t.Fatalf("attempt %d: delivery is %s with next attempt %v, want pending with a retry", attempt, delivery.Status, delivery.NextAttemptAt)
+			}
+			wait := delivery.NextAttemptAt.Sub(delivery.Attempts[attempt-1].At)
+			if want := webhookRetryDelay(attempt); wait < want || wait > want+time.Minute {
+				t.Errorf("attempt %d: retried after %v, want %v", attempt, wait, want)
+			}
+			continue
+		}
+		if delivery.Status != Domain.WebhookDeliveryFailed || delivery.NextAttemptAt != nil {
+			t.Errorf("delivery is %s with next attempt %v, want failed with none", delivery.Status, delivery.NextAttemptAt)
+		}
+	}
+
+	attempted, err := wt.uc.DispatchDue(context.Background(), now.Add(2*Domain.MaxWebhookRetryDelay))
+	if err != nil || attempted != 0 {
+		t.Errorf("DispatchDue after failing = %d, %v; want 0, nil", attempted, err)
+	}
+	if got := requests.Load(); got != Domain.MaxWebhookAttempts {
+		t.Errorf("receiver got %d requests, want %d", got, Domain.MaxWebhookAttempts)
+	}
+
+	deadLetters, err := wt.uc.ListDeadLetters(context.Background(), wt.policy, 0)
+	if err != nil || len(deadLetters) != 1 {
+		t.Errorf("ListDeadLetters = %d deliveries, %v; want 1, nil", len(deadLetters), err)
+	}
+}
+
+func TestCreateWebhookRefusesPrivateAddresses(t *testing.T) {
+	uc := NewWebhookUseCase(
+		Repositories.NewInMemoryWebhookRepository(),
+		Repositories.NewInMemoryWebhookDeliveryRepository(),
+		Repositories.NewInMemoryAuditRepository(),
+		Infrastructure.NewHTTPWebhookSender(5*time.Second, false),
+		false,
+	)
+	policy := Domain.NewPolicy(primitive.NewObjectID(), Domain.RoleUser)
+
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.1.2.3/hook",
+		"http://192.168.0.10/hook",
+		"http://0.0.0.0/hook",
+	} {
+		_, _, err := uc.CreateWebhook(context.Background(), Domain.CreateWebhookRequest{URL: url}, policy)
+		if !errors.Is(err, Domain.ErrInvalidInput) {
+			t.Errorf("CreateWebhook(%s) = %v, want invalid input", url, err)
+		}
+	}
+}